@@ -7,9 +7,14 @@ import (
 )
 
 type Config struct {
-	Logger   LoggerConf
-	HTTP     HTTPConf
-	Postgres PostgresConf
+	Logger    LoggerConf
+	HTTP      HTTPConf
+	GRPC      GRPCConf
+	Storage   StorageConf
+	Postgres  PostgresConf
+	Queue     QueueConf
+	Scheduler SchedulerConf
+	Sender    SenderConf
 }
 
 type LoggerConf struct {
@@ -25,6 +30,33 @@ type HTTPConf struct {
 	Host string
 }
 
+type GRPCConf struct {
+	Port string
+	Host string
+}
+
+type StorageConf struct {
+	// Type is memory, sql or file, sql if not set. The memory and file storages are local to a process,
+	// so only one server may use them and the scheduler requires sql.
+	Type  string
+	File  FileStorageConf
+	Cache CacheConf
+}
+
+// CacheConf configures the cache of the servers, zero capacity disables it.
+type CacheConf struct {
+	Capacity int
+	TTL      time.Duration
+	// StatsInterval is the period of logging the hits and the misses of the cache, zero logs them only on stop.
+	StatsInterval time.Duration
+}
+
+type FileStorageConf struct {
+	Dir string
+	// SnapshotEvery is the number of the log records after which the snapshot is written.
+	SnapshotEvery int
+}
+
 type PostgresConf struct {
 	DSN             string
 	MaxOpenConns    int
@@ -33,10 +65,78 @@ type PostgresConf struct {
 	ConnMaxIdleTime time.Duration
 }
 
+type QueueConf struct {
+	// Type is memory or amqp, the memory queue only works inside one process.
+	Type string
+	AMQP AMQPConf
+}
+
+type AMQPConf struct {
+	URL        string
+	Exchange   string
+	Queue      string
+	RoutingKey string
+	// Prefetch is the number of unacknowledged messages delivered to a consumer.
+	Prefetch int
+}
+
+type SchedulerConf struct {
+	Interval time.Duration
+	Cleanup  CleanupConf
+	Leader   LeaderConf
+}
+
+// LeaderConf configures the election of the replica running the scheduler jobs.
+type LeaderConf struct {
+	// Name identifies the lock, replicas with the same name elect one leader.
+	Name string
+	// Interval is the pause between the campaigns and between the checks of the leadership.
+	Interval time.Duration
+}
+
+type CleanupConf struct {
+	Interval  time.Duration
+	Retention time.Duration
+	DryRun    bool
+}
+
+type SenderConf struct {
+	Channels       []string
+	DeadLetterFile string
+	// DedupCapacity is the number of the latest delivered notification keys kept to drop duplicates.
+	DedupCapacity int
+	// DedupFile keeps the delivered notification keys between restarts, the keys are kept in memory only if empty.
+	DedupFile string
+	Retry     RetryConf
+	Webhook   WebhookConf
+	SMTP      SMTPConf
+}
+
+type RetryConf struct {
+	Attempts     int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+type WebhookConf struct {
+	URL     string
+	Timeout time.Duration
+}
+
+type SMTPConf struct {
+	Addr            string
+	From            string
+	RecipientFormat string
+	Username        string
+	Password        string
+}
+
 func ReadConfig(path string) (*Config, error) {
 	v := viper.New()
 	v.AutomaticEnv()
 	v.SetConfigFile(path)
+	// configs written before the storage type was introduced use sql
+	v.SetDefault("storage.type", "sql")
 
 	if err := v.ReadInConfig(); err != nil {
 		return nil, err
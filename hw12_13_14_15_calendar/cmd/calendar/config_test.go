@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfig(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.toml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	t.Run(
+		"when storage type is not set, uses sql storage", func(t *testing.T) {
+			config, err := ReadConfig(write(t, "[storage.cache]\ncapacity = 10\n"))
+			require.NoError(t, err)
+			require.Equal(t, "sql", config.Storage.Type)
+			require.Equal(t, 10, config.Storage.Cache.Capacity)
+		},
+	)
+
+	t.Run(
+		"when storage type is set, keeps it", func(t *testing.T) {
+			config, err := ReadConfig(write(t, "[storage]\ntype = \"memory\"\n"))
+			require.NoError(t, err)
+			require.Equal(t, "memory", config.Storage.Type)
+		},
+	)
+}
@@ -2,15 +2,28 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/app"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/ics"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/leader"
 	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/logger"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+	amqpqueue "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue/amqp"
+	memoryqueue "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue/memory"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/scheduler"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/sender"
+	internalgrpc "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/server/grpc"
 	internalhttp "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/server/http"
+	cachestorage "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage/cache"
+	filestorage "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage/file"
+	memorystorage "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage/memory"
 	sqlstorage "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage/sql"
 	"github.com/spf13/cobra"
 )
@@ -27,6 +40,27 @@ func main() {
 			RunE:  runHTTPServer,
 		},
 	)
+	rootCmd.AddCommand(
+		&cobra.Command{
+			Use:   "grpc",
+			Short: "Run grpc server",
+			RunE:  runGRPCServer,
+		},
+	)
+	rootCmd.AddCommand(
+		&cobra.Command{
+			Use:   "scheduler",
+			Short: "Run notification scheduler",
+			RunE:  runScheduler,
+		},
+	)
+	rootCmd.AddCommand(
+		&cobra.Command{
+			Use:   "sender",
+			Short: "Run notification sender",
+			RunE:  runSender,
+		},
+	)
 	rootCmd.AddCommand(
 		&cobra.Command{
 			Use:   "version",
@@ -42,6 +76,17 @@ func main() {
 		},
 	)
 
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import events from iCalendar file",
+		RunE:  runImport,
+	}
+	importCmd.Flags().String("file", "", "Path to .ics file")
+	importCmd.Flags().String("user", "", "Owner of imported events")
+	_ = importCmd.MarkFlagRequired("file")
+	_ = importCmd.MarkFlagRequired("user")
+	rootCmd.AddCommand(importCmd)
+
 	rootCmd.PersistentFlags().String("config", "/etc/calendar/config.toml", "Path to configuration file")
 
 	if err := rootCmd.Execute(); err != nil {
@@ -55,36 +100,69 @@ func runVersion(cmd *cobra.Command, args []string) error {
 }
 
 func runHTTPServer(cmd *cobra.Command, args []string) error {
-	configFile, err := cmd.Root().PersistentFlags().GetString("config")
+	config, err := loadConfig(cmd)
 	if err != nil {
 		return err
 	}
 
-	config, err := ReadConfig(configFile)
+	logg := logger.New(logger.LevelFromString(config.Logger.Level), os.Stderr)
+	storage, err := connectStorage(config)
+	if err != nil {
+		return err
+	}
+	defer closeStorage(logg, storage)
+
+	cached := withCache(config, storage)
+	defer logCacheStats(logg, cached)
+
+	calendar := app.New(logg, cached)
+	server := internalhttp.NewServer(logg, calendar, config.HTTP.Host, config.HTTP.Port)
+
+	notifyCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	go reportCacheStats(notifyCtx, logg, cached, config.Storage.Cache.StatsInterval)
+
+	go func() {
+		<-notifyCtx.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer cancel()
+
+		if err := server.Stop(ctx); err != nil {
+			logg.Error("failed to stop http server: " + err.Error())
+		}
+	}()
+
+	logg.Info("calendar is running...")
+
+	return server.Start(notifyCtx)
+}
+
+func runGRPCServer(cmd *cobra.Command, args []string) error {
+	config, err := loadConfig(cmd)
 	if err != nil {
 		return err
 	}
 
 	logg := logger.New(logger.LevelFromString(config.Logger.Level), os.Stderr)
-	storage := sqlstorage.New(
-		config.Postgres.DSN,
-		config.Postgres.MaxOpenConns,
-		config.Postgres.MaxIdleConns,
-		config.Postgres.ConnMaxLifetime,
-		config.Postgres.ConnMaxIdleTime,
-	)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := storage.Connect(ctx); err != nil {
+	storage, err := connectStorage(config)
+	if err != nil {
 		return err
 	}
+	defer closeStorage(logg, storage)
 
-	calendar := app.New(logg, storage)
-	server := internalhttp.NewServer(logg, calendar, config.HTTP.Host, config.HTTP.Port)
+	cached := withCache(config, storage)
+	defer logCacheStats(logg, cached)
+
+	calendar := app.New(logg, cached)
+	server := internalgrpc.NewServer(logg, calendar, config.GRPC.Host, config.GRPC.Port)
 
 	notifyCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	defer stop()
 
+	go reportCacheStats(notifyCtx, logg, cached, config.Storage.Cache.StatsInterval)
+
 	go func() {
 		<-notifyCtx.Done()
 
@@ -92,7 +170,7 @@ func runHTTPServer(cmd *cobra.Command, args []string) error {
 		defer cancel()
 
 		if err := server.Stop(ctx); err != nil {
-			logg.Error("failed to stop http server: " + err.Error())
+			logg.Error("failed to stop grpc server: " + err.Error())
 		}
 	}()
 
@@ -101,17 +179,293 @@ func runHTTPServer(cmd *cobra.Command, args []string) error {
 	return server.Start(notifyCtx)
 }
 
+func runScheduler(cmd *cobra.Command, args []string) error {
+	config, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// the scheduler is a separate process, so it must share the storage with the servers
+	if config.Storage.Type != "sql" {
+		return fmt.Errorf("scheduler requires sql storage, storage type %q is local to a process", config.Storage.Type)
+	}
+
+	logg := logger.New(logger.LevelFromString(config.Logger.Level), os.Stderr)
+	// the scheduler bypasses the cache of the servers on purpose: withCache is applied only in runHTTPServer
+	// and runGRPCServer, while due reminders must be read as soon as they are written by any replica
+	// and their completion is checked against the stored state.
+	storage, err := connectSQLStorage(config)
+	if err != nil {
+		return err
+	}
+	defer closeStorage(logg, storage)
+
+	notifyCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	publisher, closeQueue, err := connectQueue(config)
+	if err != nil {
+		return err
+	}
+	defer closeQueue()
+
+	notifier := scheduler.New(logg, storage, publisher, config.Scheduler.Interval)
+	cleaner := scheduler.NewCleaner(
+		logg,
+		storage,
+		config.Scheduler.Cleanup.Retention,
+		config.Scheduler.Cleanup.Interval,
+		config.Scheduler.Cleanup.DryRun,
+	)
+
+	logg.Info("scheduler is running...")
+
+	// only the leader among the replicas scans the reminders and cleans the events
+	elector := storage.Elector(config.Scheduler.Leader.Name)
+	return leader.Run(notifyCtx, logg, elector, config.Scheduler.Leader.Interval, func(ctx context.Context) {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cleaner.Run(ctx); err != nil {
+				logg.Error("cleaner stopped: " + err.Error())
+			}
+		}()
+
+		if err := notifier.Run(ctx); err != nil {
+			logg.Error("scheduler stopped: " + err.Error())
+		}
+		wg.Wait()
+	})
+}
+
+func runSender(cmd *cobra.Command, args []string) error {
+	config, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	logg := logger.New(logger.LevelFromString(config.Logger.Level), os.Stderr)
+
+	channels := make([]sender.Channel, 0, len(config.Sender.Channels))
+	for _, name := range config.Sender.Channels {
+		var s sender.Sender
+		switch name {
+		case "log":
+			s = sender.NewLogSender(logg)
+		case "webhook":
+			s = sender.NewWebhookSender(config.Sender.Webhook.URL, config.Sender.Webhook.Timeout)
+		case "smtp":
+			s = sender.NewSMTPSender(
+				config.Sender.SMTP.Addr,
+				config.Sender.SMTP.From,
+				config.Sender.SMTP.RecipientFormat,
+				config.Sender.SMTP.Username,
+				config.Sender.SMTP.Password,
+			)
+		default:
+			return fmt.Errorf("unknown sender channel %q", name)
+		}
+		channels = append(channels, sender.Channel{Name: name, Sender: s})
+	}
+
+	var deadLetter sender.DeadLetterStore = sender.NewMemoryDeadLetterStore()
+	if config.Sender.DeadLetterFile != "" {
+		deadLetter = sender.NewFileDeadLetterStore(config.Sender.DeadLetterFile)
+	}
+
+	var delivered sender.KeyStore = sender.NewMemoryKeyStore(config.Sender.DedupCapacity)
+	if config.Sender.DedupFile != "" {
+		delivered, err = sender.NewFileKeyStore(config.Sender.DedupFile, config.Sender.DedupCapacity)
+		if err != nil {
+			return err
+		}
+	}
+
+	backoff := sender.Backoff{
+		Attempts:     config.Sender.Retry.Attempts,
+		InitialDelay: config.Sender.Retry.InitialDelay,
+		MaxDelay:     config.Sender.Retry.MaxDelay,
+	}
+
+	consumer, closeQueue, err := connectQueue(config)
+	if err != nil {
+		return err
+	}
+	defer closeQueue()
+
+	notifyCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	logg.Info("sender is running...")
+
+	return sender.New(
+		logg,
+		consumer,
+		channels,
+		backoff,
+		deadLetter,
+		delivered,
+	).Run(notifyCtx)
+}
+
 func runMigrations(cmd *cobra.Command, args []string) error {
-	configFile, err := cmd.Root().PersistentFlags().GetString("config")
+	config, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	if config.Storage.Type != "sql" {
+		return fmt.Errorf("storage type %q has no migrations", config.Storage.Type)
+	}
+	storage, err := connectSQLStorage(config)
+	if err != nil {
+		return err
+	}
+
+	return storage.Migrate(context.Background(), args[0])
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	file, err := cmd.Flags().GetString("file")
+	if err != nil {
+		return err
+	}
+	userID, err := cmd.Flags().GetString("user")
+	if err != nil {
+		return err
+	}
+
+	config, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	logg := logger.New(logger.LevelFromString(config.Logger.Level), os.Stderr)
+	storage, err := connectStorage(config)
+	if err != nil {
+		return err
+	}
+	defer closeStorage(logg, storage)
+
+	f, err := os.Open(file)
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 
-	config, err := ReadConfig(configFile)
+	events, err := ics.Decode(f)
 	if err != nil {
 		return err
 	}
 
+	calendar := app.New(logg, storage)
+	ctx := context.Background()
+	failed := 0
+	for _, event := range events {
+		if _, err := calendar.CreateEvent(
+			ctx,
+			event.Title,
+			event.Description,
+			userID,
+			"",
+			event.StartAt,
+			event.EndAt,
+			event.Offsets(),
+			event.RRule,
+			event.Timezone,
+			event.ExDates,
+			nil,
+		); err != nil {
+			failed++
+			logg.Warn(fmt.Sprintf("event %q at %s is not imported: %s", event.Title, event.StartAt.Format(time.RFC3339), err))
+		}
+	}
+
+	logg.Info(fmt.Sprintf("imported %d of %d events", len(events)-failed, len(events)))
+	if failed > 0 {
+		return fmt.Errorf("%d of %d events are not imported", failed, len(events))
+	}
+
+	return nil
+}
+
+func loadConfig(cmd *cobra.Command) (*Config, error) {
+	configFile, err := cmd.Root().PersistentFlags().GetString("config")
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadConfig(configFile)
+}
+
+// appStorage is implemented by every storage type.
+type appStorage interface {
+	app.Storage
+	scheduler.Storage
+	Close(ctx context.Context) error
+}
+
+func connectStorage(config *Config) (appStorage, error) {
+	switch config.Storage.Type {
+	case "memory":
+		return memorystorage.New(), nil
+	case "sql":
+		return connectSQLStorage(config)
+	case "file":
+		storage := filestorage.New(config.Storage.File.Dir, config.Storage.File.SnapshotEvery)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := storage.Connect(ctx); err != nil {
+			return nil, err
+		}
+		return storage, nil
+	default:
+		return nil, fmt.Errorf("unknown storage type %q", config.Storage.Type)
+	}
+}
+
+// withCache wraps the storage with the cache unless it is disabled.
+func withCache(config *Config, storage app.Storage) app.Storage {
+	if config.Storage.Cache.Capacity <= 0 {
+		return storage
+	}
+	return cachestorage.New(storage, config.Storage.Cache.TTL, config.Storage.Cache.Capacity)
+}
+
+// reportCacheStats logs the cache stats every interval until the context is done,
+// the storage without the cache and zero interval are ignored.
+func reportCacheStats(ctx context.Context, logg *logger.Logger, storage app.Storage, interval time.Duration) {
+	if _, ok := storage.(*cachestorage.Storage); !ok || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			logCacheStats(logg, storage)
+		}
+	}
+}
+
+func logCacheStats(logg *logger.Logger, storage app.Storage) {
+	if cached, ok := storage.(*cachestorage.Storage); ok {
+		stats := cached.Stats()
+		logg.Info(fmt.Sprintf("cache hits: %d, misses: %d", stats.Hits, stats.Misses))
+	}
+}
+
+func closeStorage(logg *logger.Logger, storage appStorage) {
+	if err := storage.Close(context.Background()); err != nil {
+		logg.Error("failed to close storage: " + err.Error())
+	}
+}
+
+func connectSQLStorage(config *Config) (*sqlstorage.Storage, error) {
 	storage := sqlstorage.New(
 		config.Postgres.DSN,
 		config.Postgres.MaxOpenConns,
@@ -122,8 +476,37 @@ func runMigrations(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := storage.Connect(ctx); err != nil {
-		return err
+		return nil, err
 	}
 
-	return storage.Migrate(context.Background(), args[0])
+	return storage, nil
+}
+
+type messageQueue interface {
+	queue.Publisher
+	queue.Consumer
+}
+
+// connectQueue returns the queue of the notifications and the function closing it.
+func connectQueue(config *Config) (messageQueue, func(), error) {
+	switch config.Queue.Type {
+	case "memory":
+		return memoryqueue.New(), func() {}, nil
+	case "amqp":
+		q := amqpqueue.New(
+			config.Queue.AMQP.URL,
+			config.Queue.AMQP.Exchange,
+			config.Queue.AMQP.Queue,
+			config.Queue.AMQP.RoutingKey,
+			config.Queue.AMQP.Prefetch,
+		)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := q.Connect(ctx); err != nil {
+			return nil, nil, fmt.Errorf("queue connect: %w", err)
+		}
+		return q, func() { _ = q.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown queue type %q", config.Queue.Type)
+	}
 }
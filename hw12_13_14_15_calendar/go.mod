@@ -4,8 +4,17 @@ go 1.16
 
 require (
 	github.com/google/uuid v1.3.0
+	github.com/jackc/pgconn v1.12.1
+	github.com/jackc/pgtype v1.11.0
 	github.com/jackc/pgx/v4 v4.16.1
 	github.com/pressly/goose/v3 v3.6.1
+	github.com/rabbitmq/amqp091-go v1.9.0
 	github.com/spf13/cobra v1.5.0
 	github.com/spf13/viper v1.12.0
+	github.com/stretchr/testify v1.8.3
+	golang.org/x/net v0.12.0 // indirect
+	golang.org/x/sys v0.10.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+	google.golang.org/grpc v1.56.3
+	google.golang.org/protobuf v1.30.0
 )
@@ -3,15 +3,14 @@ package app
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
 )
 
-var (
-	ErrDateBusy       = errors.New("date is busy")
-	ErrEventNotExists = errors.New("event not exists")
-)
+var ErrInvalidEvent = errors.New("invalid event")
 
 type App struct {
 	logger  Logger
@@ -28,112 +27,277 @@ type Logger interface {
 type Storage interface {
 	NextID(ctx context.Context) (storage.EventID, error)
 	Save(ctx context.Context, event *storage.Event) error
+	SaveWithChange(ctx context.Context, event *storage.Event, change *storage.Change) error
 	FindByID(ctx context.Context, eventID storage.EventID) (*storage.Event, error)
+	// FindCurrentByID is FindByID bypassing any cache, the writes are based on the events it returns,
+	// so a stale version or state of an event changed by another process is not written back.
+	FindCurrentByID(ctx context.Context, eventID storage.EventID) (*storage.Event, error)
 	Delete(ctx context.Context, event *storage.Event) error
+	DeleteWithChange(ctx context.Context, event *storage.Event, change *storage.Change) error
 	FindAllByUserIDAndPeriod(
 		ctx context.Context, ownerID storage.UserID,
 		from time.Time, to time.Time,
 	) ([]storage.Event, error)
-	HasByUserIDAndPeriod(ctx context.Context, ownerID storage.UserID, from time.Time, to time.Time) (bool, error)
-	HasByUserIDAndPeriodForUpdate(
-		ctx context.Context,
-		forUpdate storage.EventID,
-		ownerID storage.UserID,
-		from time.Time,
-		to time.Time,
-	) (bool, error)
+	FindPageByUserIDAndPeriod(
+		ctx context.Context, userID storage.UserID,
+		from time.Time, to time.Time,
+		after storage.Cursor, limit int,
+	) ([]storage.Event, error)
+	FindSeriesPageByUserIDAndPeriod(
+		ctx context.Context, userID storage.UserID,
+		from time.Time, to time.Time,
+		after storage.Cursor, limit int,
+	) ([]storage.Event, error)
+	FindBusyByUserIDsAndPeriod(
+		ctx context.Context, userIDs []storage.UserID,
+		from time.Time, to time.Time,
+	) ([]storage.Event, error)
+	FindAllByUserID(ctx context.Context, ownerID storage.UserID) ([]storage.Event, error)
+	CountAllEndedBefore(ctx context.Context, before time.Time) (int, error)
+	DeleteAllEndedBefore(ctx context.Context, before time.Time) (int, error)
+	SaveCalendar(ctx context.Context, calendar *storage.Calendar) error
+	FindCalendarByID(ctx context.Context, calendarID storage.CalendarID) (*storage.Calendar, error)
+	FindCalendarsByUserID(ctx context.Context, userID storage.UserID) ([]storage.Calendar, error)
+	DeleteCalendar(ctx context.Context, calendarID storage.CalendarID) error
+	DeleteCalendarWithChanges(ctx context.Context, calendarID storage.CalendarID, changes []*storage.Change) error
+	FindAllByCalendarIDAndPeriod(
+		ctx context.Context, calendarID storage.CalendarID,
+		from time.Time, to time.Time,
+	) ([]storage.Event, error)
+	Search(ctx context.Context, filter storage.SearchFilter) ([]storage.Event, int, error)
+	FindChangesByEventID(ctx context.Context, eventID storage.EventID) ([]storage.Change, error)
 }
 
 func New(logger Logger, storage Storage) *App {
 	return &App{logger, storage}
 }
 
+// CreateEvent creates the event of the user, empty calendar id means the default calendar of the user.
+// An event of a calendar shared with write access is owned by the owner of the calendar.
+// A recurring event is expanded in the IANA timezone, empty timezone means UTC.
 func (a *App) CreateEvent(
 	ctx context.Context,
-	title, description, ownerID string,
+	title, description, userID, calendarID string,
 	startAt, endAt time.Time,
-	notifyThreshold time.Duration,
+	reminders []time.Duration,
+	rrule, timezone string,
+	exDates []time.Time,
+	attendees []string,
 ) (storage.EventID, error) {
-	isBusy, err := a.storage.HasByUserIDAndPeriod(ctx, storage.UserID(ownerID), startAt, endAt)
-	if err != nil {
+	if err := validateEvent(title, startAt, endAt); err != nil {
 		return "", err
 	}
-
-	if isBusy {
-		return "", ErrDateBusy
-	}
-
-	id, err := a.storage.NextID(ctx)
+	offsets, err := normalizeReminders(reminders)
 	if err != nil {
 		return "", err
 	}
 
 	event := &storage.Event{
-		ID:          id,
 		Title:       title,
 		StartAt:     startAt,
 		EndAt:       endAt,
 		Description: description,
-		OwnerID:     storage.UserID(ownerID),
-		NotifyAt:    startAt.Truncate(notifyThreshold),
+		OwnerID:     storage.UserID(userID),
+	}
+	if calendarID != "" {
+		calendar, err := a.writableCalendar(ctx, userID, calendarID)
+		if err != nil {
+			return "", err
+		}
+		event.OwnerID = calendar.OwnerID
+		event.CalendarID = calendar.ID
+	}
+	if err := setRecurrence(event, rrule, timezone, exDates); err != nil {
+		return "", err
+	}
+	if err := setAttendees(event, attendees); err != nil {
+		return "", err
 	}
 
-	if err := a.storage.Save(ctx, event); err != nil {
+	id, err := a.storage.NextID(ctx)
+	if err != nil {
+		return "", err
+	}
+	event.ID = id
+
+	if event.Reminders, err = storage.ScheduleReminders(*event, offsets, time.Now()); err != nil {
+		return "", err
+	}
+
+	if err := a.save(ctx, event, newChange(storage.ChangeCreate, userID, nil)); err != nil {
 		return "", err
 	}
 
 	return event.ID, nil
 }
 
+// UpdateEvent replaces the event and returns its new version, the user must have write access to the event.
+// Non-zero version must match the stored one, otherwise storage.ErrVersionMismatch is returned,
+// zero version updates the latest one. The owner and the calendar of the event are kept.
 func (a *App) UpdateEvent(
 	ctx context.Context,
-	eventID, title, description, ownerID string,
+	eventID string,
+	version int64,
+	title, description, userID string,
 	startAt, endAt time.Time,
-	notifyThreshold time.Duration,
-) error {
-	event, err := a.storage.FindByID(ctx, storage.EventID(eventID))
+	reminders []time.Duration,
+	rrule, timezone string,
+	exDates []time.Time,
+	attendees []string,
+) (int64, error) {
+	if err := validateEvent(title, startAt, endAt); err != nil {
+		return 0, err
+	}
+	offsets, err := normalizeReminders(reminders)
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	event, err := a.findVersion(ctx, eventID, version)
+	if err != nil {
+		return 0, err
 	}
-	if event == nil {
-		return ErrEventNotExists
+	if err := a.checkAccess(ctx, userID, event, storage.AccessWrite); err != nil {
+		return 0, err
 	}
+	// the fields below are replaced, not modified in place, so the copy keeps the old state
+	before := *event
 
 	event.Title = title
 	event.Description = description
-	event.OwnerID = storage.UserID(ownerID)
 	event.StartAt = startAt
 	event.EndAt = endAt
-	event.NotifyAt = startAt.Truncate(notifyThreshold)
-
-	isBusy, err := a.storage.HasByUserIDAndPeriodForUpdate(
-		ctx,
-		event.ID,
-		event.OwnerID,
-		event.StartAt,
-		event.EndAt,
-	)
+	if err := setRecurrence(event, rrule, timezone, exDates); err != nil {
+		return 0, err
+	}
+	if err := setAttendees(event, attendees); err != nil {
+		return 0, err
+	}
+	if event.Reminders, err = storage.ScheduleReminders(*event, offsets, time.Now()); err != nil {
+		return 0, err
+	}
+
+	if err := a.save(ctx, event, newChange(storage.ChangeUpdate, userID, &before)); err != nil {
+		return 0, err
+	}
+
+	return event.Version, nil
+}
+
+// DeleteEvent deletes the event, the user must have write access to it. Non-zero version must match the stored one.
+func (a *App) DeleteEvent(ctx context.Context, userID, id string, version int64) error {
+	event, err := a.findVersion(ctx, id, version)
 	if err != nil {
 		return err
 	}
-	if isBusy {
-		return ErrDateBusy
+	if err := a.checkAccess(ctx, userID, event, storage.AccessWrite); err != nil {
+		return err
 	}
-
-	return a.storage.Save(ctx, event)
+	return a.storage.DeleteWithChange(ctx, event, newChange(storage.ChangeDelete, userID, event))
 }
 
-func (a *App) DeleteEvent(ctx context.Context, id string) error {
+// GetEvent returns the event by id, users with free/busy access get only its time.
+func (a *App) GetEvent(ctx context.Context, userID, id string) (*storage.Event, error) {
 	event, err := a.storage.FindByID(ctx, storage.EventID(id))
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	access, err := a.accessTo(ctx, userID, event)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case access.Allows(storage.AccessRead):
+		return event, nil
+	case access.Allows(storage.AccessFreeBusy):
+		busy := busyOnly(*event)
+		busy.ID = event.ID
+		return &busy, nil
+	default:
+		return nil, fmt.Errorf("%w: user %s has no access to event %s", ErrForbidden, userID, id)
+	}
+}
+
+// findVersion returns the event if its version matches, zero version matches any.
+// The storage checks the version again on write, so a concurrent update is not lost.
+func (a *App) findVersion(ctx context.Context, id string, version int64) (*storage.Event, error) {
+	event, err := a.storage.FindCurrentByID(ctx, storage.EventID(id))
+	if err != nil {
+		return nil, err
 	}
-	if event == nil {
-		return ErrEventNotExists
+	if version != 0 {
+		if err := storage.CheckVersion(*event, version); err != nil {
+			return nil, err
+		}
 	}
-	return a.storage.Delete(ctx, event)
+	return event, nil
 }
 
+// GetEventList returns events overlapping the half-open period [from, to),
+// recurring events are expanded into occurrences.
 func (a *App) GetEventList(ctx context.Context, ownerID string, from, to time.Time) ([]storage.Event, error) {
-	return a.storage.FindAllByUserIDAndPeriod(ctx, storage.UserID(ownerID), from, to)
+	events, err := a.storage.FindAllByUserIDAndPeriod(ctx, storage.UserID(ownerID), from, to)
+	if err != nil {
+		return nil, err
+	}
+	return expandOccurrences(events, from, to)
+}
+
+// expandOccurrences returns the occurrences of the events overlapping [from, to) sorted by start.
+func expandOccurrences(events []storage.Event, from, to time.Time) ([]storage.Event, error) {
+	res := make([]storage.Event, 0, len(events))
+	for _, event := range events {
+		occurrences, err := event.Occurrences(from, to)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, occurrences...)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].StartAt.Before(res[j].StartAt)
+	})
+
+	return res, nil
+}
+
+// GetUserEvents returns all events of the user, recurring events are returned as series.
+func (a *App) GetUserEvents(ctx context.Context, ownerID string) ([]storage.Event, error) {
+	return a.storage.FindAllByUserID(ctx, storage.UserID(ownerID))
+}
+
+// save stores the event together with the change of its history, the storage rejects events overlapping
+// other events of the owner or of the attendees with storage.ErrDateBusy.
+func (a *App) save(ctx context.Context, event *storage.Event, change *storage.Change) error {
+	return a.storage.SaveWithChange(ctx, event, change)
+}
+
+// normalizeReminders removes duplicated offsets and sorts them from the earliest reminder.
+func normalizeReminders(reminders []time.Duration) ([]time.Duration, error) {
+	offsets := make([]time.Duration, 0, len(reminders))
+	seen := make(map[time.Duration]struct{}, len(reminders))
+	for _, offset := range reminders {
+		if offset < 0 {
+			return nil, fmt.Errorf("%w: reminder offset must not be negative", ErrInvalidEvent)
+		}
+		if _, ok := seen[offset]; ok {
+			continue
+		}
+		seen[offset] = struct{}{}
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool {
+		return offsets[i] > offsets[j]
+	})
+	return offsets, nil
+}
+
+func validateEvent(title string, startAt, endAt time.Time) error {
+	if title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidEvent)
+	}
+	if !endAt.After(startAt) {
+		return fmt.Errorf("%w: end date must be after start date", ErrInvalidEvent)
+	}
+	return nil
 }
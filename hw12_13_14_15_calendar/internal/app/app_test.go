@@ -0,0 +1,345 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/logger"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestApp() *App {
+	return New(logger.New(logger.LevelError, io.Discard), memorystorage.New())
+}
+
+func TestApp_CreateEvent_Recurrence(t *testing.T) {
+	ctx := context.Background()
+	monday := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+
+	t.Run(
+		"when rule is invalid, returns invalid event error", func(t *testing.T) {
+			a := newTestApp()
+			_, err := a.CreateEvent(
+				ctx, "standup", "", "user", "", monday, monday.Add(15*time.Minute), nil, "FREQ=HOURLY", "", nil, nil,
+			)
+			require.True(t, errors.Is(err, ErrInvalidEvent))
+		},
+	)
+
+	t.Run(
+		"when exception dates are given without rule, returns invalid event error", func(t *testing.T) {
+			a := newTestApp()
+			_, err := a.CreateEvent(
+				ctx, "standup", "", "user", "", monday, monday.Add(15*time.Minute), nil, "", "", []time.Time{monday}, nil,
+			)
+			require.True(t, errors.Is(err, ErrInvalidEvent))
+		},
+	)
+
+	t.Run(
+		"when event overlaps a later occurrence, returns date busy error", func(t *testing.T) {
+			a := newTestApp()
+			_, err := a.CreateEvent(
+				ctx, "standup", "", "user", "", monday, monday.Add(15*time.Minute), nil, "FREQ=WEEKLY", "", nil, nil,
+			)
+			require.NoError(t, err)
+
+			nextMonth := monday.AddDate(0, 0, 28).Add(5 * time.Minute)
+			_, err = a.CreateEvent(ctx, "review", "", "user", "", nextMonth, nextMonth.Add(time.Hour), nil, "", "", nil, nil)
+			require.True(t, errors.Is(err, storage.ErrDateBusy))
+		},
+	)
+
+	t.Run(
+		"when occurrence is excluded, creates event", func(t *testing.T) {
+			a := newTestApp()
+			excluded := monday.AddDate(0, 0, 14)
+			_, err := a.CreateEvent(
+				ctx, "standup", "", "user", "", monday, monday.Add(15*time.Minute), nil,
+				"FREQ=WEEKLY", "", []time.Time{excluded}, nil,
+			)
+			require.NoError(t, err)
+
+			_, err = a.CreateEvent(ctx, "review", "", "user", "", excluded, excluded.Add(time.Hour), nil, "", "", nil, nil)
+			require.NoError(t, err)
+		},
+	)
+
+	t.Run(
+		"when series overlaps an existing event, returns date busy error", func(t *testing.T) {
+			a := newTestApp()
+			wednesday := monday.AddDate(0, 0, 9)
+			_, err := a.CreateEvent(ctx, "review", "", "user", "", wednesday, wednesday.Add(time.Hour), nil, "", "", nil, nil)
+			require.NoError(t, err)
+
+			_, err = a.CreateEvent(
+				ctx, "standup", "", "user", "", monday, monday.Add(15*time.Minute), nil, "FREQ=WEEKLY;BYDAY=MO,WE", "", nil, nil,
+			)
+			require.True(t, errors.Is(err, storage.ErrDateBusy))
+
+			_, err = a.CreateEvent(
+				ctx, "standup", "", "user", "", monday, monday.Add(15*time.Minute), nil, "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3",
+				"",
+				nil, nil,
+			)
+			require.NoError(t, err)
+		},
+	)
+}
+
+func TestApp_UpdateEvent_Recurrence(t *testing.T) {
+	ctx := context.Background()
+	a := newTestApp()
+	monday := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+
+	id, err := a.CreateEvent(
+		ctx, "standup", "", "user", "", monday, monday.Add(15*time.Minute), nil, "FREQ=DAILY", "", nil, nil,
+	)
+	require.NoError(t, err)
+
+	_, err = a.UpdateEvent(
+		ctx, id.String(), 1, "standup", "", "user", monday, monday.Add(30*time.Minute), nil, "FREQ=DAILY;INTERVAL=2",
+		"",
+		nil, nil,
+	)
+	require.NoError(t, err, "event must not be busy with itself")
+
+	tuesday := monday.AddDate(0, 0, 1)
+	_, err = a.CreateEvent(ctx, "review", "", "user", "", tuesday, tuesday.Add(time.Hour), nil, "", "", nil, nil)
+	require.NoError(t, err)
+}
+
+func TestApp_GetEventList_ExpandsOccurrences(t *testing.T) {
+	ctx := context.Background()
+	a := newTestApp()
+	monday := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+
+	_, err := a.CreateEvent(
+		ctx, "standup", "", "user", "", monday, monday.Add(15*time.Minute), []time.Duration{10 * time.Minute},
+		"FREQ=WEEKLY;BYDAY=MO,FR;COUNT=4", "", []time.Time{monday.AddDate(0, 0, 4)}, nil,
+	)
+	require.NoError(t, err)
+	single := monday.AddDate(0, 0, 8)
+	_, err = a.CreateEvent(ctx, "review", "", "user", "", single, single.Add(time.Hour), nil, "", "", nil, nil)
+	require.NoError(t, err)
+
+	events, err := a.GetEventList(ctx, "user", monday, monday.AddDate(0, 1, 0))
+	require.NoError(t, err)
+
+	titles := make([]string, 0, len(events))
+	starts := make([]time.Time, 0, len(events))
+	for _, event := range events {
+		titles = append(titles, event.Title)
+		starts = append(starts, event.StartAt)
+	}
+	require.Equal(t, []string{"standup", "standup", "review", "standup"}, titles)
+	require.Equal(t, []time.Time{
+		monday,
+		monday.AddDate(0, 0, 7),
+		single,
+		monday.AddDate(0, 0, 11),
+	}, starts)
+
+	occurrence := events[3]
+	require.Equal(t, occurrence.StartAt.Add(15*time.Minute), occurrence.EndAt)
+	require.Equal(t, []time.Duration{10 * time.Minute}, occurrence.Offsets())
+}
+
+func TestApp_CreateEvent_Timezone(t *testing.T) {
+	ctx := context.Background()
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("tzdata is not available")
+	}
+	// the client sends the start with the offset of the first occurrence only
+	startAt := time.Date(2022, time.March, 21, 9, 0, 0, 0, time.FixedZone("", 3600))
+
+	t.Run(
+		"when series crosses DST change, lists occurrences at the same wall clock time", func(t *testing.T) {
+			a := newTestApp()
+			_, err := a.CreateEvent(
+				ctx, "standup", "", "user", "", startAt, startAt.Add(time.Hour), nil,
+				"FREQ=WEEKLY;COUNT=3", "Europe/Berlin", nil, nil,
+			)
+			require.NoError(t, err)
+
+			events, err := a.GetEventList(ctx, "user", startAt, startAt.AddDate(0, 1, 0))
+			require.NoError(t, err)
+			require.Len(t, events, 3)
+			for _, event := range events {
+				require.Equal(t, 9, event.StartAt.In(loc).Hour())
+				require.Equal(t, "Europe/Berlin", event.Timezone)
+			}
+		},
+	)
+
+	t.Run(
+		"when timezone is not set, expands series in UTC", func(t *testing.T) {
+			a := newTestApp()
+			_, err := a.CreateEvent(
+				ctx, "standup", "", "user", "", startAt, startAt.Add(time.Hour), nil, "FREQ=WEEKLY;COUNT=3", "", nil, nil,
+			)
+			require.NoError(t, err)
+
+			events, err := a.GetEventList(ctx, "user", startAt, startAt.AddDate(0, 1, 0))
+			require.NoError(t, err)
+			require.Len(t, events, 3)
+			require.Equal(t, 8, events[2].StartAt.UTC().Hour())
+			require.Equal(t, "UTC", events[2].Timezone)
+		},
+	)
+
+	t.Run(
+		"when timezone is unknown, returns invalid timezone error", func(t *testing.T) {
+			_, err := newTestApp().CreateEvent(
+				ctx, "standup", "", "user", "", startAt, startAt.Add(time.Hour), nil, "FREQ=WEEKLY", "Mars/Olympus", nil, nil,
+			)
+			require.ErrorIs(t, err, ErrInvalidTimezone)
+		},
+	)
+}
+
+func TestApp_CreateEvent_Reminders(t *testing.T) {
+	ctx := context.Background()
+	startAt := time.Now().Add(72 * time.Hour).Truncate(time.Minute)
+
+	t.Run(
+		"when reminders are given, schedules one per offset before start", func(t *testing.T) {
+			a := newTestApp()
+			id, err := a.CreateEvent(
+				ctx, "review", "", "user", "", startAt, startAt.Add(time.Hour),
+				[]time.Duration{15 * time.Minute, 24 * time.Hour, 15 * time.Minute}, "", "", nil, nil,
+			)
+			require.NoError(t, err)
+
+			event, err := a.storage.FindByID(ctx, id)
+			require.NoError(t, err)
+			require.Len(t, event.Reminders, 2)
+			require.Equal(t, startAt.Add(-24*time.Hour), event.Reminders[0].NotifyAt)
+			require.Equal(t, startAt.Add(-15*time.Minute), event.Reminders[1].NotifyAt)
+			for _, reminder := range event.Reminders {
+				require.False(t, reminder.Sent)
+				require.Equal(t, startAt, reminder.OccurrenceAt)
+			}
+		},
+	)
+
+	t.Run(
+		"when event is moved, reschedules reminders", func(t *testing.T) {
+			a := newTestApp()
+			id, err := a.CreateEvent(
+				ctx, "review", "", "user", "", startAt, startAt.Add(time.Hour), []time.Duration{time.Hour}, "", "", nil, nil,
+			)
+			require.NoError(t, err)
+
+			movedAt := startAt.Add(2 * time.Hour)
+			_, err = a.UpdateEvent(
+				ctx, id.String(), 0, "review", "", "user", movedAt, movedAt.Add(time.Hour), []time.Duration{time.Hour}, "",
+				"",
+				nil, nil,
+			)
+			require.NoError(t, err)
+
+			event, err := a.storage.FindByID(ctx, id)
+			require.NoError(t, err)
+			require.Len(t, event.Reminders, 1)
+			require.Equal(t, movedAt.Add(-time.Hour), event.Reminders[0].NotifyAt)
+		},
+	)
+
+	t.Run(
+		"when reminder offset is negative, returns invalid event error", func(t *testing.T) {
+			a := newTestApp()
+			_, err := a.CreateEvent(
+				ctx, "review", "", "user", "", startAt, startAt.Add(time.Hour), []time.Duration{-time.Minute}, "", "", nil, nil,
+			)
+			require.True(t, errors.Is(err, ErrInvalidEvent))
+		},
+	)
+}
+
+func TestApp_CreateEvent_Overlap(t *testing.T) {
+	ctx := context.Background()
+	a := newTestApp()
+	day := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+
+	_, err := a.CreateEvent(
+		ctx, "workday", "", "user", "", day.Add(9*time.Hour), day.Add(18*time.Hour), nil, "", "", nil, nil,
+	)
+	require.NoError(t, err)
+
+	t.Run(
+		"when event is inside an existing event, returns date busy error", func(t *testing.T) {
+			_, err := a.CreateEvent(
+				ctx, "meeting", "", "user", "", day.Add(10*time.Hour), day.Add(11*time.Hour), nil, "", "", nil, nil,
+			)
+			require.True(t, errors.Is(err, storage.ErrDateBusy))
+		},
+	)
+
+	t.Run(
+		"when event starts at the end of an existing event, creates it", func(t *testing.T) {
+			_, err := a.CreateEvent(
+				ctx, "dinner", "", "user", "", day.Add(18*time.Hour), day.Add(19*time.Hour), nil, "", "", nil, nil,
+			)
+			require.NoError(t, err)
+		},
+	)
+
+	t.Run(
+		"when listing a period inside an existing event, returns it", func(t *testing.T) {
+			events, err := a.GetEventList(ctx, "user", day.Add(12*time.Hour), day.Add(13*time.Hour))
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			require.Equal(t, "workday", events[0].Title)
+		},
+	)
+}
+
+func TestApp_UpdateEvent_Version(t *testing.T) {
+	ctx := context.Background()
+	startAt := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+	a := newTestApp()
+	id, err := a.CreateEvent(ctx, "review", "", "user", "", startAt, startAt.Add(time.Hour), nil, "", "", nil, nil)
+	require.NoError(t, err)
+
+	update := func(version int64, title string) (int64, error) {
+		return a.UpdateEvent(
+			ctx, id.String(), version, title, "", "user", startAt, startAt.Add(time.Hour), nil, "", "", nil, nil,
+		)
+	}
+
+	t.Run(
+		"when version matches, updates event and returns next version", func(t *testing.T) {
+			version, err := update(1, "first")
+			require.NoError(t, err)
+			require.Equal(t, int64(2), version)
+		},
+	)
+
+	t.Run(
+		"when version is stale, returns version mismatch error", func(t *testing.T) {
+			_, err := update(1, "second")
+			require.ErrorIs(t, err, storage.ErrVersionMismatch)
+			require.ErrorIs(t, a.DeleteEvent(ctx, "user", id.String(), 1), storage.ErrVersionMismatch)
+
+			event, err := a.GetEvent(ctx, "user", id.String())
+			require.NoError(t, err)
+			require.Equal(t, "first", event.Title)
+		},
+	)
+
+	t.Run(
+		"when version is zero, updates the latest version", func(t *testing.T) {
+			version, err := update(0, "third")
+			require.NoError(t, err)
+			require.Equal(t, int64(3), version)
+			require.NoError(t, a.DeleteEvent(ctx, "user", id.String(), 0))
+		},
+	)
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// setAttendees invites the users, attendees who stay invited keep their status.
+func setAttendees(event *storage.Event, userIDs []string) error {
+	attendees := make([]storage.Attendee, 0, len(userIDs))
+	seen := make(map[storage.UserID]struct{}, len(userIDs))
+	for _, id := range userIDs {
+		userID := storage.UserID(id)
+		switch {
+		case userID == "":
+			return fmt.Errorf("%w: attendee id is required", ErrInvalidEvent)
+		case userID == event.OwnerID:
+			return fmt.Errorf("%w: owner can't be an attendee", ErrInvalidEvent)
+		}
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+
+		attendee, ok := event.Attendee(userID)
+		if !ok {
+			attendee = storage.Attendee{UserID: userID, Status: storage.StatusNeedsAction}
+		}
+		attendees = append(attendees, attendee)
+	}
+
+	event.Attendees = attendees
+	return nil
+}
+
+// RespondToInvitation sets the participation status of the invited user and returns the new event version.
+// A declined event does not take the user's time, accepting it again may fail with storage.ErrDateBusy.
+func (a *App) RespondToInvitation(ctx context.Context, eventID, userID, status string) (int64, error) {
+	parsed, err := storage.ParseAttendeeStatus(status)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidEvent, err)
+	}
+
+	event, err := a.storage.FindCurrentByID(ctx, storage.EventID(eventID))
+	if err != nil {
+		return 0, err
+	}
+
+	attendees := make([]storage.Attendee, len(event.Attendees))
+	copy(attendees, event.Attendees)
+	invited := false
+	for i := range attendees {
+		if attendees[i].UserID == storage.UserID(userID) {
+			attendees[i].Status = parsed
+			invited = true
+		}
+	}
+	if !invited {
+		return 0, fmt.Errorf("user %s is not invited to event %s: %w", userID, eventID, storage.ErrNotFound)
+	}
+	before := *event
+	event.Attendees = attendees
+
+	if err := a.save(ctx, event, newChange(storage.ChangeUpdate, userID, &before)); err != nil {
+		return 0, err
+	}
+
+	return event.Version, nil
+}
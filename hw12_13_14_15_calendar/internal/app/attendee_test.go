@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_Attendees(t *testing.T) {
+	ctx := context.Background()
+	startAt := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+	a := newTestApp()
+
+	create := func(ownerID string, attendees ...string) (storage.EventID, error) {
+		return a.CreateEvent(ctx, "meeting", "", ownerID, "", startAt, startAt.Add(time.Hour), nil, "", "", nil, attendees)
+	}
+
+	t.Run(
+		"when owner is invited, returns invalid event error", func(t *testing.T) {
+			_, err := create("owner", "owner")
+			require.ErrorIs(t, err, ErrInvalidEvent)
+		},
+	)
+
+	id, err := create("owner", "alice", "bob", "alice")
+	require.NoError(t, err)
+
+	t.Run(
+		"when attendees are invited, they need action", func(t *testing.T) {
+			event, err := a.GetEvent(ctx, "owner", id.String())
+			require.NoError(t, err)
+			require.Equal(t, []storage.Attendee{
+				{UserID: "alice", Status: storage.StatusNeedsAction},
+				{UserID: "bob", Status: storage.StatusNeedsAction},
+			}, event.Attendees)
+		},
+	)
+
+	t.Run(
+		"when attendee is busy, returns date busy error", func(t *testing.T) {
+			_, err := create("alice")
+			require.ErrorIs(t, err, storage.ErrDateBusy)
+		},
+	)
+
+	t.Run(
+		"when user is not invited, returns not found error", func(t *testing.T) {
+			_, err := a.RespondToInvitation(ctx, id.String(), "carol", "accepted")
+			require.ErrorIs(t, err, storage.ErrNotFound)
+		},
+	)
+
+	t.Run(
+		"when status is unknown, returns invalid event error", func(t *testing.T) {
+			_, err := a.RespondToInvitation(ctx, id.String(), "alice", "maybe")
+			require.ErrorIs(t, err, ErrInvalidEvent)
+		},
+	)
+
+	t.Run(
+		"when attendee declines, frees their time", func(t *testing.T) {
+			version, err := a.RespondToInvitation(ctx, id.String(), "alice", "declined")
+			require.NoError(t, err)
+			require.Equal(t, int64(2), version)
+
+			_, err = create("alice")
+			require.NoError(t, err)
+
+			_, err = a.RespondToInvitation(ctx, id.String(), "alice", "accepted")
+			require.ErrorIs(t, err, storage.ErrDateBusy)
+		},
+	)
+
+	t.Run(
+		"when event is updated, attendees keep their status", func(t *testing.T) {
+			_, err := a.RespondToInvitation(ctx, id.String(), "bob", "tentative")
+			require.NoError(t, err)
+
+			_, err = a.UpdateEvent(
+				ctx, id.String(), 0, "meeting", "", "owner", startAt, startAt.Add(time.Hour), nil, "", "", nil,
+				[]string{"bob", "carol"},
+			)
+			require.NoError(t, err)
+
+			event, err := a.GetEvent(ctx, "owner", id.String())
+			require.NoError(t, err)
+			require.Equal(t, []storage.Attendee{
+				{UserID: "bob", Status: storage.StatusTentative},
+				{UserID: "carol", Status: storage.StatusNeedsAction},
+			}, event.Attendees)
+		},
+	)
+}
@@ -0,0 +1,203 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+var (
+	ErrInvalidCalendar = errors.New("invalid calendar")
+	ErrForbidden       = errors.New("forbidden")
+)
+
+// CreateCalendar creates a calendar of the user and returns its id.
+func (a *App) CreateCalendar(ctx context.Context, userID, name string) (storage.CalendarID, error) {
+	if name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrInvalidCalendar)
+	}
+
+	calendar := &storage.Calendar{
+		ID:      storage.CalendarID(uuid.NewString()),
+		OwnerID: storage.UserID(userID),
+		Name:    name,
+	}
+	if err := a.storage.SaveCalendar(ctx, calendar); err != nil {
+		return "", err
+	}
+	return calendar.ID, nil
+}
+
+// GetCalendars returns the calendars of the user and the ones shared with them,
+// only the owner sees the grants given to other users.
+func (a *App) GetCalendars(ctx context.Context, userID string) ([]storage.Calendar, error) {
+	calendars, err := a.storage.FindCalendarsByUserID(ctx, storage.UserID(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	user := storage.UserID(userID)
+	for i, calendar := range calendars {
+		if calendar.OwnerID != user {
+			calendars[i].Grants = []storage.Grant{{UserID: user, Access: calendar.AccessOf(user)}}
+		}
+	}
+	return calendars, nil
+}
+
+// ShareCalendar grants the access to the calendar, "none" revokes it. Only the owner can share the calendar.
+func (a *App) ShareCalendar(ctx context.Context, userID, calendarID, granteeID, access string) error {
+	parsed, err := storage.ParseAccess(access)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidCalendar, err)
+	}
+
+	calendar, err := a.ownCalendar(ctx, userID, calendarID)
+	if err != nil {
+		return err
+	}
+	if granteeID == "" || storage.UserID(granteeID) == calendar.OwnerID {
+		return fmt.Errorf("%w: grantee must be another user", ErrInvalidCalendar)
+	}
+
+	grants := make([]storage.Grant, 0, len(calendar.Grants)+1)
+	for _, grant := range calendar.Grants {
+		if grant.UserID != storage.UserID(granteeID) {
+			grants = append(grants, grant)
+		}
+	}
+	if parsed != storage.AccessNone {
+		grants = append(grants, storage.Grant{UserID: storage.UserID(granteeID), Access: parsed})
+	}
+	calendar.Grants = grants
+
+	return a.storage.SaveCalendar(ctx, calendar)
+}
+
+// DeleteCalendar deletes the calendar together with its events, only the owner can delete it.
+// The deletion of every event is recorded in its history.
+func (a *App) DeleteCalendar(ctx context.Context, userID, calendarID string) error {
+	calendar, err := a.ownCalendar(ctx, userID, calendarID)
+	if err != nil {
+		return err
+	}
+	events, _, err := a.storage.Search(ctx, storage.SearchFilter{CalendarID: calendar.ID})
+	if err != nil {
+		return err
+	}
+
+	changes := make([]*storage.Change, 0, len(events))
+	for i := range events {
+		change := newChange(storage.ChangeDelete, userID, &events[i])
+		change.EventID = events[i].ID
+		changes = append(changes, change)
+	}
+	return a.storage.DeleteCalendarWithChanges(ctx, calendar.ID, changes)
+}
+
+// ListCalendarEvents returns the occurrences of the calendar events overlapping [from, to).
+// Users with free/busy access get only the time of the events.
+func (a *App) ListCalendarEvents(
+	ctx context.Context,
+	userID, calendarID string,
+	from, to time.Time,
+) ([]storage.Event, error) {
+	calendar, err := a.storage.FindCalendarByID(ctx, storage.CalendarID(calendarID))
+	if err != nil {
+		return nil, err
+	}
+	access := calendar.AccessOf(storage.UserID(userID))
+	if !access.Allows(storage.AccessFreeBusy) {
+		return nil, fmt.Errorf("%w: calendar %s is not shared with user %s", ErrForbidden, calendarID, userID)
+	}
+
+	events, err := a.storage.FindAllByCalendarIDAndPeriod(ctx, calendar.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	res, err := expandOccurrences(events, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if !access.Allows(storage.AccessRead) {
+		for i := range res {
+			res[i] = busyOnly(res[i])
+		}
+	}
+	return res, nil
+}
+
+func (a *App) ownCalendar(ctx context.Context, userID, calendarID string) (*storage.Calendar, error) {
+	calendar, err := a.storage.FindCalendarByID(ctx, storage.CalendarID(calendarID))
+	if err != nil {
+		return nil, err
+	}
+	if calendar.OwnerID != storage.UserID(userID) {
+		return nil, fmt.Errorf("%w: calendar %s is owned by another user", ErrForbidden, calendarID)
+	}
+	return calendar, nil
+}
+
+// writableCalendar returns the calendar the user can add events to.
+func (a *App) writableCalendar(ctx context.Context, userID, calendarID string) (*storage.Calendar, error) {
+	calendar, err := a.storage.FindCalendarByID(ctx, storage.CalendarID(calendarID))
+	if err != nil {
+		return nil, err
+	}
+	if !calendar.AccessOf(storage.UserID(userID)).Allows(storage.AccessWrite) {
+		return nil, fmt.Errorf("%w: user %s can't write to calendar %s", ErrForbidden, userID, calendarID)
+	}
+	return calendar, nil
+}
+
+// accessTo returns the access of the user to the event: the owner may write, attendees may read
+// and other users have the access granted to the calendar of the event.
+func (a *App) accessTo(ctx context.Context, userID string, event *storage.Event) (storage.Access, error) {
+	if event.OwnerID == storage.UserID(userID) {
+		return storage.AccessWrite, nil
+	}
+
+	access := storage.AccessNone
+	if _, ok := event.Attendee(storage.UserID(userID)); ok {
+		access = storage.AccessRead
+	}
+	if event.CalendarID == "" {
+		return access, nil
+	}
+
+	calendar, err := a.storage.FindCalendarByID(ctx, event.CalendarID)
+	if err != nil {
+		return storage.AccessNone, err
+	}
+	if granted := calendar.AccessOf(storage.UserID(userID)); granted.Allows(access) {
+		access = granted
+	}
+	return access, nil
+}
+
+// checkAccess returns ErrForbidden if the user has no required access to the event.
+func (a *App) checkAccess(ctx context.Context, userID string, event *storage.Event, required storage.Access) error {
+	access, err := a.accessTo(ctx, userID, event)
+	if err != nil {
+		return err
+	}
+	if !access.Allows(required) {
+		return fmt.Errorf("%w: user %s has no %s access to event %s", ErrForbidden, userID, required, event.ID)
+	}
+	return nil
+}
+
+// busyOnly returns the time of the event without its details.
+func busyOnly(event storage.Event) storage.Event {
+	return storage.Event{
+		StartAt:    event.StartAt,
+		EndAt:      event.EndAt,
+		OwnerID:    event.OwnerID,
+		CalendarID: event.CalendarID,
+	}
+}
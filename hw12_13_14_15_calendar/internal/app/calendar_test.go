@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_Calendars(t *testing.T) {
+	ctx := context.Background()
+	startAt := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+	from, to := startAt.Truncate(24*time.Hour), startAt.Truncate(24*time.Hour).Add(24*time.Hour)
+	a := newTestApp()
+
+	work, err := a.CreateCalendar(ctx, "alice", "work")
+	require.NoError(t, err)
+	id, err := a.CreateEvent(
+		ctx, "review", "secret", "alice", work.String(), startAt, startAt.Add(time.Hour), nil, "", "", nil, nil,
+	)
+	require.NoError(t, err)
+
+	create := func(userID string, startAt time.Time) (storage.EventID, error) {
+		return a.CreateEvent(ctx, "sync", "", userID, work.String(), startAt, startAt.Add(time.Hour), nil, "", "", nil, nil)
+	}
+	update := func(userID string) error {
+		_, err := a.UpdateEvent(
+			ctx, id.String(), 0, "updated", "", userID, startAt, startAt.Add(time.Hour), nil, "", "", nil, nil,
+		)
+		return err
+	}
+
+	t.Run(
+		"when calendar name is empty, returns invalid calendar error", func(t *testing.T) {
+			_, err := a.CreateCalendar(ctx, "alice", "")
+			require.ErrorIs(t, err, ErrInvalidCalendar)
+		},
+	)
+
+	t.Run(
+		"when calendar is not shared, forbids every operation", func(t *testing.T) {
+			_, err := a.ListCalendarEvents(ctx, "bob", work.String(), from, to)
+			require.ErrorIs(t, err, ErrForbidden)
+			_, err = a.GetEvent(ctx, "bob", id.String())
+			require.ErrorIs(t, err, ErrForbidden)
+			_, err = create("bob", startAt.Add(2*time.Hour))
+			require.ErrorIs(t, err, ErrForbidden)
+			require.ErrorIs(t, update("bob"), ErrForbidden)
+			require.ErrorIs(t, a.DeleteEvent(ctx, "bob", id.String(), 0), ErrForbidden)
+			require.ErrorIs(t, a.ShareCalendar(ctx, "bob", work.String(), "bob", "write"), ErrForbidden)
+			require.ErrorIs(t, a.DeleteCalendar(ctx, "bob", work.String()), ErrForbidden)
+		},
+	)
+
+	t.Run(
+		"when free/busy access is granted, returns only the time of events", func(t *testing.T) {
+			require.NoError(t, a.ShareCalendar(ctx, "alice", work.String(), "bob", "free-busy"))
+
+			events, err := a.ListCalendarEvents(ctx, "bob", work.String(), from, to)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			require.Empty(t, events[0].Title)
+			require.Empty(t, events[0].Description)
+			require.Equal(t, startAt, events[0].StartAt)
+
+			event, err := a.GetEvent(ctx, "bob", id.String())
+			require.NoError(t, err)
+			require.Empty(t, event.Title)
+			require.ErrorIs(t, update("bob"), ErrForbidden)
+		},
+	)
+
+	t.Run(
+		"when read access is granted, returns events but forbids writes", func(t *testing.T) {
+			require.NoError(t, a.ShareCalendar(ctx, "alice", work.String(), "bob", "read"))
+
+			events, err := a.ListCalendarEvents(ctx, "bob", work.String(), from, to)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			require.Equal(t, "review", events[0].Title)
+
+			calendars, err := a.GetCalendars(ctx, "bob")
+			require.NoError(t, err)
+			require.Len(t, calendars, 1)
+			require.Equal(t, storage.AccessRead, calendars[0].AccessOf("bob"))
+
+			_, err = create("bob", startAt.Add(2*time.Hour))
+			require.ErrorIs(t, err, ErrForbidden)
+		},
+	)
+
+	t.Run(
+		"when write access is granted, creates events owned by the calendar owner", func(t *testing.T) {
+			require.NoError(t, a.ShareCalendar(ctx, "alice", work.String(), "bob", "write"))
+
+			created, err := create("bob", startAt.Add(2*time.Hour))
+			require.NoError(t, err)
+			event, err := a.GetEvent(ctx, "bob", created.String())
+			require.NoError(t, err)
+			require.Equal(t, storage.UserID("alice"), event.OwnerID)
+			require.Equal(t, work, event.CalendarID)
+
+			require.NoError(t, update("bob"))
+			event, err = a.GetEvent(ctx, "alice", id.String())
+			require.NoError(t, err)
+			require.Equal(t, storage.UserID("alice"), event.OwnerID, "update must keep the owner")
+
+			_, err = create("bob", startAt)
+			require.ErrorIs(t, err, storage.ErrDateBusy, "owner's time is checked")
+			require.NoError(t, a.DeleteEvent(ctx, "bob", created.String(), 0))
+		},
+	)
+
+	t.Run(
+		"when access is revoked, forbids reads again", func(t *testing.T) {
+			require.NoError(t, a.ShareCalendar(ctx, "alice", work.String(), "bob", "none"))
+			_, err := a.ListCalendarEvents(ctx, "bob", work.String(), from, to)
+			require.ErrorIs(t, err, ErrForbidden)
+
+			require.ErrorIs(t, a.ShareCalendar(ctx, "alice", work.String(), "bob", "admin"), ErrInvalidCalendar)
+		},
+	)
+
+	t.Run(
+		"when calendar is deleted, deletes its events", func(t *testing.T) {
+			require.NoError(t, a.DeleteCalendar(ctx, "alice", work.String()))
+			_, err := a.GetEvent(ctx, "alice", id.String())
+			require.ErrorIs(t, err, storage.ErrNotFound)
+		},
+	)
+}
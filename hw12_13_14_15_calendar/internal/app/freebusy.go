@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// maxFreeBusyUsers limits the number of users of a single free/busy query.
+const maxFreeBusyUsers = 100
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Interval is a half-open period [StartAt, EndAt).
+type Interval struct {
+	StartAt time.Time
+	EndAt   time.Time
+}
+
+// FreeBusy returns the merged busy intervals of every user within [from, to), ordered by start.
+// Events are not exposed, declined invitations don't take time. The time of every event counts,
+// even of a private one, calendar grants limit only the access to the details of the events.
+func (a *App) FreeBusy(ctx context.Context, userIDs []string, from, to time.Time) (map[string][]Interval, error) {
+	if len(userIDs) == 0 || len(userIDs) > maxFreeBusyUsers {
+		return nil, fmt.Errorf("%w: from 1 to %d users are required", ErrInvalidQuery, maxFreeBusyUsers)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+
+	res := make(map[string][]Interval, len(userIDs))
+	users := make([]storage.UserID, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id == "" {
+			return nil, fmt.Errorf("%w: user id is required", ErrInvalidQuery)
+		}
+		if _, ok := res[id]; !ok {
+			res[id] = []Interval{}
+			users = append(users, storage.UserID(id))
+		}
+	}
+
+	busy, err := a.busy(ctx, users, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for userID, intervals := range busy {
+		res[string(userID)] = mergeIntervals(intervals)
+	}
+
+	return res, nil
+}
+
+// busy returns the unmerged busy intervals of the users clipped to [from, to).
+func (a *App) busy(
+	ctx context.Context,
+	users []storage.UserID,
+	from, to time.Time,
+) (map[storage.UserID][]Interval, error) {
+	events, err := a.storage.FindBusyByUserIDsAndPeriod(ctx, users, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := make(map[storage.UserID]struct{}, len(users))
+	for _, userID := range users {
+		requested[userID] = struct{}{}
+	}
+
+	res := make(map[storage.UserID][]Interval, len(users))
+	for _, event := range events {
+		occurrences, err := event.Occurrences(from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, participant := range event.Participants() {
+			if _, ok := requested[participant]; !ok {
+				continue
+			}
+			for _, occurrence := range occurrences {
+				res[participant] = append(res[participant], clip(Interval{occurrence.StartAt, occurrence.EndAt}, from, to))
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func clip(interval Interval, from, to time.Time) Interval {
+	if interval.StartAt.Before(from) {
+		interval.StartAt = from
+	}
+	if interval.EndAt.After(to) {
+		interval.EndAt = to
+	}
+	return interval
+}
+
+// mergeIntervals sorts the intervals and joins the overlapping and adjacent ones.
+func mergeIntervals(intervals []Interval) []Interval {
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].StartAt.Before(intervals[j].StartAt)
+	})
+
+	res := intervals[:0]
+	for _, interval := range intervals {
+		last := len(res) - 1
+		if last >= 0 && !interval.StartAt.After(res[last].EndAt) {
+			if interval.EndAt.After(res[last].EndAt) {
+				res[last].EndAt = interval.EndAt
+			}
+			continue
+		}
+		res = append(res, interval)
+	}
+	return res
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_FreeBusy(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time {
+		return day.Add(time.Duration(hour) * time.Hour)
+	}
+	a := newTestApp()
+
+	create := func(ownerID string, from, to time.Time, rrule string, attendees ...string) string {
+		id, err := a.CreateEvent(ctx, "event", "", ownerID, "", from, to, nil, rrule, "", nil, attendees)
+		require.NoError(t, err)
+		return id.String()
+	}
+	create("alice", at(9), at(10), "")
+	create("alice", at(10), at(11), "", "bob")
+	create("alice", at(14), at(15), "FREQ=DAILY;COUNT=3")
+	create("bob", at(-1), at(1), "")
+	create("carol", at(12), at(13), "", "bob")
+	declined := create("carol", at(16), at(17), "", "bob")
+	_, err := a.RespondToInvitation(ctx, declined, "bob", "declined")
+	require.NoError(t, err)
+
+	t.Run(
+		"when users are busy, returns merged and clipped intervals without declined events", func(t *testing.T) {
+			busy, err := a.FreeBusy(ctx, []string{"alice", "bob", "dave"}, day, day.Add(24*time.Hour))
+			require.NoError(t, err)
+			require.Equal(t, map[string][]Interval{
+				"alice": {{at(9), at(11)}, {at(14), at(15)}},
+				"bob":   {{at(0), at(1)}, {at(10), at(11)}, {at(12), at(13)}},
+				"dave":  {},
+			}, busy)
+		},
+	)
+
+	t.Run(
+		"when series spans the period, returns every occurrence", func(t *testing.T) {
+			busy, err := a.FreeBusy(ctx, []string{"alice"}, day.Add(24*time.Hour), day.Add(72*time.Hour))
+			require.NoError(t, err)
+			require.Equal(t, []Interval{{at(38), at(39)}, {at(62), at(63)}}, busy["alice"])
+		},
+	)
+
+	t.Run(
+		"when event is private, still returns its time", func(t *testing.T) {
+			next := day.Add(24 * time.Hour)
+			create("dave", next.Add(9*time.Hour), next.Add(18*time.Hour), "")
+
+			busy, err := a.FreeBusy(ctx, []string{"dave"}, next, next.Add(24*time.Hour))
+			require.NoError(t, err)
+			require.Equal(t, []Interval{{next.Add(9 * time.Hour), next.Add(18 * time.Hour)}}, busy["dave"])
+		},
+	)
+
+	t.Run(
+		"when query is invalid, returns invalid query error", func(t *testing.T) {
+			_, err := a.FreeBusy(ctx, nil, day, day.Add(time.Hour))
+			require.ErrorIs(t, err, ErrInvalidQuery)
+
+			_, err = a.FreeBusy(ctx, []string{"alice"}, day, day)
+			require.ErrorIs(t, err, ErrInvalidQuery)
+
+			_, err = a.FreeBusy(ctx, []string{""}, day, day.Add(time.Hour))
+			require.ErrorIs(t, err, ErrInvalidQuery)
+		},
+	)
+}
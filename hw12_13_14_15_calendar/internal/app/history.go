@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// GetEventHistory returns the changes of the event from the oldest one, including the changes of a deleted event.
+// The user must have read access to the latest state of the event.
+func (a *App) GetEventHistory(ctx context.Context, userID, eventID string) ([]storage.Change, error) {
+	return a.history(ctx, userID, eventID, storage.AccessRead)
+}
+
+// RestoreEvent brings the event back to the state recorded by the change and returns its new version,
+// a deleted event is created again. The user must have write access to the latest state of the event.
+func (a *App) RestoreEvent(ctx context.Context, userID, eventID string, changeID int64) (int64, error) {
+	changes, err := a.history(ctx, userID, eventID, storage.AccessWrite)
+	if err != nil {
+		return 0, err
+	}
+
+	var snapshot *storage.Event
+	for _, change := range changes {
+		if change.ID == changeID {
+			snapshot = change.Snapshot()
+		}
+	}
+	if snapshot == nil {
+		return 0, fmt.Errorf("change %d of event %s: %w", changeID, eventID, storage.ErrNotFound)
+	}
+
+	event := *snapshot
+	current, err := a.storage.FindCurrentByID(ctx, event.ID)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		current, event.Version = nil, 0
+		if event.CalendarID != "" {
+			if _, err := a.storage.FindCalendarByID(ctx, event.CalendarID); err != nil {
+				return 0, err
+			}
+		}
+	case err != nil:
+		return 0, err
+	default:
+		event.Version = current.Version
+	}
+
+	if event.Reminders, err = storage.ScheduleReminders(event, snapshot.Offsets(), time.Now()); err != nil {
+		return 0, err
+	}
+	if err := a.save(ctx, &event, newChange(storage.ChangeRestore, userID, current)); err != nil {
+		return 0, err
+	}
+	return event.Version, nil
+}
+
+// history returns the changes of the event if the user has the access to its latest state.
+func (a *App) history(
+	ctx context.Context,
+	userID, eventID string,
+	required storage.Access,
+) ([]storage.Change, error) {
+	changes, err := a.storage.FindChangesByEventID(ctx, storage.EventID(eventID))
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("history of event %s: %w", eventID, storage.ErrNotFound)
+	}
+
+	if err := a.checkAccess(ctx, userID, changes[len(changes)-1].Snapshot(), required); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// newChange returns the change of the event history made by the user, the storage fills the event id
+// and the state after the change when it writes the change together with the event.
+func newChange(kind storage.ChangeKind, userID string, before *storage.Event) *storage.Change {
+	return &storage.Change{Kind: kind, ActorID: storage.UserID(userID), Before: before}
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_EventHistory(t *testing.T) {
+	ctx := context.Background()
+	startAt := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+	a := newTestApp()
+
+	id, err := a.CreateEvent(
+		ctx, "draft", "", "alice", "", startAt, startAt.Add(time.Hour), []time.Duration{time.Hour},
+		"", "", nil, []string{"bob"},
+	)
+	require.NoError(t, err)
+	_, err = a.UpdateEvent(
+		ctx, id.String(), 0, "final", "", "alice", startAt, startAt.Add(time.Hour), nil, "", "", nil, []string{"bob"},
+	)
+	require.NoError(t, err)
+	_, err = a.RespondToInvitation(ctx, id.String(), "bob", "accepted")
+	require.NoError(t, err)
+	require.NoError(t, a.DeleteEvent(ctx, "alice", id.String(), 0))
+
+	t.Run(
+		"when event is deleted, returns every change with the actor and snapshots", func(t *testing.T) {
+			changes, err := a.GetEventHistory(ctx, "alice", id.String())
+			require.NoError(t, err)
+			require.Len(t, changes, 4)
+
+			kinds := make([]storage.ChangeKind, 0, len(changes))
+			for _, change := range changes {
+				kinds = append(kinds, change.Kind)
+				require.False(t, change.At.IsZero())
+			}
+			require.Equal(t, []storage.ChangeKind{
+				storage.ChangeCreate, storage.ChangeUpdate, storage.ChangeUpdate, storage.ChangeDelete,
+			}, kinds)
+
+			require.Nil(t, changes[0].Before)
+			require.Equal(t, "draft", changes[0].After.Title)
+			require.Equal(t, "draft", changes[1].Before.Title)
+			require.Equal(t, "final", changes[1].After.Title)
+			require.Equal(t, storage.UserID("bob"), changes[2].ActorID)
+			require.Equal(t, storage.StatusAccepted, changes[2].After.Attendees[0].Status)
+			require.Equal(t, "final", changes[3].Before.Title)
+			require.Nil(t, changes[3].After)
+		},
+	)
+
+	t.Run(
+		"when user has no access to the event, forbids the history", func(t *testing.T) {
+			_, err := a.GetEventHistory(ctx, "carol", id.String())
+			require.ErrorIs(t, err, ErrForbidden)
+
+			_, err = a.GetEventHistory(ctx, "bob", id.String())
+			require.NoError(t, err)
+			_, err = a.RestoreEvent(ctx, "bob", id.String(), 1)
+			require.ErrorIs(t, err, ErrForbidden)
+		},
+	)
+
+	t.Run(
+		"when deleted event is restored, creates it again with the old state", func(t *testing.T) {
+			changes, err := a.GetEventHistory(ctx, "alice", id.String())
+			require.NoError(t, err)
+
+			version, err := a.RestoreEvent(ctx, "alice", id.String(), changes[0].ID)
+			require.NoError(t, err)
+			require.Equal(t, int64(1), version)
+
+			event, err := a.GetEvent(ctx, "alice", id.String())
+			require.NoError(t, err)
+			require.Equal(t, "draft", event.Title)
+			require.Equal(t, []time.Duration{time.Hour}, event.Offsets())
+
+			changes, err = a.GetEventHistory(ctx, "alice", id.String())
+			require.NoError(t, err)
+			require.Len(t, changes, 5)
+			require.Equal(t, storage.ChangeRestore, changes[4].Kind)
+			require.Nil(t, changes[4].Before)
+		},
+	)
+
+	t.Run(
+		"when existing event is restored, updates it", func(t *testing.T) {
+			changes, err := a.GetEventHistory(ctx, "alice", id.String())
+			require.NoError(t, err)
+
+			version, err := a.RestoreEvent(ctx, "alice", id.String(), changes[3].ID)
+			require.NoError(t, err)
+			require.Equal(t, int64(2), version)
+
+			event, err := a.GetEvent(ctx, "alice", id.String())
+			require.NoError(t, err)
+			require.Equal(t, "final", event.Title)
+		},
+	)
+
+	t.Run(
+		"when change or event is unknown, returns not found", func(t *testing.T) {
+			_, err := a.RestoreEvent(ctx, "alice", id.String(), 100)
+			require.ErrorIs(t, err, storage.ErrNotFound)
+			_, err = a.GetEventHistory(ctx, "alice", "unknown")
+			require.ErrorIs(t, err, storage.ErrNotFound)
+		},
+	)
+}
+
+func TestApp_DeleteCalendar_History(t *testing.T) {
+	ctx := context.Background()
+	startAt := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+	a := newTestApp()
+
+	work, err := a.CreateCalendar(ctx, "alice", "work")
+	require.NoError(t, err)
+	id, err := a.CreateEvent(
+		ctx, "review", "", "alice", work.String(), startAt, startAt.Add(time.Hour), nil, "", "", nil, nil,
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, a.DeleteCalendar(ctx, "alice", work.String()))
+
+	changes, err := a.GetEventHistory(ctx, "alice", id.String())
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	require.Equal(t, storage.ChangeDelete, changes[1].Kind)
+
+	_, err = a.RestoreEvent(ctx, "alice", id.String(), changes[0].ID)
+	require.ErrorIs(t, err, storage.ErrNotFound, "the calendar of the event is deleted")
+}
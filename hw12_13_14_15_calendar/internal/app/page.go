@@ -0,0 +1,226 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+	// exportPageSize is the number of events read from the storage at once while exporting.
+	exportPageSize = 500
+	// seriesPageSize is the number of series read from the storage at once while listing a page.
+	seriesPageSize = 100
+)
+
+// pageToken is the position after the last event of the page, it is opaque to clients.
+type pageToken struct {
+	StartAt time.Time       `json:"s"`
+	ID      storage.EventID `json:"i"`
+}
+
+func encodePageToken(cursor storage.Cursor) string {
+	data, _ := json.Marshal(pageToken{StartAt: cursor.StartAt, ID: cursor.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(token string) (storage.Cursor, error) {
+	if token == "" {
+		return storage.Cursor{}, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return storage.Cursor{}, fmt.Errorf("%w: malformed page token", ErrInvalidQuery)
+	}
+	var decoded pageToken
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.ID == "" {
+		return storage.Cursor{}, fmt.Errorf("%w: malformed page token", ErrInvalidQuery)
+	}
+	return storage.Cursor{StartAt: decoded.StartAt, ID: decoded.ID}, nil
+}
+
+// ListEventsPage returns a page of the occurrences of the events and the series of the user which overlap
+// [from, to) ordered by start and id, and the token of the next page, empty for the last page. Zero from and to
+// mean an unbounded period, occurrences of an endless series are listed page by page like single events.
+func (a *App) ListEventsPage(
+	ctx context.Context,
+	userID string,
+	from, to time.Time,
+	token string,
+	pageSize int,
+) ([]storage.Event, string, error) {
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return nil, "", fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+	if pageSize < 0 || pageSize > maxPageSize {
+		return nil, "", fmt.Errorf("%w: page size must be from 1 to %d", ErrInvalidQuery, maxPageSize)
+	}
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	after, err := decodePageToken(token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// one more occurrence tells whether there is a next page
+	limit := pageSize + 1
+	res, err := a.nextSingleEvents(ctx, storage.UserID(userID), from, to, after, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	if res, err = a.mergeNextOccurrences(ctx, storage.UserID(userID), from, to, after, limit, res); err != nil {
+		return nil, "", err
+	}
+
+	if len(res) <= pageSize {
+		return res, "", nil
+	}
+
+	res = res[:pageSize]
+	return res, encodePageToken(storage.CursorOf(res[pageSize-1])), nil
+}
+
+// nextSingleEvents returns up to limit events of the user which overlap [from, to) and follow the cursor,
+// the series are skipped.
+func (a *App) nextSingleEvents(
+	ctx context.Context,
+	userID storage.UserID,
+	from, to time.Time,
+	after storage.Cursor,
+	limit int,
+) ([]storage.Event, error) {
+	res := make([]storage.Event, 0, limit)
+	for {
+		events, err := a.storage.FindPageByUserIDAndPeriod(ctx, userID, from, to, after, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			if event.RRule != "" {
+				continue
+			}
+			res = append(res, event)
+			if len(res) == limit {
+				return res, nil
+			}
+		}
+		if len(events) < limit {
+			return res, nil
+		}
+		after = storage.CursorOf(events[len(events)-1])
+	}
+}
+
+// mergeNextOccurrences merges the occurrences of the series of the user which overlap [from, to) and follow
+// the cursor into the sorted events and returns the first limit of them. The series are read page by page
+// in the order of start and only while they may start before the last kept occurrence, so neither all
+// the series of the user nor more than limit occurrences of each of them are held at once.
+func (a *App) mergeNextOccurrences(
+	ctx context.Context,
+	userID storage.UserID,
+	from, to time.Time,
+	after storage.Cursor,
+	limit int,
+	res []storage.Event,
+) ([]storage.Event, error) {
+	// the series without occurrences after the cursor are skipped by the storage
+	seriesFrom := from
+	if !after.IsZero() && after.StartAt.After(seriesFrom) {
+		seriesFrom = after.StartAt
+	}
+
+	var seriesAfter storage.Cursor
+	for {
+		series, err := a.storage.FindSeriesPageByUserIDAndPeriod(ctx, userID, seriesFrom, to, seriesAfter, seriesPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range series {
+			// every occurrence of the series follows its first one, so it can't get into a full page
+			if len(res) == limit && storage.CursorOf(res[limit-1]).Before(event) {
+				return res, nil
+			}
+			occurrences, err := nextOccurrences(event, from, to, after, limit)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, occurrences...)
+			sort.Slice(res, func(i, j int) bool {
+				return storage.CursorOf(res[i]).Before(res[j])
+			})
+			if len(res) > limit {
+				res = res[:limit]
+			}
+		}
+		if len(series) < seriesPageSize {
+			return res, nil
+		}
+		seriesAfter = storage.CursorOf(series[len(series)-1])
+	}
+}
+
+// nextOccurrences returns up to limit occurrences of the series which overlap [from, to) and follow the cursor.
+func nextOccurrences(
+	series storage.Event,
+	from, to time.Time,
+	after storage.Cursor,
+	limit int,
+) ([]storage.Event, error) {
+	// an occurrence overlaps [from, to) if it starts after from minus its duration
+	start := series.StartAt.Add(-time.Nanosecond)
+	if bound := from.Add(-series.EndAt.Sub(series.StartAt)); !from.IsZero() && bound.After(start) {
+		start = bound
+	}
+	if !after.IsZero() {
+		// the occurrence starting at the cursor follows it only if the id of the series does
+		bound := after.StartAt
+		if series.ID > after.ID {
+			bound = bound.Add(-time.Nanosecond)
+		}
+		if bound.After(start) {
+			start = bound
+		}
+	}
+	return series.OccurrencesAfter(start, to, limit)
+}
+
+// ExportEvents passes the events and the series of the user which overlap [from, to) to fn in the order
+// of start, reading them from the storage page by page. It stops on the first error of fn.
+// Unlike ListEventsPage the series are not expanded: the export is a copy of the stored events which can be
+// imported again, and an endless series has no last occurrence to stop the stream at.
+func (a *App) ExportEvents(
+	ctx context.Context,
+	userID string,
+	from, to time.Time,
+	fn func(event storage.Event) error,
+) error {
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+
+	var after storage.Cursor
+	for {
+		events, err := a.storage.FindPageByUserIDAndPeriod(ctx, storage.UserID(userID), from, to, after, exportPageSize)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+		if len(events) < exportPageSize {
+			return nil
+		}
+		after = storage.CursorOf(events[len(events)-1])
+	}
+}
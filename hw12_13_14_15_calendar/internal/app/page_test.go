@@ -0,0 +1,186 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_ListEventsPage(t *testing.T) {
+	ctx := context.Background()
+	startAt := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+	a := newTestApp()
+
+	for i := 0; i < 5; i++ {
+		from := startAt.Add(time.Duration(i) * time.Hour)
+		_, err := a.CreateEvent(ctx, "event", "", "alice", "", from, from.Add(time.Hour), nil, "", "", nil, nil)
+		require.NoError(t, err)
+	}
+
+	t.Run(
+		"when pages are followed by tokens, returns every event once in order", func(t *testing.T) {
+			var starts []time.Time
+			token := ""
+			for pages := 0; ; pages++ {
+				require.Less(t, pages, 3)
+
+				events, next, err := a.ListEventsPage(ctx, "alice", time.Time{}, time.Time{}, token, 2)
+				require.NoError(t, err)
+				for _, event := range events {
+					starts = append(starts, event.StartAt)
+				}
+				if next == "" {
+					break
+				}
+				token = next
+			}
+
+			require.Len(t, starts, 5)
+			for i, start := range starts {
+				require.Equal(t, startAt.Add(time.Duration(i)*time.Hour), start)
+			}
+		},
+	)
+
+	t.Run(
+		"when the page is the last one, returns empty token", func(t *testing.T) {
+			events, next, err := a.ListEventsPage(ctx, "alice", startAt.Add(3*time.Hour), time.Time{}, "", 2)
+			require.NoError(t, err)
+			require.Len(t, events, 2)
+			require.Empty(t, next)
+		},
+	)
+
+	t.Run(
+		"when query is invalid, returns invalid query error", func(t *testing.T) {
+			_, _, err := a.ListEventsPage(ctx, "alice", time.Time{}, time.Time{}, "not a token", 0)
+			require.ErrorIs(t, err, ErrInvalidQuery)
+			_, _, err = a.ListEventsPage(ctx, "alice", time.Time{}, time.Time{}, "", maxPageSize+1)
+			require.ErrorIs(t, err, ErrInvalidQuery)
+			_, _, err = a.ListEventsPage(ctx, "alice", startAt, startAt, "", 0)
+			require.ErrorIs(t, err, ErrInvalidQuery)
+		},
+	)
+}
+
+func TestApp_ListEventsPage_Occurrences(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	at := func(days, hour int) time.Time {
+		return day.AddDate(0, 0, days).Add(time.Duration(hour) * time.Hour)
+	}
+	a := newTestApp()
+
+	create := func(userID string, from time.Time, rrule string, exDates ...time.Time) {
+		_, err := a.CreateEvent(ctx, "event", "", userID, "", from, from.Add(30*time.Minute), nil, rrule, "", exDates, nil)
+		require.NoError(t, err)
+	}
+	create("alice", at(0, 10), "")
+	create("alice", at(1, 12), "")
+	create("alice", at(0, 9), "FREQ=DAILY;COUNT=3", at(1, 9))
+	create("bob", at(0, 9), "FREQ=DAILY")
+	// more series than fit into a single page of the storage
+	seriesCount := seriesPageSize + 1
+	for i := 0; i < seriesCount; i++ {
+		create("carol", at(0, 9).Add(time.Duration(i)*30*time.Minute), "FREQ=WEEKLY;COUNT=2")
+	}
+
+	list := func(userID string, from, to time.Time, pageSize int) []time.Time {
+		var starts []time.Time
+		token := ""
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 10)
+
+			events, next, err := a.ListEventsPage(ctx, userID, from, to, token, pageSize)
+			require.NoError(t, err)
+			for _, event := range events {
+				starts = append(starts, event.StartAt)
+			}
+			if next == "" {
+				return starts
+			}
+			token = next
+		}
+	}
+
+	t.Run(
+		"when series is in the period, returns its occurrences between the events", func(t *testing.T) {
+			require.Equal(t, []time.Time{at(0, 9), at(0, 10), at(1, 12), at(2, 9)}, list("alice", time.Time{}, time.Time{}, 1))
+		},
+	)
+
+	t.Run(
+		"when occurrence starts before the period, returns it if it overlaps", func(t *testing.T) {
+			from := at(0, 9).Add(15 * time.Minute)
+			require.Equal(t, []time.Time{at(0, 9), at(0, 10)}, list("alice", from, at(1, 0), 1))
+		},
+	)
+
+	t.Run(
+		"when series is endless, returns its occurrences page by page", func(t *testing.T) {
+			events, next, err := a.ListEventsPage(ctx, "bob", time.Time{}, time.Time{}, "", 2)
+			require.NoError(t, err)
+			require.Len(t, events, 2)
+			require.NotEmpty(t, next)
+
+			events, _, err = a.ListEventsPage(ctx, "bob", time.Time{}, time.Time{}, next, 2)
+			require.NoError(t, err)
+			require.Equal(t, at(2, 9), events[0].StartAt)
+			require.Equal(t, at(3, 9), events[1].StartAt)
+		},
+	)
+
+	t.Run(
+		"when series don't fit into a single page of the storage, returns occurrences of all of them", func(t *testing.T) {
+			starts := list("carol", time.Time{}, time.Time{}, 70)
+			require.Len(t, starts, 2*seriesCount)
+			for i := range starts {
+				require.Equal(t, at(i/seriesCount*7, 9).Add(time.Duration(i%seriesCount)*30*time.Minute), starts[i])
+			}
+		},
+	)
+}
+
+func TestApp_ExportEvents(t *testing.T) {
+	ctx := context.Background()
+	startAt := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+	a := newTestApp()
+
+	// more events than fit into a single page of the storage
+	count := exportPageSize + 1
+	for i := 0; i < count; i++ {
+		from := startAt.Add(time.Duration(i) * time.Hour)
+		_, err := a.CreateEvent(ctx, "event", "", "alice", "", from, from.Add(time.Hour), nil, "", "", nil, nil)
+		require.NoError(t, err)
+	}
+
+	t.Run(
+		"when events span several pages, passes all of them in order", func(t *testing.T) {
+			var exported []storage.Event
+			err := a.ExportEvents(ctx, "alice", time.Time{}, time.Time{}, func(event storage.Event) error {
+				exported = append(exported, event)
+				return nil
+			})
+			require.NoError(t, err)
+			require.Len(t, exported, count)
+			require.Equal(t, startAt.Add(time.Duration(count-1)*time.Hour), exported[count-1].StartAt)
+		},
+	)
+
+	t.Run(
+		"when callback fails, stops with its error", func(t *testing.T) {
+			errStop := errors.New("stop")
+			calls := 0
+			err := a.ExportEvents(ctx, "alice", time.Time{}, time.Time{}, func(event storage.Event) error {
+				calls++
+				return errStop
+			})
+			require.ErrorIs(t, err, errStop)
+			require.Equal(t, 1, calls)
+		},
+	)
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+var ErrInvalidTimezone = errors.New("invalid timezone")
+
+// ListDay returns events of the day containing date in the IANA timezone, empty timezone means UTC.
+func (a *App) ListDay(ctx context.Context, ownerID string, date time.Time, timezone string) ([]storage.Event, error) {
+	return a.listPeriod(ctx, ownerID, date, timezone, dayPeriod)
+}
+
+// ListWeek returns events of the ISO week (starting on Monday) containing date in the IANA timezone.
+func (a *App) ListWeek(ctx context.Context, ownerID string, date time.Time, timezone string) ([]storage.Event, error) {
+	return a.listPeriod(ctx, ownerID, date, timezone, weekPeriod)
+}
+
+// ListMonth returns events of the month containing date in the IANA timezone.
+func (a *App) ListMonth(ctx context.Context, ownerID string, date time.Time, timezone string) ([]storage.Event, error) {
+	return a.listPeriod(ctx, ownerID, date, timezone, monthPeriod)
+}
+
+func (a *App) listPeriod(
+	ctx context.Context,
+	ownerID string,
+	date time.Time,
+	timezone string,
+	period func(date time.Time) (from, to time.Time),
+) ([]storage.Event, error) {
+	loc, err := LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := period(date.In(loc))
+	events, err := a.GetEventList(ctx, ownerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range events {
+		events[i].StartAt = events[i].StartAt.In(loc)
+		events[i].EndAt = events[i].EndAt.In(loc)
+	}
+
+	return events, nil
+}
+
+// LoadLocation returns the location by IANA name, empty name means UTC.
+func LoadLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimezone, timezone)
+	}
+	return loc, nil
+}
+
+// dayPeriod returns local midnights around the date, the day lasts 23 or 25 hours on DST transitions.
+func dayPeriod(date time.Time) (from, to time.Time) {
+	y, m, d := date.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, date.Location()), time.Date(y, m, d+1, 0, 0, 0, 0, date.Location())
+}
+
+func weekPeriod(date time.Time) (from, to time.Time) {
+	y, m, d := date.Date()
+	monday := d - (int(date.Weekday())+6)%7
+	return time.Date(y, m, monday, 0, 0, 0, 0, date.Location()), time.Date(y, m, monday+7, 0, 0, 0, 0, date.Location())
+}
+
+func monthPeriod(date time.Time) (from, to time.Time) {
+	y, m, _ := date.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, date.Location()), time.Date(y, m+1, 1, 0, 0, 0, 0, date.Location())
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeriods(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("tzdata is not available")
+	}
+
+	t.Run(
+		"when day has DST transition, lasts 23 hours", func(t *testing.T) {
+			from, to := dayPeriod(time.Date(2022, time.March, 27, 12, 0, 0, 0, berlin))
+			require.Equal(t, time.Date(2022, time.March, 27, 0, 0, 0, 0, berlin), from)
+			require.Equal(t, 23*time.Hour, to.Sub(from))
+		},
+	)
+
+	t.Run(
+		"when date is sunday, week starts on previous monday", func(t *testing.T) {
+			from, to := weekPeriod(time.Date(2022, time.October, 30, 23, 0, 0, 0, berlin))
+			require.Equal(t, time.Date(2022, time.October, 24, 0, 0, 0, 0, berlin), from)
+			require.Equal(t, time.Date(2022, time.October, 31, 0, 0, 0, 0, berlin), to)
+			require.Equal(t, 7*24*time.Hour+time.Hour, to.Sub(from))
+		},
+	)
+
+	t.Run(
+		"when week crosses a year, keeps ISO boundaries", func(t *testing.T) {
+			from, to := weekPeriod(time.Date(2022, time.January, 1, 10, 0, 0, 0, time.UTC))
+			require.Equal(t, time.Date(2021, time.December, 27, 0, 0, 0, 0, time.UTC), from)
+			require.Equal(t, time.Date(2022, time.January, 3, 0, 0, 0, 0, time.UTC), to)
+		},
+	)
+
+	t.Run(
+		"when date is in december, month ends on next year", func(t *testing.T) {
+			from, to := monthPeriod(time.Date(2022, time.December, 31, 10, 0, 0, 0, berlin))
+			require.Equal(t, time.Date(2022, time.December, 1, 0, 0, 0, 0, berlin), from)
+			require.Equal(t, time.Date(2023, time.January, 1, 0, 0, 0, 0, berlin), to)
+		},
+	)
+}
+
+func TestApp_ListDay(t *testing.T) {
+	ctx := context.Background()
+	a := newTestApp()
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata is not available")
+	}
+
+	// 23:30 in New York is the next day in UTC
+	startAt := time.Date(2022, time.January, 10, 23, 30, 0, 0, newYork)
+	_, err = a.CreateEvent(ctx, "late call", "", "user", "", startAt, startAt.Add(15*time.Minute), nil, "", "", nil, nil)
+	require.NoError(t, err)
+
+	date := time.Date(2022, time.January, 10, 12, 0, 0, 0, newYork)
+	events, err := a.ListDay(ctx, "user", date, "America/New_York")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, newYork, events[0].StartAt.Location())
+
+	events, err = a.ListDay(ctx, "user", time.Date(2022, time.January, 10, 12, 0, 0, 0, time.UTC), "")
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	_, err = a.ListWeek(ctx, "user", date, "Mars/Olympus")
+	require.True(t, errors.Is(err, ErrInvalidTimezone))
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/rrule"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// setRecurrence sets the rule and the IANA timezone the series is expanded in, empty timezone means UTC.
+func setRecurrence(event *storage.Event, rule, timezone string, exDates []time.Time) error {
+	event.RRule = ""
+	event.Timezone = ""
+	event.ExDates = nil
+	event.RecurrenceEndAt = time.Time{}
+
+	loc, err := LoadLocation(timezone)
+	if err != nil {
+		return err
+	}
+	event.Timezone = loc.String()
+
+	if rule == "" {
+		if len(exDates) > 0 {
+			return fmt.Errorf("%w: exception dates require a recurrence rule", ErrInvalidEvent)
+		}
+		return nil
+	}
+
+	parsed, err := rrule.Parse(rule)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidEvent, err)
+	}
+
+	event.RRule = parsed.String()
+	event.ExDates = exDates
+	if last, ok := parsed.Last(event.StartAt.In(loc)); ok {
+		event.RecurrenceEndAt = last.Add(event.EndAt.Sub(event.StartAt))
+	}
+
+	return nil
+}
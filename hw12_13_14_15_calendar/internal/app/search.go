@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchQuery filters the events of the user, or of the calendar shared with them if CalendarID is set.
+type SearchQuery struct {
+	Text string
+	// From and To select the events overlapping [From, To), zero means unbounded.
+	From time.Time
+	To   time.Time
+	// HasReminders selects the events with or without reminders, nil means any.
+	HasReminders *bool
+	CalendarID   string
+	// Sort is start, -start or relevance, empty means start.
+	Sort   string
+	Offset int
+	Limit  int
+}
+
+// SearchEvents returns a page of the events matching the query and the number of all matching events.
+// Searching a calendar of another user requires read access to it.
+func (a *App) SearchEvents(ctx context.Context, userID string, query SearchQuery) ([]storage.Event, int, error) {
+	filter := storage.SearchFilter{
+		UserID:       storage.UserID(userID),
+		CalendarID:   storage.CalendarID(query.CalendarID),
+		Text:         query.Text,
+		From:         query.From,
+		To:           query.To,
+		HasReminders: query.HasReminders,
+		Sort:         storage.SearchSort(query.Sort),
+		Offset:       query.Offset,
+		Limit:        query.Limit,
+	}
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = storage.SortStartAsc
+	case storage.SortStartAsc, storage.SortStartDesc, storage.SortRelevance:
+	default:
+		return nil, 0, fmt.Errorf("%w: unknown sort %q", ErrInvalidQuery, query.Sort)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, 0, fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+	if filter.Offset < 0 {
+		return nil, 0, fmt.Errorf("%w: offset must not be negative", ErrInvalidQuery)
+	}
+	if filter.Limit < 0 || filter.Limit > maxSearchLimit {
+		return nil, 0, fmt.Errorf("%w: limit must be from 1 to %d", ErrInvalidQuery, maxSearchLimit)
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultSearchLimit
+	}
+
+	if filter.CalendarID != "" {
+		calendar, err := a.storage.FindCalendarByID(ctx, filter.CalendarID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !calendar.AccessOf(filter.UserID).Allows(storage.AccessRead) {
+			return nil, 0, fmt.Errorf("%w: user %s can't read calendar %s", ErrForbidden, userID, query.CalendarID)
+		}
+	}
+
+	return a.storage.Search(ctx, filter)
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_SearchEvents(t *testing.T) {
+	ctx := context.Background()
+	startAt := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+	a := newTestApp()
+
+	work, err := a.CreateCalendar(ctx, "alice", "work")
+	require.NoError(t, err)
+	for i, title := range []string{"Planning", "Design review", "Review retro"} {
+		_, err := a.CreateEvent(
+			ctx, title, "", "alice", work.String(),
+			startAt.Add(time.Duration(i)*time.Hour), startAt.Add(time.Duration(i+1)*time.Hour),
+			nil, "", "", nil, nil,
+		)
+		require.NoError(t, err)
+	}
+
+	t.Run(
+		"when text matches, returns the page and the total", func(t *testing.T) {
+			events, total, err := a.SearchEvents(ctx, "alice", SearchQuery{Text: "review", Sort: "-start", Limit: 1})
+			require.NoError(t, err)
+			require.Equal(t, 2, total)
+			require.Len(t, events, 1)
+			require.Equal(t, "Review retro", events[0].Title)
+		},
+	)
+
+	t.Run(
+		"when calendar is shared with read access, searches it", func(t *testing.T) {
+			_, _, err := a.SearchEvents(ctx, "bob", SearchQuery{CalendarID: work.String()})
+			require.ErrorIs(t, err, ErrForbidden)
+
+			require.NoError(t, a.ShareCalendar(ctx, "alice", work.String(), "bob", "free-busy"))
+			_, _, err = a.SearchEvents(ctx, "bob", SearchQuery{Text: "review", CalendarID: work.String()})
+			require.ErrorIs(t, err, ErrForbidden)
+
+			require.NoError(t, a.ShareCalendar(ctx, "alice", work.String(), "bob", "read"))
+			events, total, err := a.SearchEvents(ctx, "bob", SearchQuery{CalendarID: work.String()})
+			require.NoError(t, err)
+			require.Equal(t, 3, total)
+			require.Len(t, events, 3)
+
+			_, total, err = a.SearchEvents(ctx, "bob", SearchQuery{})
+			require.NoError(t, err)
+			require.Zero(t, total)
+		},
+	)
+
+	t.Run(
+		"when query is invalid, returns invalid query error", func(t *testing.T) {
+			for name, query := range map[string]SearchQuery{
+				"unknown sort":    {Sort: "title"},
+				"reversed period": {From: startAt, To: startAt},
+				"negative offset": {Offset: -1},
+				"too large limit": {Limit: maxSearchLimit + 1},
+				"negative limit":  {Limit: -1},
+			} {
+				_, _, err := a.SearchEvents(ctx, "alice", query)
+				require.ErrorIs(t, err, ErrInvalidQuery, name)
+			}
+		},
+	)
+}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	defaultSlotLimit = 5
+	maxSlotLimit     = 100
+	// maxSlotWindow limits the search window, so the working hours are expanded for a bounded number of days.
+	maxSlotWindow = 366 * 24 * time.Hour
+)
+
+// Participant is a user of the meeting together with the IANA timezone of their working hours.
+type Participant struct {
+	UserID   string
+	Timezone string
+}
+
+// WorkingHours is a daily wall clock period [Start, End) as offsets from midnight,
+// the zero value means the whole day.
+type WorkingHours struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// SlotQuery describes the meeting to find a time for.
+type SlotQuery struct {
+	Participants []Participant
+	Duration     time.Duration
+	// From and To are the search window.
+	From time.Time
+	To   time.Time
+	// WorkingHours are applied in the timezone of every participant.
+	WorkingHours WorkingHours
+	// Gap is the minimum time between the slot and other meetings of the participants.
+	Gap time.Duration
+	// Limit is the number of slots to return, zero means defaultSlotLimit.
+	Limit int
+}
+
+// ParseWorkingHours parses the working hours in "15:04" format, empty start and end mean the whole day
+// and "24:00" is accepted as the end of the day.
+func ParseWorkingHours(start, end string) (WorkingHours, error) {
+	if start == "" && end == "" {
+		return WorkingHours{}, nil
+	}
+
+	startAt, err := parseClock(start)
+	if err != nil {
+		return WorkingHours{}, fmt.Errorf("%w: working hours start: %s", ErrInvalidQuery, err)
+	}
+	endAt, err := parseClock(end)
+	if err != nil {
+		return WorkingHours{}, fmt.Errorf("%w: working hours end: %s", ErrInvalidQuery, err)
+	}
+
+	return WorkingHours{Start: startAt, End: endAt}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	clock, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q must be in 15:04 format", s)
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+// FindSlots returns the first slots of the query duration in the window where all participants are free,
+// within the working hours of everyone and at least the gap away from their other meetings.
+// Slots of a long free period follow each other, they are alternatives to pick one from.
+// Every meeting of the participants blocks its time, whether the caller may see its details or not.
+func (a *App) FindSlots(ctx context.Context, query SlotQuery) ([]Interval, error) {
+	if err := validateSlotQuery(&query); err != nil {
+		return nil, err
+	}
+
+	users := make([]storage.UserID, 0, len(query.Participants))
+	locations := make([]*time.Location, 0, len(query.Participants))
+	for _, participant := range query.Participants {
+		loc, err := LoadLocation(participant.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("user %s: %w", participant.UserID, err)
+		}
+		users = append(users, storage.UserID(participant.UserID))
+		locations = append(locations, loc)
+	}
+
+	// busy time is extended by the gap, so it is enough to search the window extended by the gap
+	busy, err := a.busy(ctx, users, query.From.Add(-query.Gap), query.To.Add(query.Gap))
+	if err != nil {
+		return nil, err
+	}
+
+	var blocked []Interval
+	for _, intervals := range busy {
+		for _, interval := range intervals {
+			blocked = append(blocked, Interval{interval.StartAt.Add(-query.Gap), interval.EndAt.Add(query.Gap)})
+		}
+	}
+	if query.WorkingHours != (WorkingHours{}) {
+		for _, loc := range locations {
+			blocked = append(blocked, offHours(query.WorkingHours, loc, query.From, query.To)...)
+		}
+	}
+
+	var slots []Interval
+	for _, free := range complement(mergeIntervals(blocked), query.From, query.To) {
+		for start := free.StartAt; !start.Add(query.Duration).After(free.EndAt); start = start.Add(query.Duration) {
+			slots = append(slots, Interval{start, start.Add(query.Duration)})
+			if len(slots) == query.Limit {
+				return slots, nil
+			}
+		}
+	}
+
+	return slots, nil
+}
+
+func validateSlotQuery(query *SlotQuery) error {
+	switch {
+	case len(query.Participants) == 0 || len(query.Participants) > maxFreeBusyUsers:
+		return fmt.Errorf("%w: from 1 to %d participants are required", ErrInvalidQuery, maxFreeBusyUsers)
+	case query.Duration <= 0:
+		return fmt.Errorf("%w: duration must be positive", ErrInvalidQuery)
+	case !query.From.Before(query.To):
+		return fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	case query.To.Sub(query.From) > maxSlotWindow:
+		return fmt.Errorf("%w: window must not be longer than %s", ErrInvalidQuery, maxSlotWindow)
+	case query.Gap < 0:
+		return fmt.Errorf("%w: gap must not be negative", ErrInvalidQuery)
+	case query.Limit < 0 || query.Limit > maxSlotLimit:
+		return fmt.Errorf("%w: limit must be from 1 to %d", ErrInvalidQuery, maxSlotLimit)
+	}
+
+	hours := query.WorkingHours
+	if hours != (WorkingHours{}) && (hours.Start < 0 || hours.Start >= hours.End || hours.End > 24*time.Hour) {
+		return fmt.Errorf("%w: working hours must start before they end within a day", ErrInvalidQuery)
+	}
+
+	for _, participant := range query.Participants {
+		if participant.UserID == "" {
+			return fmt.Errorf("%w: user id is required", ErrInvalidQuery)
+		}
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultSlotLimit
+	}
+	return nil
+}
+
+// offHours returns the time out of the working hours in the location within [from, to).
+// The hours are wall clock, so a working day keeps its local start and end on DST transitions.
+func offHours(hours WorkingHours, loc *time.Location, from, to time.Time) []Interval {
+	var working []Interval
+	y, m, d := from.In(loc).Date()
+	for day := time.Date(y, m, d, 0, 0, 0, 0, loc); day.Before(to); day = time.Date(y, m, d, 0, 0, 0, 0, loc) {
+		working = append(working, Interval{
+			StartAt: time.Date(y, m, d, 0, 0, int(hours.Start/time.Second), 0, loc),
+			EndAt:   time.Date(y, m, d, 0, 0, int(hours.End/time.Second), 0, loc),
+		})
+		d++
+	}
+	return complement(working, from, to)
+}
+
+// complement returns the parts of [from, to) not covered by the sorted non-overlapping intervals.
+func complement(intervals []Interval, from, to time.Time) []Interval {
+	var res []Interval
+	cursor := from
+	for _, interval := range intervals {
+		if !interval.EndAt.After(cursor) {
+			continue
+		}
+		if !interval.StartAt.Before(to) {
+			break
+		}
+		if interval.StartAt.After(cursor) {
+			res = append(res, Interval{cursor, interval.StartAt})
+		}
+		cursor = interval.EndAt
+	}
+	if cursor.Before(to) {
+		res = append(res, Interval{cursor, to})
+	}
+	return res
+}
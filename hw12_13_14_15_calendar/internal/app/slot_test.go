@@ -0,0 +1,151 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_FindSlots(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	a := newTestApp()
+
+	create := func(ownerID string, from, to time.Time) {
+		_, err := a.CreateEvent(ctx, "event", "", ownerID, "", from, to, nil, "", "", nil, nil)
+		require.NoError(t, err)
+	}
+	create("alice", at(9, 0), at(10, 0))
+	create("bob", at(10, 30), at(11, 0))
+	create("bob", at(13, 0), at(14, 0))
+
+	participants := []Participant{{UserID: "alice"}, {UserID: "bob"}}
+	workday := WorkingHours{Start: 9 * time.Hour, End: 18 * time.Hour}
+
+	t.Run(
+		"when everyone is free, returns slots between meetings", func(t *testing.T) {
+			slots, err := a.FindSlots(ctx, SlotQuery{
+				Participants: participants,
+				Duration:     time.Hour,
+				From:         day,
+				To:           day.Add(24 * time.Hour),
+				WorkingHours: workday,
+				Limit:        3,
+			})
+			require.NoError(t, err)
+			require.Equal(t, []Interval{
+				{at(11, 0), at(12, 0)},
+				{at(12, 0), at(13, 0)},
+				{at(14, 0), at(15, 0)},
+			}, slots)
+		},
+	)
+
+	t.Run(
+		"when gap is set, keeps it around other meetings", func(t *testing.T) {
+			slots, err := a.FindSlots(ctx, SlotQuery{
+				Participants: participants,
+				Duration:     30 * time.Minute,
+				From:         day,
+				To:           day.Add(24 * time.Hour),
+				WorkingHours: workday,
+				Gap:          15 * time.Minute,
+				Limit:        2,
+			})
+			require.NoError(t, err)
+			require.Equal(t, []Interval{
+				{at(11, 15), at(11, 45)},
+				{at(11, 45), at(12, 15)},
+			}, slots)
+		},
+	)
+
+	t.Run(
+		"when participant is in another timezone, respects their working hours", func(t *testing.T) {
+			slots, err := a.FindSlots(ctx, SlotQuery{
+				Participants: []Participant{{UserID: "alice"}, {UserID: "carol", Timezone: "Asia/Tokyo"}},
+				Duration:     time.Hour,
+				From:         day,
+				To:           day.Add(24 * time.Hour),
+				WorkingHours: workday,
+			})
+			require.NoError(t, err)
+			// Tokyo is UTC+9, carol works from 00:00 to 09:00 UTC, alice from 09:00, so there is no common time
+			require.Empty(t, slots)
+		},
+	)
+
+	t.Run(
+		"when working hours are not set, searches the whole day", func(t *testing.T) {
+			slots, err := a.FindSlots(ctx, SlotQuery{
+				Participants: participants,
+				Duration:     3 * time.Hour,
+				From:         at(8, 0),
+				To:           day.Add(24 * time.Hour),
+				Limit:        1,
+			})
+			require.NoError(t, err)
+			require.Equal(t, []Interval{{at(14, 0), at(17, 0)}}, slots)
+		},
+	)
+
+	t.Run(
+		"when participant has a private meeting, does not offer its time", func(t *testing.T) {
+			next := day.Add(24 * time.Hour)
+			create("bob", next.Add(9*time.Hour), next.Add(18*time.Hour))
+
+			slots, err := a.FindSlots(ctx, SlotQuery{
+				Participants: participants,
+				Duration:     time.Hour,
+				From:         next,
+				To:           next.Add(24 * time.Hour),
+				WorkingHours: workday,
+			})
+			require.NoError(t, err)
+			require.Empty(t, slots)
+		},
+	)
+
+	t.Run(
+		"when query is invalid, returns invalid query error", func(t *testing.T) {
+			valid := SlotQuery{Participants: participants, Duration: time.Hour, From: day, To: day.Add(time.Hour)}
+
+			for name, modify := range map[string]func(q *SlotQuery){
+				"no participants":  func(q *SlotQuery) { q.Participants = nil },
+				"zero duration":    func(q *SlotQuery) { q.Duration = 0 },
+				"reversed window":  func(q *SlotQuery) { q.To = q.From },
+				"negative gap":     func(q *SlotQuery) { q.Gap = -time.Minute },
+				"too large limit":  func(q *SlotQuery) { q.Limit = maxSlotLimit + 1 },
+				"reversed workday": func(q *SlotQuery) { q.WorkingHours = WorkingHours{Start: 18 * time.Hour, End: time.Hour} },
+			} {
+				query := valid
+				modify(&query)
+				_, err := a.FindSlots(ctx, query)
+				require.ErrorIs(t, err, ErrInvalidQuery, name)
+			}
+
+			query := valid
+			query.Participants = []Participant{{UserID: "alice", Timezone: "Mars/Olympus"}}
+			_, err := a.FindSlots(ctx, query)
+			require.ErrorIs(t, err, ErrInvalidTimezone)
+		},
+	)
+}
+
+func TestParseWorkingHours(t *testing.T) {
+	hours, err := ParseWorkingHours("09:30", "24:00")
+	require.NoError(t, err)
+	require.Equal(t, WorkingHours{Start: 9*time.Hour + 30*time.Minute, End: 24 * time.Hour}, hours)
+
+	hours, err = ParseWorkingHours("", "")
+	require.NoError(t, err)
+	require.Equal(t, WorkingHours{}, hours)
+
+	_, err = ParseWorkingHours("9am", "18:00")
+	require.ErrorIs(t, err, ErrInvalidQuery)
+}
@@ -0,0 +1,325 @@
+package ics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+type contentLine struct {
+	number int
+	text   string
+}
+
+type trigger struct {
+	offset     time.Duration
+	relatedEnd bool
+	at         time.Time
+}
+
+type eventBuilder struct {
+	event       storage.Event
+	hasStart    bool
+	isDate      bool
+	hasEnd      bool
+	duration    time.Duration
+	hasDuration bool
+	triggers    []trigger
+}
+
+// Decode parses VEVENT components of the calendar. Only the properties known to storage.Event
+// are read, every VALARM triggered before the start becomes a reminder. Floating times are treated as UTC.
+// An event with DATE-TIME start and without end and duration lasts instantDuration.
+func Decode(r io.Reader) ([]storage.Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events   []storage.Event
+		stack    []string
+		builder  *eventBuilder
+		alarm    *trigger
+		calendar bool
+	)
+	for _, line := range lines {
+		prop, err := parseProperty(line.text)
+		if err != nil {
+			return nil, lineError(line.number, err)
+		}
+
+		switch prop.name {
+		case "BEGIN":
+			component := strings.ToUpper(prop.value)
+			switch {
+			case len(stack) == 0 && component != "VCALENDAR":
+				return nil, lineError(line.number, fmt.Errorf("unexpected component %s", component))
+			case component == "VCALENDAR":
+				calendar = true
+			case component == "VEVENT" && top(stack) == "VCALENDAR":
+				builder = &eventBuilder{}
+			case component == "VALARM" && top(stack) == "VEVENT":
+				alarm = &trigger{}
+			}
+			stack = append(stack, component)
+		case "END":
+			component := strings.ToUpper(prop.value)
+			if top(stack) != component {
+				return nil, lineError(line.number, fmt.Errorf("unexpected END:%s", component))
+			}
+			stack = stack[:len(stack)-1]
+			switch {
+			case component == "VEVENT" && builder != nil:
+				event, err := builder.build()
+				if err != nil {
+					return nil, lineError(line.number, err)
+				}
+				events = append(events, event)
+				builder = nil
+			case component == "VALARM" && alarm != nil && builder != nil:
+				builder.triggers = append(builder.triggers, *alarm)
+				alarm = nil
+			}
+		default:
+			switch {
+			case top(stack) == "VEVENT" && builder != nil:
+				err = builder.set(prop)
+			case top(stack) == "VALARM" && alarm != nil:
+				err = alarm.set(prop)
+			}
+			if err != nil {
+				return nil, lineError(line.number, err)
+			}
+		}
+	}
+
+	if !calendar {
+		return nil, fmt.Errorf("%w: VCALENDAR is not found", ErrInvalidCalendar)
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("%w: %s is not closed", ErrInvalidCalendar, top(stack))
+	}
+
+	return events, nil
+}
+
+func lineError(number int, err error) error {
+	return fmt.Errorf("%w: line %d: %s", ErrInvalidCalendar, number, err)
+}
+
+func top(stack []string) string {
+	if len(stack) == 0 {
+		return ""
+	}
+	return stack[len(stack)-1]
+}
+
+// unfold joins folded lines, continuation lines start with a space or a tab.
+func unfold(r io.Reader) ([]contentLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lines []contentLine
+	for number := 1; scanner.Scan(); number++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if text == "" {
+			continue
+		}
+		if (text[0] == ' ' || text[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1].text += text[1:]
+			continue
+		}
+		lines = append(lines, contentLine{number: number, text: text})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// parseProperty parses a content line like `DTSTART;TZID="Europe/Berlin":20220110T100000`.
+func parseProperty(line string) (property, error) {
+	prop := property{params: map[string]string{}}
+
+	nameEnd := strings.IndexAny(line, ";:")
+	if nameEnd <= 0 {
+		return prop, errors.New("malformed content line")
+	}
+	prop.name = strings.ToUpper(line[:nameEnd])
+
+	rest := line[nameEnd:]
+	for strings.HasPrefix(rest, ";") {
+		rest = rest[1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return prop, errors.New("malformed parameter")
+		}
+		name := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return prop, errors.New("unterminated quoted parameter")
+			}
+			value, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			end := strings.IndexAny(rest, ";:")
+			if end < 0 {
+				return prop, errors.New("malformed parameter")
+			}
+			value, rest = rest[:end], rest[end:]
+		}
+		prop.params[name] = value
+	}
+
+	if !strings.HasPrefix(rest, ":") {
+		return prop, errors.New("malformed content line")
+	}
+	prop.value = rest[1:]
+
+	return prop, nil
+}
+
+func (b *eventBuilder) set(prop property) error {
+	var err error
+	switch prop.name {
+	case "UID":
+		b.event.ID = storage.EventID(unescapeText(prop.value))
+	case "SUMMARY":
+		b.event.Title = unescapeText(prop.value)
+	case "DESCRIPTION":
+		b.event.Description = unescapeText(prop.value)
+	case "DTSTART":
+		b.event.StartAt, b.isDate, err = parseDateTime(prop.value, prop.params)
+		b.hasStart = true
+	case "DTEND":
+		b.event.EndAt, _, err = parseDateTime(prop.value, prop.params)
+		b.hasEnd = true
+	case "DURATION":
+		b.duration, err = parseDuration(prop.value)
+		b.hasDuration = true
+	case "RRULE":
+		b.event.RRule = prop.value
+	case "EXDATE":
+		for _, value := range strings.Split(prop.value, ",") {
+			exDate, _, err := parseDateTime(value, prop.params)
+			if err != nil {
+				return err
+			}
+			b.event.ExDates = append(b.event.ExDates, exDate)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", prop.name, err)
+	}
+	return nil
+}
+
+func (b *eventBuilder) build() (storage.Event, error) {
+	if !b.hasStart {
+		return storage.Event{}, errors.New("VEVENT without DTSTART")
+	}
+
+	// the series is expanded in the TZID of DTSTART
+	b.event.Timezone = tzID(b.event.StartAt.Location())
+
+	switch {
+	case b.hasEnd:
+	case b.hasDuration:
+		b.event.EndAt = b.event.StartAt.Add(b.duration)
+	case b.isDate:
+		b.event.EndAt = b.event.StartAt.AddDate(0, 0, 1)
+	default:
+		b.event.EndAt = b.event.StartAt.Add(instantDuration)
+	}
+
+	for _, t := range b.triggers {
+		notifyAt := t.resolve(b.event)
+		if notifyAt.After(b.event.StartAt) {
+			continue
+		}
+		b.event.Reminders = append(b.event.Reminders, storage.Reminder{
+			EventID:      b.event.ID,
+			Offset:       b.event.StartAt.Sub(notifyAt),
+			OccurrenceAt: b.event.StartAt,
+			NotifyAt:     notifyAt,
+		})
+	}
+
+	return b.event, nil
+}
+
+func (t *trigger) set(prop property) error {
+	if prop.name != "TRIGGER" {
+		return nil
+	}
+
+	if strings.EqualFold(prop.params["VALUE"], "DATE-TIME") {
+		at, _, err := parseDateTime(prop.value, prop.params)
+		if err != nil {
+			return fmt.Errorf("TRIGGER: %w", err)
+		}
+		t.at = at
+		return nil
+	}
+
+	offset, err := parseDuration(prop.value)
+	if err != nil {
+		return fmt.Errorf("TRIGGER: %w", err)
+	}
+	t.offset = offset
+	t.relatedEnd = strings.EqualFold(prop.params["RELATED"], "END")
+	return nil
+}
+
+func (t trigger) resolve(event storage.Event) time.Time {
+	switch {
+	case !t.at.IsZero():
+		return t.at
+	case t.relatedEnd:
+		return event.EndAt.Add(t.offset)
+	default:
+		return event.StartAt.Add(t.offset)
+	}
+}
+
+// parseDateTime parses DATE-TIME or DATE value, isDate reports whether the value is a DATE.
+func parseDateTime(value string, params map[string]string) (t time.Time, isDate bool, err error) {
+	loc := time.UTC
+	if tzid := params["TZID"]; tzid != "" {
+		loc, err = time.LoadLocation(strings.TrimPrefix(tzid, "/"))
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown TZID %q", tzid)
+		}
+	}
+
+	switch {
+	case strings.EqualFold(params["VALUE"], "DATE") || len(value) == len(dateOnlyLayout):
+		t, err = time.ParseInLocation(dateOnlyLayout, value, loc)
+		isDate = true
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse(utcLayout, value)
+	default:
+		t, err = time.ParseInLocation(localLayout, value, loc)
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("malformed date %q", value)
+	}
+
+	return t, isDate, nil
+}
@@ -0,0 +1,100 @@
+package ics
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Encode writes the events as a VCALENDAR object, now is used as DTSTAMP of every VEVENT.
+// Times are written in the timezone of the event with TZID parameter, times of events without one
+// are written with TZID parameter if their location is named, otherwise they are converted to UTC.
+func Encode(w io.Writer, events []storage.Event, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	write := func(line string) {
+		// errors are sticky in bufio.Writer and returned by Flush
+		_, _ = bw.WriteString(fold(line))
+	}
+
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:" + prodID)
+	write("CALSCALE:GREGORIAN")
+	for _, event := range events {
+		encodeEvent(write, event, now)
+	}
+	write("END:VCALENDAR")
+
+	return bw.Flush()
+}
+
+func encodeEvent(write func(line string), event storage.Event, now time.Time) {
+	// the timezone is validated on save, an unknown one falls back to the location of the start
+	if loc, err := event.Location(); err == nil {
+		event.StartAt, event.EndAt = event.StartAt.In(loc), event.EndAt.In(loc)
+	}
+
+	write("BEGIN:VEVENT")
+	write("UID:" + escapeText(event.ID.String()))
+	write("DTSTAMP:" + now.UTC().Format(utcLayout))
+	write(dateTimeProperty("DTSTART", event.StartAt))
+	write(dateTimeProperty("DTEND", event.EndAt))
+	write("SUMMARY:" + escapeText(event.Title))
+	if event.Description != "" {
+		write("DESCRIPTION:" + escapeText(event.Description))
+	}
+	if event.RRule != "" {
+		write("RRULE:" + event.RRule)
+	}
+	if len(event.ExDates) > 0 {
+		write(exDateProperty(event.ExDates, event.StartAt.Location()))
+	}
+	for _, reminder := range event.Reminders {
+		write("BEGIN:VALARM")
+		write("ACTION:DISPLAY")
+		write("DESCRIPTION:" + escapeText(event.Title))
+		write("TRIGGER:" + formatDuration(-reminder.Offset))
+		write("END:VALARM")
+	}
+	write("END:VEVENT")
+}
+
+func dateTimeProperty(name string, t time.Time) string {
+	if tzid := tzID(t.Location()); tzid != "" {
+		return name + ";TZID=" + tzid + ":" + t.Format(localLayout)
+	}
+	return name + ":" + t.UTC().Format(utcLayout)
+}
+
+// exDateProperty formats exception dates in the location of the series start.
+func exDateProperty(exDates []time.Time, loc *time.Location) string {
+	tzid := tzID(loc)
+	values := make([]string, 0, len(exDates))
+	for _, exDate := range exDates {
+		if tzid != "" {
+			values = append(values, exDate.In(loc).Format(localLayout))
+		} else {
+			values = append(values, exDate.UTC().Format(utcLayout))
+		}
+	}
+	if tzid != "" {
+		return "EXDATE;TZID=" + tzid + ":" + strings.Join(values, ",")
+	}
+	return "EXDATE:" + strings.Join(values, ",")
+}
+
+// tzID returns IANA name of the location, empty for UTC and locations without a portable name.
+func tzID(loc *time.Location) string {
+	switch name := loc.String(); name {
+	case "", "UTC", "Local":
+		return ""
+	default:
+		if _, err := time.LoadLocation(name); err != nil {
+			return ""
+		}
+		return name
+	}
+}
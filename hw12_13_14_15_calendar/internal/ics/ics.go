@@ -0,0 +1,177 @@
+// Package ics converts events from and to iCalendar (RFC 5545) format.
+package ics
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCalendar = errors.New("invalid calendar")
+
+const (
+	prodID = "-//otus_go_homework//calendar//EN"
+
+	// maxLineOctets is the maximum length of a content line without the line break.
+	maxLineOctets = 75
+
+	utcLayout      = "20060102T150405Z"
+	localLayout    = "20060102T150405"
+	dateOnlyLayout = "20060102"
+
+	// instantDuration is the duration of a VEVENT with DATE-TIME start and neither end nor duration,
+	// RFC 5545 makes it end at its start, but events of the calendar must end after the start.
+	instantDuration = time.Minute
+)
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func unescapeText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// fold splits the content line into lines of at most maxLineOctets octets
+// without breaking UTF-8 sequences, continuation lines start with a space.
+func fold(line string) string {
+	if len(line) <= maxLineOctets {
+		return line + "\r\n"
+	}
+
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space of a continuation line counts towards its length
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// formatDuration formats the duration as RFC 5545 dur-value, e.g. "-PT15M".
+func formatDuration(d time.Duration) string {
+	var b strings.Builder
+	if d < 0 {
+		b.WriteByte('-')
+		d = -d
+	}
+	b.WriteByte('P')
+
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if days > 0 {
+		b.WriteString(strconv.FormatInt(int64(days), 10) + "D")
+	}
+	if d == 0 && days > 0 {
+		return b.String()
+	}
+
+	b.WriteByte('T')
+	hours, minutes, seconds := d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second
+	if hours > 0 {
+		b.WriteString(strconv.FormatInt(int64(hours), 10) + "H")
+	}
+	if minutes > 0 {
+		b.WriteString(strconv.FormatInt(int64(minutes), 10) + "M")
+	}
+	if seconds > 0 || (hours == 0 && minutes == 0) {
+		b.WriteString(strconv.FormatInt(int64(seconds), 10) + "S")
+	}
+	return b.String()
+}
+
+// parseDuration parses RFC 5545 dur-value, e.g. "-P1DT2H", "PT15M" or "P1W".
+func parseDuration(s string) (time.Duration, error) {
+	value := s
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(value, "-"):
+		sign = -1
+		value = value[1:]
+	case strings.HasPrefix(value, "+"):
+		value = value[1:]
+	}
+	if !strings.HasPrefix(value, "P") || len(value) < 3 {
+		return 0, fmt.Errorf("malformed duration %q", s)
+	}
+	value = value[1:]
+
+	var res time.Duration
+	inTime := false
+	number := ""
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			number += string(r)
+			continue
+		case r == 'T' && number == "" && !inTime:
+			inTime = true
+			continue
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("malformed duration %q", s)
+		}
+		number = ""
+
+		unit, ok := durationUnit(r, inTime)
+		if !ok {
+			return 0, fmt.Errorf("malformed duration %q", s)
+		}
+		res += time.Duration(n) * unit
+	}
+	if number != "" {
+		return 0, fmt.Errorf("malformed duration %q", s)
+	}
+
+	return sign * res, nil
+}
+
+func durationUnit(r rune, inTime bool) (time.Duration, bool) {
+	switch {
+	case !inTime && r == 'W':
+		return 7 * 24 * time.Hour, true
+	case !inTime && r == 'D':
+		return 24 * time.Hour, true
+	case inTime && r == 'H':
+		return time.Hour, true
+	case inTime && r == 'M':
+		return time.Minute, true
+	case inTime && r == 'S':
+		return time.Second, true
+	}
+	return 0, false
+}
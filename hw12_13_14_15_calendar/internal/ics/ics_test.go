@@ -0,0 +1,237 @@
+package ics
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFold(t *testing.T) {
+	t.Run(
+		"when line is short, keeps it", func(t *testing.T) {
+			require.Equal(t, "SUMMARY:test\r\n", fold("SUMMARY:test"))
+		},
+	)
+
+	t.Run(
+		"when line is long, splits it without breaking runes", func(t *testing.T) {
+			line := "DESCRIPTION:" + strings.Repeat("встреча ", 30)
+			folded := fold(line)
+
+			parts := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n")
+			require.Greater(t, len(parts), 1)
+			for i, part := range parts {
+				require.LessOrEqual(t, len(part), maxLineOctets)
+				if i > 0 {
+					require.True(t, strings.HasPrefix(part, " "))
+				}
+			}
+
+			lines, err := unfold(strings.NewReader(folded))
+			require.NoError(t, err)
+			require.Len(t, lines, 1)
+			require.Equal(t, line, lines[0].text)
+		},
+	)
+}
+
+func TestEscapeText(t *testing.T) {
+	s := "a;b,c\\d\ne"
+	require.Equal(t, `a\;b\,c\\d\ne`, escapeText(s))
+	require.Equal(t, s, unescapeText(escapeText(s)))
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		duration time.Duration
+	}{
+		{value: "-PT15M", duration: -15 * time.Minute},
+		{value: "PT0S", duration: 0},
+		{value: "-P1DT2H30M", duration: -(26*time.Hour + 30*time.Minute)},
+		{value: "P2D", duration: 48 * time.Hour},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.value, formatDuration(tc.duration))
+		d, err := parseDuration(tc.value)
+		require.NoError(t, err)
+		require.Equal(t, tc.duration, d)
+	}
+
+	d, err := parseDuration("-P1W")
+	require.NoError(t, err)
+	require.Equal(t, -7*24*time.Hour, d)
+
+	for _, value := range []string{"", "P", "15M", "PT15", "P1H"} {
+		_, err := parseDuration(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("tzdata is not available")
+	}
+
+	startAt := time.Date(2022, time.January, 10, 10, 0, 0, 0, loc)
+	events := []storage.Event{
+		{
+			ID:          "1",
+			Title:       "standup; daily",
+			StartAt:     startAt,
+			EndAt:       startAt.Add(15 * time.Minute),
+			Description: "line 1\nline 2, more",
+			Reminders:   []storage.Reminder{{Offset: time.Hour}, {Offset: 10 * time.Minute}},
+			RRule:       "FREQ=WEEKLY;BYDAY=MO,WE",
+			ExDates:     []time.Time{startAt.AddDate(0, 0, 7)},
+		},
+		{
+			ID:      "2",
+			Title:   "review",
+			StartAt: time.Date(2022, time.January, 11, 12, 0, 0, 0, time.UTC),
+			EndAt:   time.Date(2022, time.January, 11, 13, 0, 0, 0, time.UTC),
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, Encode(buf, events, time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)))
+
+	encoded := buf.String()
+	require.Contains(t, encoded, "DTSTART;TZID=Europe/Berlin:20220110T100000\r\n")
+	require.Contains(t, encoded, "EXDATE;TZID=Europe/Berlin:20220117T100000\r\n")
+	require.Contains(t, encoded, "DTSTART:20220111T120000Z\r\n")
+	require.Contains(t, encoded, "TRIGGER:-PT1H\r\n")
+	require.Contains(t, encoded, "TRIGGER:-PT10M\r\n")
+	require.Contains(t, encoded, `SUMMARY:standup\; daily`)
+
+	decoded, err := Decode(buf)
+	require.NoError(t, err)
+	require.Len(t, decoded, 2)
+	for i := range events {
+		require.Equal(t, events[i].ID, decoded[i].ID)
+		require.Equal(t, events[i].Title, decoded[i].Title)
+		require.Equal(t, events[i].Description, decoded[i].Description)
+		require.True(t, events[i].StartAt.Equal(decoded[i].StartAt))
+		require.True(t, events[i].EndAt.Equal(decoded[i].EndAt))
+		require.Equal(t, events[i].Offsets(), decoded[i].Offsets())
+		require.Equal(t, events[i].RRule, decoded[i].RRule)
+		require.Equal(t, len(events[i].ExDates), len(decoded[i].ExDates))
+	}
+	require.Equal(t, "Europe/Berlin", decoded[0].StartAt.Location().String())
+}
+
+func TestEncodeDecode_Timezone(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Berlin"); err != nil {
+		t.Skip("tzdata is not available")
+	}
+
+	startAt := time.Date(2022, time.March, 21, 8, 0, 0, 0, time.UTC)
+	event := storage.Event{
+		ID:       "1",
+		Title:    "standup",
+		StartAt:  startAt,
+		EndAt:    startAt.Add(time.Hour),
+		RRule:    "FREQ=WEEKLY",
+		Timezone: "Europe/Berlin",
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, Encode(buf, []storage.Event{event}, startAt))
+	require.Contains(t, buf.String(), "DTSTART;TZID=Europe/Berlin:20220321T090000\r\n")
+
+	decoded, err := Decode(buf)
+	require.NoError(t, err)
+	require.Len(t, decoded, 1)
+	require.Equal(t, "Europe/Berlin", decoded[0].Timezone)
+	require.True(t, startAt.Equal(decoded[0].StartAt))
+}
+
+func TestDecode(t *testing.T) {
+	t.Run(
+		"when event has all day date, duration and several alarms, decodes alarms before start", func(t *testing.T) {
+			input := strings.Join([]string{
+				"BEGIN:VCALENDAR",
+				"VERSION:2.0",
+				"BEGIN:VTIMEZONE",
+				"TZID:Custom",
+				"END:VTIMEZONE",
+				"BEGIN:VEVENT",
+				"UID:holiday",
+				"SUMMARY:Holi",
+				" day",
+				"DTSTART;VALUE=DATE:20220301",
+				"BEGIN:VALARM",
+				"TRIGGER;RELATED=END:-PT1H",
+				"END:VALARM",
+				"BEGIN:VALARM",
+				"TRIGGER;VALUE=DATE-TIME:20220228T090000Z",
+				"END:VALARM",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"UID:call",
+				"SUMMARY:Call",
+				"DTSTART:20220302T100000Z",
+				"DURATION:PT45M",
+				"END:VEVENT",
+				"END:VCALENDAR",
+			}, "\n")
+
+			events, err := Decode(strings.NewReader(input))
+			require.NoError(t, err)
+			require.Len(t, events, 2)
+
+			require.Equal(t, "Holiday", events[0].Title)
+			require.Equal(t, time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC), events[0].StartAt)
+			require.Equal(t, time.Date(2022, time.March, 2, 0, 0, 0, 0, time.UTC), events[0].EndAt)
+			require.Len(t, events[0].Reminders, 1)
+			require.Equal(t, 15*time.Hour, events[0].Reminders[0].Offset)
+			require.Equal(t, time.Date(2022, time.February, 28, 9, 0, 0, 0, time.UTC), events[0].Reminders[0].NotifyAt)
+
+			require.Equal(t, 45*time.Minute, events[1].EndAt.Sub(events[1].StartAt))
+			require.Empty(t, events[1].Reminders)
+		},
+	)
+
+	t.Run(
+		"when event has date-time start without end and duration, gives it minimal duration", func(t *testing.T) {
+			input := strings.Join([]string{
+				"BEGIN:VCALENDAR",
+				"BEGIN:VEVENT",
+				"UID:deadline",
+				"SUMMARY:Deadline",
+				"DTSTART:20220302T170000Z",
+				"END:VEVENT",
+				"END:VCALENDAR",
+			}, "\n")
+
+			events, err := Decode(strings.NewReader(input))
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			require.Equal(t, time.Date(2022, time.March, 2, 17, 0, 0, 0, time.UTC), events[0].StartAt)
+			require.Equal(t, instantDuration, events[0].EndAt.Sub(events[0].StartAt))
+		},
+	)
+
+	t.Run(
+		"when calendar is malformed, returns invalid calendar error", func(t *testing.T) {
+			for _, input := range []string{
+				"",
+				"BEGIN:VEVENT\nEND:VEVENT",
+				"BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:test\nEND:VEVENT\nEND:VCALENDAR",
+				"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;TZID=Nowhere/City:20220101T100000\nEND:VEVENT\nEND:VCALENDAR",
+				"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20220101T100000Z\nEND:VCALENDAR",
+				"BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT\nEND:VCALENDAR",
+				"BEGIN:VCALENDAR\nmalformed\nEND:VCALENDAR",
+			} {
+				_, err := Decode(strings.NewReader(input))
+				require.True(t, errors.Is(err, ErrInvalidCalendar), input)
+			}
+		},
+	)
+}
@@ -0,0 +1,80 @@
+package leader
+
+import (
+	"context"
+	"time"
+)
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+}
+
+// Elector grants the leadership to a single replica at a time.
+type Elector interface {
+	// Acquire tries to become the leader without waiting for the current one.
+	Acquire(ctx context.Context) (bool, error)
+	// Check returns an error if the leadership is lost.
+	Check(ctx context.Context) error
+	Release(ctx context.Context) error
+}
+
+// Run tries to become the leader every interval and runs fn while the replica is the leader.
+// The context of fn is canceled when the leadership is lost, after fn returns the replica steps down
+// and campaigns again. Run returns when ctx is done.
+func Run(ctx context.Context, logger Logger, elector Elector, interval time.Duration, fn func(context.Context)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := elector.Acquire(ctx)
+		if err != nil {
+			logger.Error("failed to acquire leadership: " + err.Error())
+		}
+		if acquired {
+			logger.Info("became the leader")
+			lead(ctx, logger, elector, interval, fn)
+			// the lock is released even if ctx is done
+			if err := elector.Release(context.Background()); err != nil {
+				logger.Error("failed to release leadership: " + err.Error())
+			}
+			logger.Info("stepped down")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead runs fn until it returns or the leadership is lost.
+func lead(ctx context.Context, logger Logger, elector Elector, interval time.Duration, fn func(context.Context)) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(ctx)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := elector.Check(ctx); err != nil {
+				logger.Warn("lost leadership: " + err.Error())
+				cancel()
+				<-done
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package leader
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	memoryleader "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/leader/memory"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+type replica struct {
+	elector *memoryleader.Elector
+	running int32
+	done    chan error
+}
+
+func startReplica(ctx context.Context, lock *memoryleader.Lock) *replica {
+	r := &replica{elector: memoryleader.New(lock), done: make(chan error)}
+	go func() {
+		r.done <- Run(
+			ctx,
+			logger.New(logger.LevelError, io.Discard),
+			r.elector,
+			10*time.Millisecond,
+			func(ctx context.Context) {
+				atomic.StoreInt32(&r.running, 1)
+				<-ctx.Done()
+				atomic.StoreInt32(&r.running, 0)
+			},
+		)
+	}()
+	return r
+}
+
+func (r *replica) isRunning() bool {
+	return atomic.LoadInt32(&r.running) == 1
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	lock := memoryleader.NewLock()
+	first := startReplica(ctx, lock)
+	require.Eventually(t, first.isRunning, time.Second, 5*time.Millisecond)
+	second := startReplica(ctx, lock)
+
+	t.Run(
+		"when leader is running, other replica waits", func(t *testing.T) {
+			require.Never(t, second.isRunning, 50*time.Millisecond, 5*time.Millisecond)
+			require.False(t, second.elector.IsLeader())
+		},
+	)
+
+	t.Run(
+		"when leader connection dies, other replica takes over", func(t *testing.T) {
+			first.elector.Disconnect()
+			require.Eventually(t, func() bool {
+				return second.isRunning() && !first.isRunning()
+			}, time.Second, 5*time.Millisecond)
+			require.True(t, second.elector.IsLeader())
+		},
+	)
+
+	t.Run(
+		"when context is done, stops the job and releases leadership", func(t *testing.T) {
+			cancel()
+			require.NoError(t, <-first.done)
+			require.NoError(t, <-second.done)
+			require.False(t, second.isRunning())
+			require.False(t, second.elector.IsLeader())
+		},
+	)
+}
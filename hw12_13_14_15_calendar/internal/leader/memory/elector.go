@@ -0,0 +1,76 @@
+package memoryleader
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrNotLeader    = errors.New("not the leader")
+	ErrDisconnected = errors.New("disconnected")
+)
+
+// Lock is shared by the electors of the replicas, like an advisory lock of a database.
+type Lock struct {
+	mu     *sync.Mutex
+	holder *Elector
+}
+
+func NewLock() *Lock {
+	return &Lock{mu: &sync.Mutex{}}
+}
+
+// Elector holds the lock while it is the leader, Disconnect simulates a lost connection of the replica.
+type Elector struct {
+	lock         *Lock
+	disconnected bool
+}
+
+func New(lock *Lock) *Elector {
+	return &Elector{lock: lock}
+}
+
+func (e *Elector) Acquire(ctx context.Context) (bool, error) {
+	e.lock.mu.Lock()
+	defer e.lock.mu.Unlock()
+	if e.disconnected {
+		return false, ErrDisconnected
+	}
+	if e.lock.holder == nil {
+		e.lock.holder = e
+	}
+	return e.lock.holder == e, nil
+}
+
+func (e *Elector) Check(ctx context.Context) error {
+	if !e.IsLeader() {
+		return ErrNotLeader
+	}
+	return nil
+}
+
+func (e *Elector) Release(ctx context.Context) error {
+	e.lock.mu.Lock()
+	defer e.lock.mu.Unlock()
+	if e.lock.holder == e {
+		e.lock.holder = nil
+	}
+	return nil
+}
+
+func (e *Elector) IsLeader() bool {
+	e.lock.mu.Lock()
+	defer e.lock.mu.Unlock()
+	return e.lock.holder == e
+}
+
+// Disconnect releases the lock held by the elector, the elector can't acquire it afterwards.
+func (e *Elector) Disconnect() {
+	e.lock.mu.Lock()
+	defer e.lock.mu.Unlock()
+	e.disconnected = true
+	if e.lock.holder == e {
+		e.lock.holder = nil
+	}
+}
@@ -0,0 +1,190 @@
+package amqpqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	ErrNotConnected = errors.New("not connected")
+	ErrNotConfirmed = errors.New("message is not confirmed by the broker")
+)
+
+// Queue publishes messages to a durable direct exchange and consumes them from a durable queue bound to it.
+// Published messages are persistent and confirmed by the broker, consumed messages are acknowledged manually.
+type Queue struct {
+	url        string
+	exchange   string
+	queue      string
+	routingKey string
+	prefetch   int
+
+	mu   *sync.Mutex
+	conn *amqp.Connection
+	ch   *amqp.Channel
+}
+
+func New(url, exchange, queue, routingKey string, prefetch int) *Queue {
+	return &Queue{
+		url:        url,
+		exchange:   exchange,
+		queue:      queue,
+		routingKey: routingKey,
+		prefetch:   prefetch,
+		mu:         &sync.Mutex{},
+	}
+}
+
+// Connect dials the broker and declares the exchange, the queue and the binding.
+func (q *Queue) Connect(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.conn != nil {
+		return nil
+	}
+
+	conn, err := amqp.Dial(q.url)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	ch, err := q.setup(conn)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	q.conn, q.ch = conn, ch
+	return nil
+}
+
+func (q *Queue) setup(conn *amqp.Connection) (*amqp.Channel, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("open channel: %w", err)
+	}
+	if err := ch.ExchangeDeclare(q.exchange, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return nil, fmt.Errorf("declare exchange: %w", err)
+	}
+	if _, err := ch.QueueDeclare(q.queue, true, false, false, false, nil); err != nil {
+		return nil, fmt.Errorf("declare queue: %w", err)
+	}
+	if err := ch.QueueBind(q.queue, q.routingKey, q.exchange, false, nil); err != nil {
+		return nil, fmt.Errorf("bind queue: %w", err)
+	}
+	if err := ch.Qos(q.prefetch, 0, false); err != nil {
+		return nil, fmt.Errorf("set prefetch: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("enable confirms: %w", err)
+	}
+	return ch, nil
+}
+
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.conn == nil {
+		return nil
+	}
+	err := q.conn.Close()
+	q.conn, q.ch = nil, nil
+	return err
+}
+
+// Publish returns after the broker has confirmed the message.
+func (q *Queue) Publish(ctx context.Context, msg queue.Message) error {
+	ch, err := q.channel()
+	if err != nil {
+		return err
+	}
+
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(
+		ctx,
+		q.exchange,
+		q.routingKey,
+		false,
+		false,
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Body:         msg.Body,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return ErrNotConfirmed
+	}
+	return nil
+}
+
+// Consume delivers the messages until ctx is done or the connection is closed.
+// The unacknowledged messages are redelivered by the broker to another consumer.
+func (q *Queue) Consume(ctx context.Context) (<-chan queue.Message, error) {
+	ch, err := q.channel()
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := ch.ConsumeWithContext(ctx, q.queue, "", false, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("consume: %w", err)
+	}
+
+	out := make(chan queue.Message)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case d, ok := <-deliveries:
+				if !ok {
+					return
+				}
+				msg := queue.Message{Body: d.Body, Acknowledger: acknowledger{delivery: d}}
+				select {
+				case out <- msg:
+				case <-ctx.Done():
+					_ = d.Nack(false, true)
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (q *Queue) channel() (*amqp.Channel, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ch == nil {
+		return nil, ErrNotConnected
+	}
+	return q.ch, nil
+}
+
+type acknowledger struct {
+	delivery amqp.Delivery
+}
+
+func (a acknowledger) Ack() error {
+	return a.delivery.Ack(false)
+}
+
+func (a acknowledger) Nack(requeue bool) error {
+	return a.delivery.Nack(false, requeue)
+}
@@ -0,0 +1,125 @@
+package amqpqueue
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+	"github.com/stretchr/testify/require"
+)
+
+// testURLEnv names the broker for the integration tests, every test declares and deletes its own queue.
+const testURLEnv = "CALENDAR_TEST_AMQP_URL"
+
+// newTestQueue returns the connected queue, the test is skipped when the broker is not configured.
+func newTestQueue(t *testing.T) *Queue {
+	t.Helper()
+	url := os.Getenv(testURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", testURLEnv)
+	}
+
+	name := "calendar-test-" + uuid.NewString()
+	q := New(url, name, name, name, 1)
+	require.NoError(t, q.Connect(context.Background()))
+	t.Cleanup(func() {
+		ch, err := q.channel()
+		require.NoError(t, err)
+		_, err = ch.QueueDelete(name, false, false, false)
+		require.NoError(t, err)
+		require.NoError(t, ch.ExchangeDelete(name, false, false))
+		require.NoError(t, q.Close())
+	})
+	return q
+}
+
+func consume(t *testing.T, q *Queue) <-chan queue.Message {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	messages, err := q.Consume(ctx)
+	require.NoError(t, err)
+	return messages
+}
+
+func receive(t *testing.T, messages <-chan queue.Message) queue.Message {
+	t.Helper()
+	select {
+	case msg, ok := <-messages:
+		require.True(t, ok, "messages channel is closed")
+		return msg
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "message is not delivered")
+		return queue.Message{}
+	}
+}
+
+func publish(t *testing.T, q *Queue, body string) {
+	t.Helper()
+	require.NoError(t, q.Publish(context.Background(), queue.Message{Body: []byte(body)}))
+}
+
+func TestQueue(t *testing.T) {
+	t.Run(
+		"when message is acked, delivers it once", func(t *testing.T) {
+			q := newTestQueue(t)
+			messages := consume(t, q)
+
+			publish(t, q, "first")
+			msg := receive(t, messages)
+			require.Equal(t, "first", string(msg.Body))
+			require.NoError(t, msg.Ack())
+
+			publish(t, q, "second")
+			require.Equal(t, "second", string(receive(t, messages).Body))
+		},
+	)
+
+	t.Run(
+		"when message is nacked with requeue, delivers it again", func(t *testing.T) {
+			q := newTestQueue(t)
+			messages := consume(t, q)
+
+			publish(t, q, "retry")
+			msg := receive(t, messages)
+			require.NoError(t, msg.Nack(true))
+
+			msg = receive(t, messages)
+			require.Equal(t, "retry", string(msg.Body))
+			require.NoError(t, msg.Ack())
+		},
+	)
+
+	t.Run(
+		"when message is nacked without requeue, drops it", func(t *testing.T) {
+			q := newTestQueue(t)
+			messages := consume(t, q)
+
+			publish(t, q, "poison")
+			require.NoError(t, receive(t, messages).Nack(false))
+
+			publish(t, q, "next")
+			require.Equal(t, "next", string(receive(t, messages).Body))
+		},
+	)
+
+	t.Run(
+		"when consumer stops without ack, delivers the message to the next consumer", func(t *testing.T) {
+			q := newTestQueue(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			messages, err := q.Consume(ctx)
+			require.NoError(t, err)
+
+			publish(t, q, "unacked")
+			require.Equal(t, "unacked", string(receive(t, messages).Body))
+			cancel()
+
+			msg := receive(t, consume(t, q))
+			require.Equal(t, "unacked", string(msg.Body))
+			require.NoError(t, msg.Ack())
+		},
+	)
+}
@@ -0,0 +1,131 @@
+package memoryqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+)
+
+var ErrSettled = errors.New("message is already acknowledged")
+
+// Queue is an unbounded in-process queue. Every message is delivered to exactly one consumer,
+// a message rejected with requeue is delivered again before the others.
+type Queue struct {
+	mu     *sync.Mutex
+	items  []queue.Message
+	notify chan struct{}
+}
+
+func New() *Queue {
+	return &Queue{
+		mu:     &sync.Mutex{},
+		items:  make([]queue.Message, 0),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (q *Queue) Publish(ctx context.Context, msg queue.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	q.items = append(q.items, msg)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (q *Queue) Consume(ctx context.Context) (<-chan queue.Message, error) {
+	out := make(chan queue.Message)
+
+	go func() {
+		defer close(out)
+		for {
+			msg, ok := q.pop()
+			if !ok {
+				select {
+				case <-q.notify:
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			msg.Acknowledger = &acknowledger{queue: q, msg: queue.Message{Body: msg.Body}}
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				q.pushFront(msg)
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Len returns the number of messages waiting for a consumer.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+func (q *Queue) pop() (queue.Message, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return queue.Message{}, false
+	}
+	msg := q.items[0]
+	q.items = q.items[1:]
+	return msg, true
+}
+
+func (q *Queue) pushFront(msg queue.Message) {
+	q.mu.Lock()
+	q.items = append([]queue.Message{msg}, q.items...)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// acknowledger puts the requeued message back to the queue, other outcomes just drop it.
+type acknowledger struct {
+	queue   *Queue
+	msg     queue.Message
+	settled int32
+}
+
+func (a *acknowledger) Ack() error {
+	return a.settle()
+}
+
+func (a *acknowledger) Nack(requeue bool) error {
+	if err := a.settle(); err != nil {
+		return err
+	}
+	if requeue {
+		a.queue.pushFront(a.msg)
+	}
+	return nil
+}
+
+func (a *acknowledger) settle() error {
+	if !atomic.CompareAndSwapInt32(&a.settled, 0, 1) {
+		return ErrSettled
+	}
+	return nil
+}
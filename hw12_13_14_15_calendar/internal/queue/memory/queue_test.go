@@ -0,0 +1,119 @@
+package memoryqueue
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue(t *testing.T) {
+	t.Run(
+		"delivers messages in publish order", func(t *testing.T) {
+			q := New()
+			for i := 0; i < 3; i++ {
+				require.NoError(t, q.Publish(context.Background(), queue.Message{Body: []byte(strconv.Itoa(i))}))
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			messages, err := q.Consume(ctx)
+			require.NoError(t, err)
+
+			for i := 0; i < 3; i++ {
+				require.Equal(t, strconv.Itoa(i), string((<-messages).Body))
+			}
+		},
+	)
+
+	t.Run(
+		"closes channel when context is done", func(t *testing.T) {
+			q := New()
+			ctx, cancel := context.WithCancel(context.Background())
+			messages, err := q.Consume(ctx)
+			require.NoError(t, err)
+
+			cancel()
+			_, ok := <-messages
+			require.False(t, ok)
+		},
+	)
+
+	t.Run(
+		"every message is delivered once to concurrent consumers", func(t *testing.T) {
+			q := New()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			received := make(chan string, 100)
+			for i := 0; i < 4; i++ {
+				messages, err := q.Consume(ctx)
+				require.NoError(t, err)
+				go func() {
+					for msg := range messages {
+						received <- string(msg.Body)
+					}
+				}()
+			}
+
+			wg := &sync.WaitGroup{}
+			wg.Add(100)
+			for i := 0; i < 100; i++ {
+				go func(i int) {
+					defer wg.Done()
+					_ = q.Publish(context.Background(), queue.Message{Body: []byte(strconv.Itoa(i))})
+				}(i)
+			}
+			wg.Wait()
+
+			seen := make(map[string]struct{})
+			for i := 0; i < 100; i++ {
+				seen[<-received] = struct{}{}
+			}
+			require.Len(t, seen, 100)
+		},
+	)
+
+	t.Run(
+		"when message is requeued, delivers it again", func(t *testing.T) {
+			q := New()
+			for _, body := range []string{"first", "second"} {
+				require.NoError(t, q.Publish(context.Background(), queue.Message{Body: []byte(body)}))
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			messages, err := q.Consume(ctx)
+			require.NoError(t, err)
+
+			msg := <-messages
+			require.NoError(t, msg.Nack(true))
+			require.ErrorIs(t, msg.Ack(), ErrSettled)
+
+			var received []string
+			for i := 0; i < 2; i++ {
+				msg := <-messages
+				require.NoError(t, msg.Ack())
+				received = append(received, string(msg.Body))
+			}
+			require.ElementsMatch(t, []string{"first", "second"}, received)
+		},
+	)
+
+	t.Run(
+		"when message is rejected, drops it", func(t *testing.T) {
+			q := New()
+			require.NoError(t, q.Publish(context.Background(), queue.Message{Body: []byte("poison")}))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			messages, err := q.Consume(ctx)
+			require.NoError(t, err)
+			require.NoError(t, (<-messages).Nack(false))
+			require.Zero(t, q.Len())
+		},
+	)
+}
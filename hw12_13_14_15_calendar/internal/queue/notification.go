@@ -0,0 +1,41 @@
+package queue
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+type Notification struct {
+	EventID storage.EventID `json:"eventId"`
+	Title   string          `json:"title"`
+	Date    time.Time       `json:"date"`
+	UserID  storage.UserID  `json:"userId"`
+	// Key is the same for every delivery of the notification, consumers drop the notifications
+	// with the keys they have already handled.
+	Key string `json:"key,omitempty"`
+}
+
+func NewNotification(event storage.Event) Notification {
+	return Notification{
+		EventID: event.ID,
+		Title:   event.Title,
+		Date:    event.StartAt,
+		UserID:  event.OwnerID,
+	}
+}
+
+func EncodeNotification(n Notification) (Message, error) {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Body: body}, nil
+}
+
+func DecodeNotification(msg Message) (Notification, error) {
+	var n Notification
+	err := json.Unmarshal(msg.Body, &n)
+	return n, err
+}
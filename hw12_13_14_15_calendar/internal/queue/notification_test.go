@@ -0,0 +1,30 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeNotification(t *testing.T) {
+	n := Notification{
+		EventID: "event",
+		Title:   "title",
+		Date:    time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC),
+		UserID:  "user",
+		Key:     "key",
+	}
+
+	msg, err := EncodeNotification(n)
+	require.NoError(t, err)
+	require.JSONEq(
+		t,
+		`{"eventId": "event", "title": "title", "date": "2022-01-10T10:00:00Z", "userId": "user", "key": "key"}`,
+		string(msg.Body),
+	)
+
+	decoded, err := DecodeNotification(msg)
+	require.NoError(t, err)
+	require.Equal(t, n, decoded)
+}
@@ -0,0 +1,40 @@
+package queue
+
+import "context"
+
+type Message struct {
+	Body []byte
+	// Acknowledger settles the consumed message, it is nil for the messages being published.
+	Acknowledger Acknowledger
+}
+
+// Ack confirms that the message is handled, so it is not delivered again.
+func (m Message) Ack() error {
+	if m.Acknowledger == nil {
+		return nil
+	}
+	return m.Acknowledger.Ack()
+}
+
+// Nack rejects the message, a requeued message is delivered again, otherwise it is dropped.
+func (m Message) Nack(requeue bool) error {
+	if m.Acknowledger == nil {
+		return nil
+	}
+	return m.Acknowledger.Nack(requeue)
+}
+
+type Acknowledger interface {
+	Ack() error
+	Nack(requeue bool) error
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+type Consumer interface {
+	// Consume returns a channel of incoming messages which is closed when ctx is done.
+	// Every message must be acknowledged or rejected by the consumer.
+	Consume(ctx context.Context) (<-chan Message, error)
+}
@@ -0,0 +1,392 @@
+// Package rrule implements a subset of RFC 5545 recurrence rules:
+// FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, BYDAY, COUNT and UNTIL.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// maxPeriods protects from endless iteration over rules which never produce an occurrence.
+const maxPeriods = 100000
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// WeekdayNum is a BYDAY item, N is the ordinal of the weekday within
+// the month or year (negative counts from the end), zero means every such weekday.
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []WeekdayNum
+	Count    int
+	Until    time.Time
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var weekdayNames = map[time.Weekday]string{
+	time.Sunday:    "SU",
+	time.Monday:    "MO",
+	time.Tuesday:   "TU",
+	time.Wednesday: "WE",
+	time.Thursday:  "TH",
+	time.Friday:    "FR",
+	time.Saturday:  "SA",
+}
+
+// Parse parses the value of RRULE property, e.g. "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10".
+func Parse(s string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return Rule{}, fmt.Errorf("%w: empty rule", ErrInvalidRule)
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return Rule{}, fmt.Errorf("%w: malformed part %q", ErrInvalidRule, part)
+		}
+		name, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+
+		var err error
+		switch name {
+		case "FREQ":
+			rule.Freq = Frequency(value)
+		case "INTERVAL":
+			rule.Interval, err = parsePositive(value)
+		case "COUNT":
+			rule.Count, err = parsePositive(value)
+		case "UNTIL":
+			rule.Until, err = parseUntil(value)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		default:
+			err = fmt.Errorf("unsupported part %s", name)
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("%w: %s", ErrInvalidRule, err)
+		}
+	}
+
+	if err := rule.validate(); err != nil {
+		return Rule{}, fmt.Errorf("%w: %s", ErrInvalidRule, err)
+	}
+
+	return rule, nil
+}
+
+func (r Rule) validate() error {
+	switch r.Freq {
+	case Daily, Weekly, Monthly, Yearly:
+	case "":
+		return errors.New("FREQ is required")
+	default:
+		return fmt.Errorf("unsupported FREQ %s", r.Freq)
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return errors.New("COUNT and UNTIL must not occur together")
+	}
+	for _, day := range r.ByDay {
+		if day.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return errors.New("BYDAY ordinals are allowed only with MONTHLY or YEARLY frequency")
+		}
+	}
+	return nil
+}
+
+func parsePositive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%q is not a positive integer", value)
+	}
+	return n, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// a date bound includes the whole day
+				t = t.Add(24*time.Hour - time.Nanosecond)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("malformed UNTIL %q", value)
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	items := strings.Split(value, ",")
+	days := make([]WeekdayNum, 0, len(items))
+	for _, item := range items {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("malformed BYDAY %q", item)
+		}
+		weekday, ok := weekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("malformed BYDAY %q", item)
+		}
+		var n int
+		if ordinal := item[:len(item)-2]; ordinal != "" {
+			var err error
+			n, err = strconv.Atoi(ordinal)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("malformed BYDAY %q", item)
+			}
+		}
+		days = append(days, WeekdayNum{N: n, Weekday: weekday})
+	}
+	return days, nil
+}
+
+// String formats the rule as the value of RRULE property.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			name := weekdayNames[day.Weekday]
+			if day.N != 0 {
+				name = strconv.Itoa(day.N) + name
+			}
+			days = append(days, name)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Between returns the starts of occurrences within [from, to] of the series started at dtstart.
+func (r Rule) Between(dtstart, from, to time.Time) []time.Time {
+	var res []time.Time
+	r.iterate(dtstart, to, func(t time.Time) bool {
+		if t.After(to) {
+			return false
+		}
+		if !t.Before(from) {
+			res = append(res, t)
+		}
+		return true
+	})
+	return res
+}
+
+// After returns the start of the first occurrence after t, ok is false if the series ends before.
+func (r Rule) After(dtstart, t time.Time) (next time.Time, ok bool) {
+	r.iterate(dtstart, time.Time{}, func(occurrence time.Time) bool {
+		if occurrence.After(t) {
+			next, ok = occurrence, true
+			return false
+		}
+		return true
+	})
+	return next, ok
+}
+
+// Next returns up to n starts of the occurrences after t and before limit, zero limit means no limit.
+func (r Rule) Next(dtstart, t, limit time.Time, n int) []time.Time {
+	if n <= 0 {
+		return nil
+	}
+
+	var res []time.Time
+	r.iterate(dtstart, limit, func(occurrence time.Time) bool {
+		if !limit.IsZero() && !occurrence.Before(limit) {
+			return false
+		}
+		if occurrence.After(t) {
+			res = append(res, occurrence)
+		}
+		return len(res) < n
+	})
+	return res
+}
+
+// Last returns the start of the last occurrence, ok is false for an endless series.
+func (r Rule) Last(dtstart time.Time) (last time.Time, ok bool) {
+	if r.Count == 0 && r.Until.IsZero() {
+		return time.Time{}, false
+	}
+	r.iterate(dtstart, time.Time{}, func(t time.Time) bool {
+		last = t
+		return true
+	})
+	return last, !last.IsZero()
+}
+
+// iterate calls fn for every occurrence in order until fn returns false,
+// the series ends or a period starts after limit.
+func (r Rule) iterate(dtstart, limit time.Time, fn func(t time.Time) bool) {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	count := 0
+	for period := 0; period < maxPeriods; period++ {
+		periodStart, candidates := r.candidates(dtstart, period*interval)
+		if !limit.IsZero() && periodStart.After(limit) {
+			return
+		}
+		if !r.Until.IsZero() && periodStart.After(r.Until) {
+			return
+		}
+
+		for _, t := range candidates {
+			if t.Before(dtstart) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return
+			}
+			count++
+			if !fn(t) {
+				return
+			}
+			if r.Count > 0 && count >= r.Count {
+				return
+			}
+		}
+	}
+}
+
+// candidates returns the start of the n-th period after dtstart and
+// the sorted occurrences within it. The wall clock of dtstart is kept in its location,
+// so dtstart must be in the timezone of the series to keep the time across DST changes.
+func (r Rule) candidates(dtstart time.Time, n int) (time.Time, []time.Time) {
+	h, m, s := dtstart.Clock()
+	loc := dtstart.Location()
+
+	switch r.Freq {
+	case Daily:
+		day := dtstart.AddDate(0, 0, n)
+		if len(r.ByDay) > 0 && !r.matchWeekday(day.Weekday()) {
+			return day, nil
+		}
+		return day, []time.Time{day}
+	case Weekly:
+		base := dtstart.AddDate(0, 0, 7*n)
+		if len(r.ByDay) == 0 {
+			return base, []time.Time{base}
+		}
+		weekStart := base.AddDate(0, 0, -((int(base.Weekday()) + 6) % 7))
+		var res []time.Time
+		for i := 0; i < 7; i++ {
+			day := weekStart.AddDate(0, 0, i)
+			if r.matchWeekday(day.Weekday()) {
+				res = append(res, day)
+			}
+		}
+		return weekStart, res
+	case Monthly:
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(n), 1, h, m, s, dtstart.Nanosecond(), loc)
+		last := first.AddDate(0, 1, -1)
+		if len(r.ByDay) == 0 {
+			day := time.Date(first.Year(), first.Month(), dtstart.Day(), h, m, s, dtstart.Nanosecond(), loc)
+			if day.Month() != first.Month() {
+				return first, nil
+			}
+			return first, []time.Time{day}
+		}
+		return first, r.byDayWithin(first, last)
+	case Yearly:
+		first := time.Date(dtstart.Year()+n, time.January, 1, h, m, s, dtstart.Nanosecond(), loc)
+		last := time.Date(dtstart.Year()+n, time.December, 31, h, m, s, dtstart.Nanosecond(), loc)
+		if len(r.ByDay) == 0 {
+			day := time.Date(first.Year(), dtstart.Month(), dtstart.Day(), h, m, s, dtstart.Nanosecond(), loc)
+			if day.Month() != dtstart.Month() {
+				return first, nil
+			}
+			return first, []time.Time{day}
+		}
+		return first, r.byDayWithin(first, last)
+	}
+
+	return dtstart, nil
+}
+
+// byDayWithin returns the days within [first, last] matching BYDAY items.
+func (r Rule) byDayWithin(first, last time.Time) []time.Time {
+	var days []time.Time
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+
+	seen := make(map[int]struct{})
+	for _, item := range r.ByDay {
+		var matched []int
+		for i, day := range days {
+			if day.Weekday() == item.Weekday {
+				matched = append(matched, i)
+			}
+		}
+
+		switch {
+		case item.N == 0:
+			for _, i := range matched {
+				seen[i] = struct{}{}
+			}
+		case item.N > 0 && item.N <= len(matched):
+			seen[matched[item.N-1]] = struct{}{}
+		case item.N < 0 && -item.N <= len(matched):
+			seen[matched[len(matched)+item.N]] = struct{}{}
+		}
+	}
+
+	indexes := make([]int, 0, len(seen))
+	for i := range seen {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	res := make([]time.Time, 0, len(indexes))
+	for _, i := range indexes {
+		res = append(res, days[i])
+	}
+	return res
+}
+
+func (r Rule) matchWeekday(weekday time.Weekday) bool {
+	for _, day := range r.ByDay {
+		if day.Weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
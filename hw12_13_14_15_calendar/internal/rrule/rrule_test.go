@@ -0,0 +1,235 @@
+package rrule
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	res, err := time.Parse(time.RFC3339, s)
+	require.NoError(t, err)
+	return res
+}
+
+func TestParse(t *testing.T) {
+	t.Run(
+		"valid rules", func(t *testing.T) {
+			tests := []struct {
+				input    string
+				expected string
+			}{
+				{input: "FREQ=DAILY", expected: "FREQ=DAILY"},
+				{input: "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", expected: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE"},
+				{input: "freq=monthly;byday=-1fr;count=3", expected: "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3"},
+				{input: "FREQ=YEARLY;UNTIL=20250101T000000Z", expected: "FREQ=YEARLY;UNTIL=20250101T000000Z"},
+				{input: "FREQ=DAILY;UNTIL=20250101", expected: "FREQ=DAILY;UNTIL=20250101T235959Z"},
+			}
+			for _, tc := range tests {
+				rule, err := Parse(tc.input)
+				require.NoError(t, err, tc.input)
+				require.Equal(t, tc.expected, rule.String())
+			}
+		},
+	)
+
+	t.Run(
+		"invalid rules", func(t *testing.T) {
+			for _, input := range []string{
+				"",
+				"INTERVAL=2",
+				"FREQ=HOURLY",
+				"FREQ=DAILY;INTERVAL=0",
+				"FREQ=DAILY;COUNT=2;UNTIL=20250101T000000Z",
+				"FREQ=WEEKLY;BYDAY=1MO",
+				"FREQ=MONTHLY;BYDAY=XX",
+				"FREQ=MONTHLY;BYMONTHDAY=1",
+				"FREQ",
+			} {
+				_, err := Parse(input)
+				require.True(t, errors.Is(err, ErrInvalidRule), input)
+			}
+		},
+	)
+}
+
+func TestRule_Between(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		dtstart  string
+		from     string
+		to       string
+		expected []string
+	}{
+		{
+			name:    "daily with count",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: "2022-01-10T10:00:00Z",
+			from:    "2022-01-01T00:00:00Z",
+			to:      "2022-02-01T00:00:00Z",
+			expected: []string{
+				"2022-01-10T10:00:00Z",
+				"2022-01-11T10:00:00Z",
+				"2022-01-12T10:00:00Z",
+			},
+		},
+		{
+			name:    "weekly by day within window",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,WE",
+			dtstart: "2022-01-10T09:30:00Z",
+			from:    "2022-01-17T00:00:00Z",
+			to:      "2022-01-24T00:00:00Z",
+			expected: []string{
+				"2022-01-17T09:30:00Z",
+				"2022-01-19T09:30:00Z",
+			},
+		},
+		{
+			name:    "biweekly with until",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;UNTIL=20220207T093000Z",
+			dtstart: "2022-01-10T09:30:00Z",
+			from:    "2022-01-01T00:00:00Z",
+			to:      "2022-12-31T00:00:00Z",
+			expected: []string{
+				"2022-01-10T09:30:00Z",
+				"2022-01-24T09:30:00Z",
+				"2022-02-07T09:30:00Z",
+			},
+		},
+		{
+			name:    "monthly skips short months",
+			rule:    "FREQ=MONTHLY;COUNT=3",
+			dtstart: "2022-01-31T10:00:00Z",
+			from:    "2022-01-01T00:00:00Z",
+			to:      "2022-12-31T00:00:00Z",
+			expected: []string{
+				"2022-01-31T10:00:00Z",
+				"2022-03-31T10:00:00Z",
+				"2022-05-31T10:00:00Z",
+			},
+		},
+		{
+			name:    "monthly last friday",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			dtstart: "2022-01-01T18:00:00Z",
+			from:    "2022-01-01T00:00:00Z",
+			to:      "2022-12-31T00:00:00Z",
+			expected: []string{
+				"2022-01-28T18:00:00Z",
+				"2022-02-25T18:00:00Z",
+				"2022-03-25T18:00:00Z",
+			},
+		},
+		{
+			name:    "yearly skips non leap years",
+			rule:    "FREQ=YEARLY;COUNT=2",
+			dtstart: "2020-02-29T10:00:00Z",
+			from:    "2020-01-01T00:00:00Z",
+			to:      "2030-01-01T00:00:00Z",
+			expected: []string{
+				"2020-02-29T10:00:00Z",
+				"2024-02-29T10:00:00Z",
+			},
+		},
+		{
+			name:    "daily filtered by day",
+			rule:    "FREQ=DAILY;BYDAY=SA,SU",
+			dtstart: "2022-01-10T10:00:00Z",
+			from:    "2022-01-10T00:00:00Z",
+			to:      "2022-01-17T00:00:00Z",
+			expected: []string{
+				"2022-01-15T10:00:00Z",
+				"2022-01-16T10:00:00Z",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := Parse(tc.rule)
+			require.NoError(t, err)
+
+			var expected []time.Time
+			for _, s := range tc.expected {
+				expected = append(expected, mustParseTime(t, s))
+			}
+
+			actual := rule.Between(mustParseTime(t, tc.dtstart), mustParseTime(t, tc.from), mustParseTime(t, tc.to))
+			require.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestRule_BetweenKeepsWallClockAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("tzdata is not available")
+	}
+
+	rule, err := Parse("FREQ=DAILY;COUNT=3")
+	require.NoError(t, err)
+
+	dtstart := time.Date(2022, time.March, 26, 9, 0, 0, 0, loc)
+	actual := rule.Between(dtstart, dtstart, dtstart.AddDate(0, 0, 5))
+	require.Len(t, actual, 3)
+	for _, occurrence := range actual {
+		require.Equal(t, 9, occurrence.Hour())
+	}
+	require.Equal(t, 23*time.Hour, actual[1].Sub(actual[0]))
+}
+
+func TestRule_Last(t *testing.T) {
+	dtstart := mustParseTime(t, "2022-01-10T10:00:00Z")
+
+	rule, err := Parse("FREQ=WEEKLY;COUNT=4")
+	require.NoError(t, err)
+	last, ok := rule.Last(dtstart)
+	require.True(t, ok)
+	require.Equal(t, mustParseTime(t, "2022-01-31T10:00:00Z"), last)
+
+	rule, err = Parse("FREQ=WEEKLY")
+	require.NoError(t, err)
+	_, ok = rule.Last(dtstart)
+	require.False(t, ok)
+}
+
+func TestRule_After(t *testing.T) {
+	dtstart := mustParseTime(t, "2022-01-10T10:00:00Z")
+
+	rule, err := Parse("FREQ=WEEKLY;BYDAY=MO,TH;COUNT=4")
+	require.NoError(t, err)
+
+	next, ok := rule.After(dtstart, mustParseTime(t, "2022-01-10T10:00:00Z"))
+	require.True(t, ok)
+	require.Equal(t, mustParseTime(t, "2022-01-13T10:00:00Z"), next)
+
+	next, ok = rule.After(dtstart, time.Time{})
+	require.True(t, ok)
+	require.Equal(t, dtstart, next)
+
+	_, ok = rule.After(dtstart, mustParseTime(t, "2022-01-20T10:00:00Z"))
+	require.False(t, ok)
+}
+
+func TestRule_Next(t *testing.T) {
+	dtstart := mustParseTime(t, "2022-01-10T10:00:00Z")
+
+	rule, err := Parse("FREQ=DAILY;COUNT=5")
+	require.NoError(t, err)
+
+	require.Equal(t, []time.Time{
+		mustParseTime(t, "2022-01-11T10:00:00Z"),
+		mustParseTime(t, "2022-01-12T10:00:00Z"),
+	}, rule.Next(dtstart, dtstart, time.Time{}, 2))
+
+	require.Equal(t, []time.Time{
+		mustParseTime(t, "2022-01-13T10:00:00Z"),
+	}, rule.Next(dtstart, mustParseTime(t, "2022-01-12T10:00:00Z"), mustParseTime(t, "2022-01-14T10:00:00Z"), 10))
+
+	require.Empty(t, rule.Next(dtstart, mustParseTime(t, "2022-01-14T10:00:00Z"), time.Time{}, 10))
+}
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type CleanerStorage interface {
+	CountAllEndedBefore(ctx context.Context, before time.Time) (int, error)
+	DeleteAllEndedBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// Cleaner deletes events which ended more than retention ago.
+// In dry-run mode it only reports how many events would be deleted.
+type Cleaner struct {
+	logger    Logger
+	storage   CleanerStorage
+	retention time.Duration
+	interval  time.Duration
+	dryRun    bool
+}
+
+func NewCleaner(
+	logger Logger,
+	storage CleanerStorage,
+	retention, interval time.Duration,
+	dryRun bool,
+) *Cleaner {
+	return &Cleaner{
+		logger:    logger,
+		storage:   storage,
+		retention: retention,
+		interval:  interval,
+		dryRun:    dryRun,
+	}
+}
+
+// Run cleans the storage every interval until ctx is done.
+func (c *Cleaner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.Clean(ctx, time.Now()); err != nil {
+			c.logger.Error("failed to clean old events: " + err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Clean returns the number of deleted events, or of events to delete in dry-run mode.
+func (c *Cleaner) Clean(ctx context.Context, now time.Time) (int, error) {
+	before := now.Add(-c.retention)
+
+	if c.dryRun {
+		count, err := c.storage.CountAllEndedBefore(ctx, before)
+		if err != nil {
+			return 0, err
+		}
+		c.logger.Info(fmt.Sprintf(
+			"dry run: %d events ended before %s would be deleted",
+			count,
+			before.UTC().Format(time.RFC3339),
+		))
+		return count, nil
+	}
+
+	count, err := c.storage.DeleteAllEndedBefore(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+	c.logger.Info(fmt.Sprintf("deleted %d events ended before %s", count, before.UTC().Format(time.RFC3339)))
+
+	return count, nil
+}
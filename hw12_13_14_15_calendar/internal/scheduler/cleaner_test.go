@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/logger"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleaner_Clean(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2022-01-10T10:00:00Z")
+	retention := 365 * 24 * time.Hour
+
+	newStorage := func(t *testing.T) *memorystorage.Storage {
+		t.Helper()
+		store := memorystorage.New()
+		old := storage.Event{ID: "old", StartAt: now.AddDate(-2, 0, 0), EndAt: now.AddDate(-2, 0, 0).Add(time.Hour)}
+		recent := storage.Event{ID: "recent", StartAt: now.AddDate(0, -1, 0), EndAt: now.AddDate(0, -1, 0).Add(time.Hour)}
+		require.NoError(t, store.Save(context.Background(), &old))
+		require.NoError(t, store.Save(context.Background(), &recent))
+		return store
+	}
+
+	t.Run(
+		"deletes events ended before retention period", func(t *testing.T) {
+			store := newStorage(t)
+			buf := &bytes.Buffer{}
+			c := NewCleaner(logger.New(logger.LevelInfo, buf), store, retention, time.Hour, false)
+
+			count, err := c.Clean(context.Background(), now)
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+			require.Contains(t, buf.String(), "deleted 1 events ended before 2021-01-10T10:00:00Z")
+
+			_, err = store.FindByID(context.Background(), "old")
+			require.ErrorIs(t, err, storage.ErrNotFound)
+
+			event, err := store.FindByID(context.Background(), "recent")
+			require.NoError(t, err)
+			require.NotNil(t, event)
+		},
+	)
+
+	t.Run(
+		"in dry-run mode only reports events to delete", func(t *testing.T) {
+			store := newStorage(t)
+			buf := &bytes.Buffer{}
+			c := NewCleaner(logger.New(logger.LevelInfo, buf), store, retention, time.Hour, true)
+
+			count, err := c.Clean(context.Background(), now)
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+			require.Contains(t, buf.String(), "dry run: 1 events ended before 2021-01-10T10:00:00Z would be deleted")
+
+			event, err := store.FindByID(context.Background(), "old")
+			require.NoError(t, err)
+			require.NotNil(t, event)
+		},
+	)
+}
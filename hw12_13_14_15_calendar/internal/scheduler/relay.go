@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+type OutboxStorage interface {
+	FindOutbox(ctx context.Context, limit int) ([]storage.OutboxMessage, error)
+	DeleteOutbox(ctx context.Context, id int64) error
+}
+
+// Relay publishes the outbox messages to the queue and deletes every published message. A message
+// is published again if the relay stops before deleting it, so delivery is at least once.
+// Only one relay may run at a time, otherwise the order of the messages is not kept.
+type Relay struct {
+	logger    Logger
+	storage   OutboxStorage
+	publisher queue.Publisher
+	batchSize int
+}
+
+func NewRelay(logger Logger, storage OutboxStorage, publisher queue.Publisher, batchSize int) *Relay {
+	return &Relay{
+		logger:    logger,
+		storage:   storage,
+		publisher: publisher,
+		batchSize: batchSize,
+	}
+}
+
+// Flush publishes the outbox in the order of messages until it is empty, it stops on the first failure
+// so the failed message is retried before the later ones.
+func (r *Relay) Flush(ctx context.Context) error {
+	published := 0
+	defer func() {
+		if published > 0 {
+			r.logger.Info(fmt.Sprintf("published %d notifications", published))
+		}
+	}()
+
+	for {
+		messages, err := r.storage.FindOutbox(ctx, r.batchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range messages {
+			if err := r.publisher.Publish(ctx, queue.Message{Body: msg.Body}); err != nil {
+				return fmt.Errorf("publish outbox message %s: %w", msg.Key, err)
+			}
+			if err := r.storage.DeleteOutbox(ctx, msg.ID); err != nil {
+				return fmt.Errorf("delete outbox message %s: %w", msg.Key, err)
+			}
+			published++
+		}
+
+		if len(messages) < r.batchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/logger"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+	memoryqueue "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue/memory"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type flakyPublisher struct {
+	queue.Publisher
+	failures int
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, msg queue.Message) error {
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("unavailable")
+	}
+	return p.Publisher.Publish(ctx, msg)
+}
+
+func TestRelay_Flush(t *testing.T) {
+	ctx := context.Background()
+	store := memorystorage.New()
+	event := storage.Event{ID: "event", OwnerID: "user", Reminders: []storage.Reminder{{EventID: "event"}}}
+	require.NoError(t, store.Save(ctx, &event))
+
+	var messages []storage.OutboxMessage
+	for _, key := range []string{"a", "b", "c"} {
+		messages = append(messages, storage.OutboxMessage{Key: key, Body: []byte(key)})
+	}
+	require.NoError(t, store.CompleteReminder(ctx, event.Reminders[0], event.Reminders[0], messages))
+
+	q := memoryqueue.New()
+	publisher := &flakyPublisher{Publisher: q, failures: 1}
+	relay := NewRelay(logger.New(logger.LevelError, io.Discard), store, publisher, 2)
+
+	t.Run(
+		"when publishing fails, keeps the message in outbox", func(t *testing.T) {
+			require.Error(t, relay.Flush(ctx))
+			require.Zero(t, q.Len())
+			outbox, err := store.FindOutbox(ctx, 10)
+			require.NoError(t, err)
+			require.Len(t, outbox, 3)
+		},
+	)
+
+	t.Run(
+		"when publisher recovers, publishes all messages in order", func(t *testing.T) {
+			require.NoError(t, relay.Flush(ctx))
+			outbox, err := store.FindOutbox(ctx, 10)
+			require.NoError(t, err)
+			require.Empty(t, outbox)
+
+			consumeCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			published, err := q.Consume(consumeCtx)
+			require.NoError(t, err)
+			for _, key := range []string{"a", "b", "c"} {
+				require.Equal(t, []byte(key), (<-published).Body)
+			}
+		},
+	)
+}
@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+}
+
+// relayBatchSize is the number of outbox messages read at once.
+const relayBatchSize = 100
+
+type Storage interface {
+	OutboxStorage
+	FindDueReminders(ctx context.Context, until time.Time) ([]storage.Reminder, error)
+	FindByID(ctx context.Context, eventID storage.EventID) (*storage.Event, error)
+	// CompleteReminder replaces the due reminder with the next one and puts its notifications to the outbox
+	// atomically, it returns storage.ErrConflict if the reminder was changed after it was read.
+	CompleteReminder(ctx context.Context, due, next storage.Reminder, messages []storage.OutboxMessage) error
+}
+
+type Scheduler struct {
+	logger   Logger
+	storage  Storage
+	relay    *Relay
+	interval time.Duration
+}
+
+func New(logger Logger, storage Storage, publisher queue.Publisher, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		logger:   logger,
+		storage:  storage,
+		relay:    NewRelay(logger, storage, publisher, relayBatchSize),
+		interval: interval,
+	}
+}
+
+// Run scans the storage every interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Notify(ctx, time.Now()); err != nil {
+			s.logger.Error("failed to send notifications: " + err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Notify puts a notification for every participant of every reminder due until now to the outbox and publishes
+// the outbox. A reminder of a recurring event is rescheduled to the next occurrence, otherwise it is marked as sent,
+// in the same transaction with the outbox, so a notification is neither lost nor produced twice.
+func (s *Scheduler) Notify(ctx context.Context, now time.Time) error {
+	err := s.enqueue(ctx, now)
+	// the outbox is flushed anyway to publish the notifications enqueued before the failure
+	if flushErr := s.relay.Flush(ctx); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+func (s *Scheduler) enqueue(ctx context.Context, now time.Time) error {
+	reminders, err := s.storage.FindDueReminders(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, reminder := range reminders {
+		event, err := s.storage.FindByID(ctx, reminder.EventID)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		occurrence := *event
+		occurrence.StartAt = reminder.OccurrenceAt
+		var messages []storage.OutboxMessage
+		for _, participant := range event.Participants() {
+			notification := queue.NewNotification(occurrence)
+			notification.UserID = participant
+			notification.Key = notificationKey(reminder, participant)
+			msg, err := queue.EncodeNotification(notification)
+			if err != nil {
+				return err
+			}
+			messages = append(messages, storage.OutboxMessage{Key: notification.Key, Body: msg.Body})
+		}
+
+		next, err := reminder.Next(*event)
+		if err != nil {
+			return err
+		}
+		err = s.storage.CompleteReminder(ctx, reminder, next, messages)
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrConflict) {
+			// the event was deleted or rescheduled after it was read, a rescheduled reminder is found again when due
+			s.logger.Debug(fmt.Sprintf("skip reminder of event %s: %s", event.ID, err))
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("complete reminder of event %s: %w", event.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// notificationKey identifies the notification of the participant about the occurrence.
+func notificationKey(reminder storage.Reminder, userID storage.UserID) string {
+	return fmt.Sprintf(
+		"%s/%s/%s/%s",
+		reminder.EventID,
+		reminder.OccurrenceAt.UTC().Format(time.RFC3339),
+		reminder.Offset,
+		userID,
+	)
+}
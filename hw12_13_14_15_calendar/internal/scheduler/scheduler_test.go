@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/logger"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+	memoryqueue "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue/memory"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_Notify(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2022-01-10T10:00:00Z")
+	store := memorystorage.New()
+	due := storage.Event{
+		ID:      "due",
+		Title:   "due",
+		StartAt: now.Add(time.Hour),
+		EndAt:   now.Add(2 * time.Hour),
+		OwnerID: "user",
+	}
+	future := storage.Event{
+		ID:      "future",
+		Title:   "future",
+		StartAt: now.Add(3 * time.Hour),
+		EndAt:   now.Add(4 * time.Hour),
+		OwnerID: "user",
+	}
+	var err error
+	due.Reminders, err = storage.ScheduleReminders(due, []time.Duration{61 * time.Minute, 30 * time.Minute}, now)
+	require.NoError(t, err)
+	future.Reminders, err = storage.ScheduleReminders(future, []time.Duration{2 * time.Hour}, now)
+	require.NoError(t, err)
+	require.NoError(t, store.Save(context.Background(), &due))
+	require.NoError(t, store.Save(context.Background(), &future))
+
+	q := memoryqueue.New()
+	s := New(logger.New(logger.LevelError, io.Discard), store, q, time.Minute)
+
+	require.NoError(t, s.Notify(context.Background(), now))
+	require.Equal(t, 1, q.Len())
+
+	t.Run(
+		"publishes notification for due event", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			messages, err := q.Consume(ctx)
+			require.NoError(t, err)
+
+			n, err := queue.DecodeNotification(<-messages)
+			require.NoError(t, err)
+			require.Equal(t, queue.Notification{
+				EventID: due.ID,
+				Title:   due.Title,
+				Date:    due.StartAt,
+				UserID:  due.OwnerID,
+				Key:     "due/2022-01-10T11:00:00Z/1h1m0s/user",
+			}, n)
+		},
+	)
+
+	t.Run(
+		"does not publish notification twice", func(t *testing.T) {
+			require.NoError(t, s.Notify(context.Background(), now))
+			require.Equal(t, 0, q.Len())
+
+			event, err := store.FindByID(context.Background(), due.ID)
+			require.NoError(t, err)
+			require.True(t, event.Reminders[0].Sent)
+			require.False(t, event.Reminders[1].Sent)
+		},
+	)
+}
+
+func TestScheduler_NotifyRecurring(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2022-01-10T09:50:00Z")
+	store := memorystorage.New()
+	standup := storage.Event{
+		ID:      "standup",
+		Title:   "standup",
+		StartAt: now.Add(10 * time.Minute),
+		EndAt:   now.Add(25 * time.Minute),
+		OwnerID: "user",
+		RRule:   "FREQ=DAILY;COUNT=2",
+	}
+	var err error
+	standup.Reminders, err = storage.ScheduleReminders(standup, []time.Duration{15 * time.Minute}, now)
+	require.NoError(t, err)
+	require.NoError(t, store.Save(context.Background(), &standup))
+
+	q := memoryqueue.New()
+	s := New(logger.New(logger.LevelError, io.Discard), store, q, time.Minute)
+
+	require.NoError(t, s.Notify(context.Background(), now))
+	require.Equal(t, 1, q.Len())
+
+	event, err := store.FindByID(context.Background(), standup.ID)
+	require.NoError(t, err)
+	require.Equal(t, standup.StartAt.AddDate(0, 0, 1), event.Reminders[0].OccurrenceAt)
+	require.False(t, event.Reminders[0].Sent)
+
+	require.NoError(t, s.Notify(context.Background(), now.AddDate(0, 0, 1)))
+	require.Equal(t, 2, q.Len())
+
+	event, err = store.FindByID(context.Background(), standup.ID)
+	require.NoError(t, err)
+	require.True(t, event.Reminders[0].Sent, "series has no more occurrences")
+}
+
+func TestScheduler_NotifyAttendees(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2022-01-10T10:00:00Z")
+	store := memorystorage.New()
+	meeting := storage.Event{
+		ID:      "meeting",
+		Title:   "meeting",
+		StartAt: now.Add(10 * time.Minute),
+		EndAt:   now.Add(time.Hour),
+		OwnerID: "owner",
+		Attendees: []storage.Attendee{
+			{UserID: "accepted", Status: storage.StatusAccepted},
+			{UserID: "declined", Status: storage.StatusDeclined},
+			{UserID: "invited", Status: storage.StatusNeedsAction},
+		},
+	}
+	var err error
+	meeting.Reminders, err = storage.ScheduleReminders(meeting, []time.Duration{15 * time.Minute}, now)
+	require.NoError(t, err)
+	require.NoError(t, store.Save(context.Background(), &meeting))
+
+	q := memoryqueue.New()
+	s := New(logger.New(logger.LevelError, io.Discard), store, q, time.Minute)
+
+	require.NoError(t, s.Notify(context.Background(), now))
+	require.Equal(t, 3, q.Len())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages, err := q.Consume(ctx)
+	require.NoError(t, err)
+
+	var recipients []storage.UserID
+	for i := 0; i < 3; i++ {
+		n, err := queue.DecodeNotification(<-messages)
+		require.NoError(t, err)
+		recipients = append(recipients, n.UserID)
+	}
+	require.ElementsMatch(t, []storage.UserID{"owner", "accepted", "invited"}, recipients)
+}
@@ -0,0 +1,150 @@
+package sender
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/logger"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+	"github.com/stretchr/testify/require"
+)
+
+func testNotification() queue.Notification {
+	date, _ := time.Parse(time.RFC3339, "2022-01-10T10:00:00Z")
+	return queue.Notification{EventID: "event", Title: "Stand-up", Date: date, UserID: "user"}
+}
+
+func TestLogSender(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s := NewLogSender(logger.New(logger.LevelInfo, buf))
+
+	require.NoError(t, s.Send(context.Background(), testNotification()))
+	require.Contains(t, buf.String(), `notification for user user: event event "Stand-up" at 2022-01-10T10:00:00Z`)
+}
+
+func TestWebhookSender(t *testing.T) {
+	t.Run(
+		"posts notification as json", func(t *testing.T) {
+			received := make(chan queue.Notification, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+				require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+				var n queue.Notification
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&n))
+				received <- n
+			}))
+			defer server.Close()
+
+			s := NewWebhookSender(server.URL, time.Second)
+			require.NoError(t, s.Send(context.Background(), testNotification()))
+			require.Equal(t, testNotification(), <-received)
+		},
+	)
+
+	t.Run(
+		"when webhook responds with error status, returns error", func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			}))
+			defer server.Close()
+
+			s := NewWebhookSender(server.URL, time.Second)
+			require.EqualError(t, s.Send(context.Background(), testNotification()), "webhook responded with status 502")
+		},
+	)
+}
+
+// fakeSMTPServer accepts a single mail and sends its data to the returned channel.
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	mails := make(chan string, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				mails <- data.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return lis.Addr().String(), mails
+}
+
+func TestSMTPSender(t *testing.T) {
+	addr, mails := fakeSMTPServer(t)
+	s := NewSMTPSender(addr, "calendar@example.com", "%s@example.com", "", "")
+
+	require.NoError(t, s.Send(context.Background(), testNotification()))
+
+	mail := <-mails
+	require.Contains(t, mail, "To: user@example.com\r\n")
+	require.Contains(t, mail, "Subject: Reminder: Stand-up\r\n")
+	require.Contains(t, mail, `Event "Stand-up" starts at Mon, 10 Jan 2022 10:00:00 +0000.`)
+}
+
+func TestFileDeadLetterStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead_letters.jsonl")
+	s := NewFileDeadLetterStore(path)
+	letter := DeadLetter{Notification: testNotification(), Channel: "smtp", Error: "failed", FailedAt: time.Now().UTC()}
+
+	require.NoError(t, s.Put(context.Background(), letter))
+	require.NoError(t, s.Put(context.Background(), letter))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var decoded DeadLetter
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &decoded))
+	require.Equal(t, letter.Notification, decoded.Notification)
+	require.Equal(t, letter.Channel, decoded.Channel)
+}
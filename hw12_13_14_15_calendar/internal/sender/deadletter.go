@@ -0,0 +1,74 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+)
+
+type DeadLetter struct {
+	Notification queue.Notification `json:"notification"`
+	Channel      string             `json:"channel"`
+	Error        string             `json:"error"`
+	FailedAt     time.Time          `json:"failedAt"`
+}
+
+type DeadLetterStore interface {
+	Put(ctx context.Context, letter DeadLetter) error
+}
+
+type MemoryDeadLetterStore struct {
+	mu      *sync.Mutex
+	letters []DeadLetter
+}
+
+func NewMemoryDeadLetterStore() *MemoryDeadLetterStore {
+	return &MemoryDeadLetterStore{mu: &sync.Mutex{}}
+}
+
+func (s *MemoryDeadLetterStore) Put(ctx context.Context, letter DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters = append(s.letters, letter)
+	return nil
+}
+
+func (s *MemoryDeadLetterStore) All() []DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]DeadLetter(nil), s.letters...)
+}
+
+// FileDeadLetterStore appends dead letters to a file as JSON lines.
+type FileDeadLetterStore struct {
+	mu   *sync.Mutex
+	path string
+}
+
+func NewFileDeadLetterStore(path string) *FileDeadLetterStore {
+	return &FileDeadLetterStore{mu: &sync.Mutex{}, path: path}
+}
+
+func (s *FileDeadLetterStore) Put(ctx context.Context, letter DeadLetter) error {
+	line, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,134 @@
+package sender
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// KeyStore remembers the keys of the delivered notifications.
+type KeyStore interface {
+	// Seen reports whether the notification with the key was delivered.
+	Seen(ctx context.Context, key string) (bool, error)
+	Mark(ctx context.Context, key string) error
+}
+
+// MemoryKeyStore keeps up to capacity latest keys, the oldest key is forgotten first.
+type MemoryKeyStore struct {
+	mu       *sync.Mutex
+	capacity int
+	keys     map[string]struct{}
+	order    []string
+}
+
+func NewMemoryKeyStore(capacity int) *MemoryKeyStore {
+	return &MemoryKeyStore{
+		mu:       &sync.Mutex{},
+		capacity: capacity,
+		keys:     make(map[string]struct{}, capacity),
+		order:    make([]string, 0, capacity),
+	}
+}
+
+func (s *MemoryKeyStore) Seen(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.keys[key]
+	return ok, nil
+}
+
+func (s *MemoryKeyStore) Mark(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mark(key)
+	return nil
+}
+
+// mark remembers the key and reports whether it is new, the caller holds the lock.
+func (s *MemoryKeyStore) mark(key string) bool {
+	if _, ok := s.keys[key]; ok || s.capacity <= 0 {
+		return false
+	}
+
+	if len(s.order) == s.capacity {
+		delete(s.keys, s.order[0])
+		s.order = s.order[1:]
+	}
+	s.keys[key] = struct{}{}
+	s.order = append(s.order, key)
+	return true
+}
+
+// FileKeyStore keeps the latest keys in memory like MemoryKeyStore and appends them to a file as lines,
+// so the keys survive restarts. The file is rewritten with the kept keys when it grows twice over the capacity.
+type FileKeyStore struct {
+	*MemoryKeyStore
+	path    string
+	written int
+}
+
+// NewFileKeyStore loads the keys written by the previous runs, the missing file is treated as empty.
+func NewFileKeyStore(path string, capacity int) (*FileKeyStore, error) {
+	s := &FileKeyStore{MemoryKeyStore: NewMemoryKeyStore(capacity), path: path}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if key := scanner.Text(); key != "" {
+			s.mark(key)
+			s.written++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read keys %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *FileKeyStore) Mark(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.mark(key) {
+		return nil
+	}
+
+	if s.written >= 2*s.capacity {
+		return s.rewrite()
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(key + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	s.written++
+	return f.Close()
+}
+
+// rewrite replaces the file with the kept keys, the temporary file is renamed so a crash keeps the old file.
+func (s *FileKeyStore) rewrite() error {
+	tmp := s.path + ".tmp"
+	data := strings.Join(s.order, "\n") + "\n"
+	if err := os.WriteFile(tmp, []byte(data), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.written = len(s.order)
+	return nil
+}
@@ -0,0 +1,28 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+)
+
+type LogSender struct {
+	logger Logger
+}
+
+func NewLogSender(logger Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+func (s *LogSender) Send(ctx context.Context, n queue.Notification) error {
+	s.logger.Info(fmt.Sprintf(
+		"notification for user %s: event %s %q at %s",
+		n.UserID,
+		n.EventID,
+		n.Title,
+		n.Date.Format(time.RFC3339),
+	))
+	return nil
+}
@@ -0,0 +1,163 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+)
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+}
+
+// Sender delivers a notification through a single channel.
+type Sender interface {
+	Send(ctx context.Context, n queue.Notification) error
+}
+
+type Channel struct {
+	Name   string
+	Sender Sender
+}
+
+type Backoff struct {
+	Attempts     int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// Delay returns the pause before the given retry, starting from 1.
+func (b Backoff) Delay(retry int) time.Duration {
+	delay := b.InitialDelay
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if b.MaxDelay > 0 && delay >= b.MaxDelay {
+			return b.MaxDelay
+		}
+	}
+	return delay
+}
+
+type Service struct {
+	logger     Logger
+	consumer   queue.Consumer
+	channels   []Channel
+	backoff    Backoff
+	deadLetter DeadLetterStore
+	delivered  KeyStore
+}
+
+func New(
+	logger Logger,
+	consumer queue.Consumer,
+	channels []Channel,
+	backoff Backoff,
+	deadLetter DeadLetterStore,
+	delivered KeyStore,
+) *Service {
+	return &Service{
+		logger:     logger,
+		consumer:   consumer,
+		channels:   channels,
+		backoff:    backoff,
+		deadLetter: deadLetter,
+		delivered:  delivered,
+	}
+}
+
+// Run consumes notifications until ctx is done.
+func (s *Service) Run(ctx context.Context) error {
+	messages, err := s.consumer.Consume(ctx)
+	if err != nil {
+		return err
+	}
+
+	for msg := range messages {
+		n, err := queue.DecodeNotification(msg)
+		if err != nil {
+			s.logger.Error("failed to decode notification: " + err.Error())
+			// a malformed message would fail again
+			s.checkAck(msg.Nack(false))
+			continue
+		}
+		s.Deliver(ctx, n)
+		if ctx.Err() != nil {
+			// the delivery was interrupted, the notification is handled again by the next run
+			s.checkAck(msg.Nack(true))
+			continue
+		}
+		s.checkAck(msg.Ack())
+	}
+
+	return nil
+}
+
+// Deliver sends the notification through every channel, a channel which
+// keeps failing after all retries puts the notification to the dead-letter store.
+// A notification with the key of an already delivered one is dropped.
+func (s *Service) Deliver(ctx context.Context, n queue.Notification) {
+	if n.Key != "" {
+		seen, err := s.delivered.Seen(ctx, n.Key)
+		if err != nil {
+			s.logger.Error("failed to check notification key: " + err.Error())
+		}
+		if seen {
+			s.logger.Debug("drop duplicate notification " + n.Key)
+			return
+		}
+	}
+
+	for _, ch := range s.channels {
+		err := s.send(ctx, ch, n)
+		if err == nil || errors.Is(err, context.Canceled) {
+			continue
+		}
+
+		s.logger.Error(fmt.Sprintf("failed to send notification for event %s via %s: %s", n.EventID, ch.Name, err))
+		if err := s.deadLetter.Put(ctx, DeadLetter{
+			Notification: n,
+			Channel:      ch.Name,
+			Error:        err.Error(),
+			FailedAt:     time.Now(),
+		}); err != nil {
+			s.logger.Error("failed to put notification to dead-letter store: " + err.Error())
+		}
+	}
+
+	if n.Key != "" && ctx.Err() == nil {
+		if err := s.delivered.Mark(ctx, n.Key); err != nil {
+			s.logger.Error("failed to mark notification as delivered: " + err.Error())
+		}
+	}
+}
+
+func (s *Service) checkAck(err error) {
+	if err != nil {
+		s.logger.Error("failed to acknowledge message: " + err.Error())
+	}
+}
+
+func (s *Service) send(ctx context.Context, ch Channel, n queue.Notification) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = ch.Sender.Send(ctx, n); err == nil {
+			return nil
+		}
+		if attempt >= s.backoff.Attempts {
+			return err
+		}
+
+		s.logger.Warn(fmt.Sprintf("retry sending notification for event %s via %s: %s", n.EventID, ch.Name, err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.backoff.Delay(attempt)):
+		}
+	}
+}
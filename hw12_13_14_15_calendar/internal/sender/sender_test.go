@@ -0,0 +1,223 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/logger"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+	memoryqueue "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSender struct {
+	mu       sync.Mutex
+	failures int
+	calls    []queue.Notification
+}
+
+func (s *fakeSender) Send(ctx context.Context, n queue.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, n)
+	if len(s.calls) <= s.failures {
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+func (s *fakeSender) Calls() []queue.Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]queue.Notification(nil), s.calls...)
+}
+
+func TestBackoff_Delay(t *testing.T) {
+	b := Backoff{Attempts: 10, InitialDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	require.Equal(t, time.Second, b.Delay(1))
+	require.Equal(t, 2*time.Second, b.Delay(2))
+	require.Equal(t, 4*time.Second, b.Delay(3))
+	require.Equal(t, 5*time.Second, b.Delay(4))
+	require.Equal(t, 5*time.Second, b.Delay(10))
+}
+
+func TestService_Deliver(t *testing.T) {
+	n := queue.Notification{EventID: "event", Title: "test", Date: time.Now().UTC(), UserID: "user"}
+	backoff := Backoff{Attempts: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	logg := logger.New(logger.LevelError, io.Discard)
+
+	t.Run(
+		"when sender recovers, delivers without dead letter", func(t *testing.T) {
+			sender := &fakeSender{failures: 2}
+			deadLetter := NewMemoryDeadLetterStore()
+			s := New(
+				logg,
+				memoryqueue.New(),
+				[]Channel{{Name: "fake", Sender: sender}},
+				backoff,
+				deadLetter,
+				NewMemoryKeyStore(10),
+			)
+
+			s.Deliver(context.Background(), n)
+			require.Len(t, sender.Calls(), 3)
+			require.Empty(t, deadLetter.All())
+		},
+	)
+
+	t.Run(
+		"when sender keeps failing, puts notification to dead-letter store", func(t *testing.T) {
+			failing := &fakeSender{failures: 100}
+			working := &fakeSender{}
+			deadLetter := NewMemoryDeadLetterStore()
+			s := New(
+				logg,
+				memoryqueue.New(),
+				[]Channel{{Name: "failing", Sender: failing}, {Name: "working", Sender: working}},
+				backoff,
+				deadLetter,
+				NewMemoryKeyStore(10),
+			)
+
+			s.Deliver(context.Background(), n)
+			require.Len(t, failing.Calls(), 3)
+			require.Equal(t, []queue.Notification{n}, working.Calls())
+
+			letters := deadLetter.All()
+			require.Len(t, letters, 1)
+			require.Equal(t, n, letters[0].Notification)
+			require.Equal(t, "failing", letters[0].Channel)
+			require.Equal(t, "unavailable", letters[0].Error)
+		},
+	)
+}
+
+func TestService_DeliverDuplicate(t *testing.T) {
+	n := queue.Notification{EventID: "event", Title: "test", Date: time.Now().UTC(), UserID: "user", Key: "key"}
+	sender := &fakeSender{}
+	s := New(
+		logger.New(logger.LevelError, io.Discard),
+		memoryqueue.New(),
+		[]Channel{{Name: "fake", Sender: sender}},
+		Backoff{Attempts: 1},
+		NewMemoryDeadLetterStore(),
+		NewMemoryKeyStore(10),
+	)
+
+	t.Run(
+		"when notification with the same key is delivered, drops it", func(t *testing.T) {
+			s.Deliver(context.Background(), n)
+			s.Deliver(context.Background(), n)
+			require.Equal(t, []queue.Notification{n}, sender.Calls())
+		},
+	)
+
+	t.Run(
+		"when notification has no key, delivers every copy", func(t *testing.T) {
+			unkeyed := n
+			unkeyed.Key = ""
+			s.Deliver(context.Background(), unkeyed)
+			s.Deliver(context.Background(), unkeyed)
+			require.Len(t, sender.Calls(), 3)
+		},
+	)
+}
+
+func TestMemoryKeyStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryKeyStore(2)
+	for _, key := range []string{"a", "b", "a", "c"} {
+		require.NoError(t, store.Mark(ctx, key))
+	}
+
+	for key, expected := range map[string]bool{"a": false, "b": true, "c": true} {
+		seen, err := store.Seen(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, expected, seen, key)
+	}
+}
+
+func TestFileKeyStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys")
+
+	t.Run(
+		"when store is reopened, remembers the delivered keys", func(t *testing.T) {
+			store, err := NewFileKeyStore(path, 2)
+			require.NoError(t, err)
+			for _, key := range []string{"a", "b", "a"} {
+				require.NoError(t, store.Mark(ctx, key))
+			}
+
+			reopened, err := NewFileKeyStore(path, 2)
+			require.NoError(t, err)
+			for _, key := range []string{"a", "b"} {
+				seen, err := reopened.Seen(ctx, key)
+				require.NoError(t, err)
+				require.True(t, seen, key)
+			}
+		},
+	)
+
+	t.Run(
+		"when file grows over the capacity, keeps only the latest keys", func(t *testing.T) {
+			store, err := NewFileKeyStore(path, 2)
+			require.NoError(t, err)
+			for _, key := range []string{"c", "d", "e"} {
+				require.NoError(t, store.Mark(ctx, key))
+			}
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.LessOrEqual(t, strings.Count(string(data), "\n"), 4)
+
+			reopened, err := NewFileKeyStore(path, 2)
+			require.NoError(t, err)
+			for key, expected := range map[string]bool{"a": false, "c": false, "d": true, "e": true} {
+				seen, err := reopened.Seen(ctx, key)
+				require.NoError(t, err)
+				require.Equal(t, expected, seen, key)
+			}
+		},
+	)
+}
+
+func TestService_Run(t *testing.T) {
+	n := queue.Notification{EventID: "event", Title: "test", Date: time.Now().UTC(), UserID: "user"}
+	q := memoryqueue.New()
+	msg, err := queue.EncodeNotification(n)
+	require.NoError(t, err)
+	require.NoError(t, q.Publish(context.Background(), msg))
+	require.NoError(t, q.Publish(context.Background(), queue.Message{Body: []byte("malformed")}))
+
+	sender := &fakeSender{}
+	s := New(
+		logger.New(logger.LevelError, io.Discard),
+		q,
+		[]Channel{{Name: "fake", Sender: sender}},
+		Backoff{Attempts: 1},
+		NewMemoryDeadLetterStore(),
+		NewMemoryKeyStore(10),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(sender.Calls()) == 1 && q.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+	require.Equal(t, []queue.Notification{n}, sender.Calls())
+}
@@ -0,0 +1,62 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/queue"
+)
+
+var headerReplacer = strings.NewReplacer("\r", " ", "\n", " ")
+
+// SMTPSender mails a notification to the address built from the user ID
+// and recipientFormat, e.g. "%s@example.com".
+type SMTPSender struct {
+	addr            string
+	from            string
+	recipientFormat string
+	auth            smtp.Auth
+}
+
+func NewSMTPSender(addr, from, recipientFormat, username, password string) *SMTPSender {
+	s := &SMTPSender{
+		addr:            addr,
+		from:            from,
+		recipientFormat: recipientFormat,
+	}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+func (s *SMTPSender) Send(ctx context.Context, n queue.Notification) error {
+	to := fmt.Sprintf(s.recipientFormat, n.UserID)
+
+	msg := strings.Join([]string{
+		"From: " + s.from,
+		"To: " + to,
+		"Subject: Reminder: " + headerReplacer.Replace(n.Title),
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		fmt.Sprintf("Event %q starts at %s.", n.Title, n.Date.Format(time.RFC1123Z)),
+		"",
+	}, "\r\n")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, s.auth, s.from, []string{to}, []byte(msg))
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
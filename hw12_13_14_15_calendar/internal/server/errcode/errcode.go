@@ -0,0 +1,72 @@
+// Package errcode translates application and storage errors into codes of the transports,
+// so HTTP and gRPC clients get the same classification of an error.
+package errcode
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/app"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/grpc/codes"
+)
+
+// Code describes an error for clients.
+type Code struct {
+	// Name is a stable machine readable name like "not_found".
+	Name       string
+	HTTPStatus int
+	GRPCCode   codes.Code
+}
+
+var (
+	Validation      = Code{Name: "validation_error", HTTPStatus: http.StatusBadRequest, GRPCCode: codes.InvalidArgument}
+	NotFound        = Code{Name: "not_found", HTTPStatus: http.StatusNotFound, GRPCCode: codes.NotFound}
+	Forbidden       = Code{Name: "forbidden", HTTPStatus: http.StatusForbidden, GRPCCode: codes.PermissionDenied}
+	DateBusy        = Code{Name: "date_busy", HTTPStatus: http.StatusConflict, GRPCCode: codes.AlreadyExists}
+	Conflict        = Code{Name: "conflict", HTTPStatus: http.StatusConflict, GRPCCode: codes.Aborted}
+	VersionMismatch = Code{
+		Name:       "version_mismatch",
+		HTTPStatus: http.StatusPreconditionFailed,
+		GRPCCode:   codes.FailedPrecondition,
+	}
+	Unavailable = Code{Name: "unavailable", HTTPStatus: http.StatusServiceUnavailable, GRPCCode: codes.Unavailable}
+	Internal    = Code{Name: "internal_error", HTTPStatus: http.StatusInternalServerError, GRPCCode: codes.Internal}
+)
+
+// Public reports whether the error text may be sent to clients, the text of internal and unavailable errors
+// may reveal queries, addresses or other details of the infrastructure.
+func (c Code) Public() bool {
+	return c != Internal && c != Unavailable
+}
+
+// Message returns the text of the error for clients, a generic text for errors which are not public.
+func (c Code) Message(err error) string {
+	if c.Public() {
+		return err.Error()
+	}
+	return http.StatusText(c.HTTPStatus)
+}
+
+// Of returns the code of the error, unknown errors are internal.
+func Of(err error) Code {
+	switch {
+	case errors.Is(err, app.ErrInvalidEvent), errors.Is(err, app.ErrInvalidTimezone), errors.Is(err, app.ErrInvalidQuery),
+		errors.Is(err, app.ErrInvalidCalendar):
+		return Validation
+	case errors.Is(err, app.ErrForbidden):
+		return Forbidden
+	case errors.Is(err, storage.ErrNotFound):
+		return NotFound
+	case errors.Is(err, storage.ErrDateBusy):
+		return DateBusy
+	case errors.Is(err, storage.ErrConflict):
+		return Conflict
+	case errors.Is(err, storage.ErrVersionMismatch):
+		return VersionMismatch
+	case errors.Is(err, storage.ErrUnavailable):
+		return Unavailable
+	default:
+		return Internal
+	}
+}
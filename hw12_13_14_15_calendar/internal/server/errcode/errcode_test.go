@@ -0,0 +1,55 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/app"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOf(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected Code
+	}{
+		{err: fmt.Errorf("%w: title is required", app.ErrInvalidEvent), expected: Validation},
+		{err: app.ErrInvalidTimezone, expected: Validation},
+		{err: app.ErrInvalidQuery, expected: Validation},
+		{err: app.ErrInvalidCalendar, expected: Validation},
+		{err: fmt.Errorf("%w: calendar is not shared", app.ErrForbidden), expected: Forbidden},
+		{err: fmt.Errorf("event 1: %w", storage.ErrNotFound), expected: NotFound},
+		{err: storage.ErrDateBusy, expected: DateBusy},
+		{err: storage.ErrConflict, expected: Conflict},
+		{err: storage.ErrVersionMismatch, expected: VersionMismatch},
+		{err: fmt.Errorf("%w: connection refused", storage.ErrUnavailable), expected: Unavailable},
+		{err: errors.New("unexpected"), expected: Internal},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			require.Equal(t, tc.expected, Of(tc.err))
+		})
+	}
+}
+
+func TestCode_Message(t *testing.T) {
+	t.Run(
+		"when error is public, returns its text", func(t *testing.T) {
+			err := fmt.Errorf("event 1: %w", storage.ErrNotFound)
+			require.Equal(t, err.Error(), Of(err).Message(err))
+		},
+	)
+
+	t.Run(
+		"when error is internal or unavailable, returns generic text", func(t *testing.T) {
+			err := errors.New("pq: relation \"events\" does not exist")
+			require.Equal(t, "Internal Server Error", Of(err).Message(err))
+
+			err = fmt.Errorf("%w: dial tcp 10.0.0.5:5432: connection refused", storage.ErrUnavailable)
+			require.Equal(t, "Service Unavailable", Of(err).Message(err))
+		},
+	)
+}
@@ -0,0 +1,109 @@
+package internalgrpc
+
+import (
+	"context"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) CreateCalendar(
+	ctx context.Context,
+	req *pb.CreateCalendarRequest,
+) (*pb.CreateCalendarResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.app.CreateCalendar(ctx, userID, req.GetName())
+	if err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	return &pb.CreateCalendarResponse{Id: id.String()}, nil
+}
+
+func (s *Server) ListCalendars(ctx context.Context, req *pb.ListCalendarsRequest) (*pb.ListCalendarsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars, err := s.app.GetCalendars(ctx, userID)
+	if err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	res := &pb.ListCalendarsResponse{Calendars: make([]*pb.Calendar, 0, len(calendars))}
+	for _, calendar := range calendars {
+		item := &pb.Calendar{
+			Id:      calendar.ID.String(),
+			OwnerId: string(calendar.OwnerID),
+			Name:    calendar.Name,
+			Access:  string(calendar.AccessOf(storage.UserID(userID))),
+		}
+		for _, grant := range calendar.Grants {
+			item.Grants = append(item.Grants, &pb.Grant{UserId: string(grant.UserID), Access: string(grant.Access)})
+		}
+		res.Calendars = append(res.Calendars, item)
+	}
+
+	return res, nil
+}
+
+func (s *Server) DeleteCalendar(
+	ctx context.Context,
+	req *pb.DeleteCalendarRequest,
+) (*pb.DeleteCalendarResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.app.DeleteCalendar(ctx, userID, req.GetId()); err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	return &pb.DeleteCalendarResponse{}, nil
+}
+
+func (s *Server) ShareCalendar(ctx context.Context, req *pb.ShareCalendarRequest) (*pb.ShareCalendarResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.app.ShareCalendar(ctx, userID, req.GetCalendarId(), req.GetUserId(), req.GetAccess()); err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	return &pb.ShareCalendarResponse{}, nil
+}
+
+func (s *Server) ListCalendarEvents(ctx context.Context, req *pb.ListCalendarEventsRequest) (*pb.ListResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.GetFrom().CheckValid(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "from: "+err.Error())
+	}
+	if err := req.GetTo().CheckValid(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "to: "+err.Error())
+	}
+
+	events, err := s.app.ListCalendarEvents(ctx, userID, req.GetCalendarId(), req.GetFrom().AsTime(), req.GetTo().AsTime())
+	if err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	res := &pb.ListResponse{Events: make([]*pb.Event, 0, len(events))}
+	for _, event := range events {
+		res.Events = append(res.Events, toPbEvent(event))
+	}
+
+	return res, nil
+}
@@ -0,0 +1,56 @@
+package internalgrpc
+
+import (
+	"context"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *Server) History(ctx context.Context, req *pb.HistoryRequest) (*pb.HistoryResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := s.app.GetEventHistory(ctx, userID, req.GetId())
+	if err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	res := &pb.HistoryResponse{Changes: make([]*pb.Change, 0, len(changes))}
+	for _, change := range changes {
+		res.Changes = append(res.Changes, &pb.Change{
+			Id:      change.ID,
+			Kind:    string(change.Kind),
+			ActorId: string(change.ActorID),
+			At:      timestamppb.New(change.At),
+			Before:  toPbSnapshot(change.Before),
+			After:   toPbSnapshot(change.After),
+		})
+	}
+
+	return res, nil
+}
+
+func (s *Server) Restore(ctx context.Context, req *pb.RestoreRequest) (*pb.RestoreResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := s.app.RestoreEvent(ctx, userID, req.GetId(), req.GetChangeId())
+	if err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	return &pb.RestoreResponse{Version: version}, nil
+}
+
+func toPbSnapshot(event *storage.Event) *pb.Event {
+	if event == nil {
+		return nil
+	}
+	return toPbEvent(*event)
+}
@@ -0,0 +1,66 @@
+package internalgrpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func loggingInterceptor(logger Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		now := time.Now()
+		res, err := handler(ctx, req)
+		logRequest(ctx, logger, info.FullMethod, now, err)
+
+		return res, err
+	}
+}
+
+// streamLoggingInterceptor logs streaming calls once the stream is finished.
+func streamLoggingInterceptor(logger Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		now := time.Now()
+		err := handler(srv, ss)
+		logRequest(ss.Context(), logger, info.FullMethod, now, err)
+
+		return err
+	}
+}
+
+func logRequest(ctx context.Context, logger Logger, method string, start time.Time, err error) {
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+	var userAgent string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			userAgent = values[0]
+		}
+	}
+
+	msg := fmt.Sprintf("%s [%s] %s %s %d \"%s\"",
+		addr,
+		start.UTC().Format(time.RFC3339),
+		method,
+		status.Code(err),
+		time.Since(start).Milliseconds(),
+		userAgent,
+	)
+	logger.Info(msg)
+}
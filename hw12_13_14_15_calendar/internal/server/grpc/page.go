@@ -0,0 +1,79 @@
+package internalgrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *Server) ListPage(ctx context.Context, req *pb.ListPageRequest) (*pb.ListPageResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	from, err := optionalTime("from", req.GetFrom())
+	if err != nil {
+		return nil, err
+	}
+	to, err := optionalTime("to", req.GetTo())
+	if err != nil {
+		return nil, err
+	}
+
+	events, next, err := s.app.ListEventsPage(ctx, userID, from, to, req.GetPageToken(), int(req.GetPageSize()))
+	if err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	res := &pb.ListPageResponse{Events: make([]*pb.Event, 0, len(events)), NextPageToken: next}
+	for _, event := range events {
+		res.Events = append(res.Events, toPbEvent(event))
+	}
+
+	return res, nil
+}
+
+// Export streams the events as they are read from the storage, so the whole calendar is never kept in memory.
+func (s *Server) Export(req *pb.ExportRequest, stream pb.EventService_ExportServer) error {
+	ctx := stream.Context()
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	from, err := optionalTime("from", req.GetFrom())
+	if err != nil {
+		return err
+	}
+	to, err := optionalTime("to", req.GetTo())
+	if err != nil {
+		return err
+	}
+
+	err = s.app.ExportEvents(ctx, userID, from, to, func(event storage.Event) error {
+		return stream.Send(toPbEvent(event))
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return s.toStatusError(err)
+	}
+
+	return nil
+}
+
+// optionalTime returns the zero time if the timestamp is not set.
+func optionalTime(name string, ts *timestamppb.Timestamp) (time.Time, error) {
+	if ts == nil {
+		return time.Time{}, nil
+	}
+	if err := ts.CheckValid(); err != nil {
+		return time.Time{}, status.Error(codes.InvalidArgument, name+": "+err.Error())
+	}
+	return ts.AsTime(), nil
+}
@@ -0,0 +1,50 @@
+package internalgrpc
+
+import (
+	"context"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/app"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
+)
+
+func (s *Server) Search(ctx context.Context, req *pb.SearchRequest) (*pb.SearchResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := optionalTime("from", req.GetFrom())
+	if err != nil {
+		return nil, err
+	}
+	to, err := optionalTime("to", req.GetTo())
+	if err != nil {
+		return nil, err
+	}
+
+	query := app.SearchQuery{
+		Text:       req.GetQuery(),
+		From:       from,
+		To:         to,
+		CalendarID: req.GetCalendarId(),
+		Sort:       req.GetSort(),
+		Offset:     int(req.GetOffset()),
+		Limit:      int(req.GetLimit()),
+	}
+	if req.GetHasReminders() != nil {
+		hasReminders := req.GetHasReminders().GetValue()
+		query.HasReminders = &hasReminders
+	}
+
+	events, total, err := s.app.SearchEvents(ctx, userID, query)
+	if err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	res := &pb.SearchResponse{Events: make([]*pb.Event, 0, len(events)), Total: int32(total)}
+	for _, event := range events {
+		res.Events = append(res.Events, toPbEvent(event))
+	}
+
+	return res, nil
+}
@@ -0,0 +1,446 @@
+package internalgrpc
+
+//go:generate protoc -I ../../../api --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative ../../../api/EventService.proto
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/app"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/server/errcode"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const UserIDKey = "x-user-id"
+
+type Server struct {
+	pb.UnimplementedEventServiceServer
+	logger Logger
+	app    Application
+	host   string
+	port   string
+	server *grpc.Server
+}
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+}
+
+type Application interface {
+	CreateEvent(
+		ctx context.Context,
+		title, description, userID, calendarID string,
+		startAt, endAt time.Time,
+		reminders []time.Duration,
+		rrule, timezone string,
+		exDates []time.Time,
+		attendees []string,
+	) (storage.EventID, error)
+	UpdateEvent(
+		ctx context.Context,
+		eventID string,
+		version int64,
+		title, description, userID string,
+		startAt, endAt time.Time,
+		reminders []time.Duration,
+		rrule, timezone string,
+		exDates []time.Time,
+		attendees []string,
+	) (int64, error)
+	DeleteEvent(ctx context.Context, userID, id string, version int64) error
+	RespondToInvitation(ctx context.Context, eventID, userID, status string) (int64, error)
+	GetEventHistory(ctx context.Context, userID, eventID string) ([]storage.Change, error)
+	RestoreEvent(ctx context.Context, userID, eventID string, changeID int64) (int64, error)
+	ListDay(ctx context.Context, ownerID string, date time.Time, timezone string) ([]storage.Event, error)
+	ListWeek(ctx context.Context, ownerID string, date time.Time, timezone string) ([]storage.Event, error)
+	ListMonth(ctx context.Context, ownerID string, date time.Time, timezone string) ([]storage.Event, error)
+	ListEventsPage(
+		ctx context.Context,
+		userID string,
+		from, to time.Time,
+		token string,
+		pageSize int,
+	) ([]storage.Event, string, error)
+	ExportEvents(ctx context.Context, userID string, from, to time.Time, fn func(event storage.Event) error) error
+	FreeBusy(ctx context.Context, userIDs []string, from, to time.Time) (map[string][]app.Interval, error)
+	FindSlots(ctx context.Context, query app.SlotQuery) ([]app.Interval, error)
+	CreateCalendar(ctx context.Context, userID, name string) (storage.CalendarID, error)
+	GetCalendars(ctx context.Context, userID string) ([]storage.Calendar, error)
+	ShareCalendar(ctx context.Context, userID, calendarID, granteeID, access string) error
+	DeleteCalendar(ctx context.Context, userID, calendarID string) error
+	ListCalendarEvents(ctx context.Context, userID, calendarID string, from, to time.Time) ([]storage.Event, error)
+	SearchEvents(ctx context.Context, userID string, query app.SearchQuery) ([]storage.Event, int, error)
+}
+
+func NewServer(logger Logger, app Application, host, port string) *Server {
+	return &Server{
+		logger: logger,
+		app:    app,
+		host:   host,
+		port:   port,
+	}
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	lis, err := net.Listen("tcp", net.JoinHostPort(s.host, s.port))
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("grpc server listen at " + lis.Addr().String())
+
+	return s.serve(lis)
+}
+
+func (s *Server) serve(lis net.Listener) error {
+	s.server = grpc.NewServer(
+		grpc.UnaryInterceptor(loggingInterceptor(s.logger)),
+		grpc.StreamInterceptor(streamLoggingInterceptor(s.logger)),
+	)
+	pb.RegisterEventServiceServer(s.server, s)
+
+	if err := s.server.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+func (s *Server) Create(ctx context.Context, req *pb.CreateRequest) (*pb.CreateResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePeriod(req.GetStartAt(), req.GetEndAt()); err != nil {
+		return nil, err
+	}
+	exDates, err := fromPbTimestamps(req.GetExDates())
+	if err != nil {
+		return nil, err
+	}
+	reminders, err := fromPbReminders(req.GetNotifyBefore(), req.GetReminders())
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.app.CreateEvent(
+		ctx,
+		req.GetTitle(),
+		req.GetDescription(),
+		userID,
+		req.GetCalendarId(),
+		req.GetStartAt().AsTime(),
+		req.GetEndAt().AsTime(),
+		reminders,
+		req.GetRrule(),
+		req.GetTimezone(),
+		exDates,
+		req.GetAttendees(),
+	)
+	if err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	return &pb.CreateResponse{Id: id.String()}, nil
+}
+
+func (s *Server) Update(ctx context.Context, req *pb.UpdateRequest) (*pb.UpdateResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePeriod(req.GetStartAt(), req.GetEndAt()); err != nil {
+		return nil, err
+	}
+	exDates, err := fromPbTimestamps(req.GetExDates())
+	if err != nil {
+		return nil, err
+	}
+	reminders, err := fromPbReminders(req.GetNotifyBefore(), req.GetReminders())
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := s.app.UpdateEvent(
+		ctx,
+		req.GetId(),
+		req.GetVersion(),
+		req.GetTitle(),
+		req.GetDescription(),
+		userID,
+		req.GetStartAt().AsTime(),
+		req.GetEndAt().AsTime(),
+		reminders,
+		req.GetRrule(),
+		req.GetTimezone(),
+		exDates,
+		req.GetAttendees(),
+	)
+	if err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	return &pb.UpdateResponse{Version: version}, nil
+}
+
+func (s *Server) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.app.DeleteEvent(ctx, userID, req.GetId(), req.GetVersion()); err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	return &pb.DeleteResponse{}, nil
+}
+
+func (s *Server) Respond(ctx context.Context, req *pb.RespondRequest) (*pb.RespondResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := s.app.RespondToInvitation(ctx, req.GetId(), userID, req.GetStatus())
+	if err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	return &pb.RespondResponse{Version: version}, nil
+}
+
+func (s *Server) ListDay(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	return s.list(ctx, req, s.app.ListDay)
+}
+
+func (s *Server) ListWeek(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	return s.list(ctx, req, s.app.ListWeek)
+}
+
+func (s *Server) ListMonth(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	return s.list(ctx, req, s.app.ListMonth)
+}
+
+func (s *Server) FreeBusy(ctx context.Context, req *pb.FreeBusyRequest) (*pb.FreeBusyResponse, error) {
+	if _, err := userIDFromContext(ctx); err != nil {
+		return nil, err
+	}
+	if err := req.GetFrom().CheckValid(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "from: "+err.Error())
+	}
+	if err := req.GetTo().CheckValid(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "to: "+err.Error())
+	}
+
+	busy, err := s.app.FreeBusy(ctx, req.GetUserIds(), req.GetFrom().AsTime(), req.GetTo().AsTime())
+	if err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	res := &pb.FreeBusyResponse{Users: make([]*pb.UserBusy, 0, len(busy))}
+	seen := make(map[string]struct{}, len(busy))
+	for _, userID := range req.GetUserIds() {
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+
+		user := &pb.UserBusy{UserId: userID}
+		for _, interval := range busy[userID] {
+			user.Busy = append(user.Busy, &pb.Interval{
+				StartAt: timestamppb.New(interval.StartAt),
+				EndAt:   timestamppb.New(interval.EndAt),
+			})
+		}
+		res.Users = append(res.Users, user)
+	}
+
+	return res, nil
+}
+
+func (s *Server) FindSlots(ctx context.Context, req *pb.FindSlotsRequest) (*pb.FindSlotsResponse, error) {
+	if _, err := userIDFromContext(ctx); err != nil {
+		return nil, err
+	}
+	if err := req.GetFrom().CheckValid(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "from: "+err.Error())
+	}
+	if err := req.GetTo().CheckValid(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "to: "+err.Error())
+	}
+	hours, err := app.ParseWorkingHours(req.GetWorkingHours().GetStart(), req.GetWorkingHours().GetEnd())
+	if err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	query := app.SlotQuery{
+		Duration:     req.GetDuration().AsDuration(),
+		From:         req.GetFrom().AsTime(),
+		To:           req.GetTo().AsTime(),
+		WorkingHours: hours,
+		Gap:          req.GetGap().AsDuration(),
+		Limit:        int(req.GetLimit()),
+	}
+	for _, participant := range req.GetParticipants() {
+		query.Participants = append(query.Participants, app.Participant{
+			UserID:   participant.GetUserId(),
+			Timezone: participant.GetTimezone(),
+		})
+	}
+
+	slots, err := s.app.FindSlots(ctx, query)
+	if err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	res := &pb.FindSlotsResponse{Slots: make([]*pb.Interval, 0, len(slots))}
+	for _, slot := range slots {
+		res.Slots = append(res.Slots, &pb.Interval{
+			StartAt: timestamppb.New(slot.StartAt),
+			EndAt:   timestamppb.New(slot.EndAt),
+		})
+	}
+
+	return res, nil
+}
+
+type listFunc func(ctx context.Context, ownerID string, date time.Time, timezone string) ([]storage.Event, error)
+
+func (s *Server) list(ctx context.Context, req *pb.ListRequest, listPeriod listFunc) (*pb.ListResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.GetDate().CheckValid(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "date: "+err.Error())
+	}
+
+	events, err := listPeriod(ctx, userID, req.GetDate().AsTime(), req.GetTimezone())
+	if err != nil {
+		return nil, s.toStatusError(err)
+	}
+
+	res := &pb.ListResponse{Events: make([]*pb.Event, 0, len(events))}
+	for _, event := range events {
+		res.Events = append(res.Events, toPbEvent(event))
+	}
+
+	return res, nil
+}
+
+func toPbEvent(event storage.Event) *pb.Event {
+	res := &pb.Event{
+		Id:          event.ID.String(),
+		Title:       event.Title,
+		StartAt:     timestamppb.New(event.StartAt),
+		EndAt:       timestamppb.New(event.EndAt),
+		Description: event.Description,
+		OwnerId:     string(event.OwnerID),
+		Rrule:       event.RRule,
+		Timezone:    event.Timezone,
+		Version:     event.Version,
+		CalendarId:  event.CalendarID.String(),
+	}
+	for _, exDate := range event.ExDates {
+		res.ExDates = append(res.ExDates, timestamppb.New(exDate))
+	}
+	for _, attendee := range event.Attendees {
+		res.Attendees = append(res.Attendees, &pb.Attendee{UserId: string(attendee.UserID), Status: string(attendee.Status)})
+	}
+	for _, reminder := range event.Reminders {
+		res.Reminders = append(res.Reminders, durationpb.New(reminder.Offset))
+		if !reminder.Sent && (res.NotifyAt == nil || reminder.NotifyAt.Before(res.NotifyAt.AsTime())) {
+			res.NotifyAt = timestamppb.New(reminder.NotifyAt)
+		}
+	}
+	return res
+}
+
+func userIDFromContext(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "metadata "+UserIDKey+" is required")
+	}
+
+	values := md.Get(UserIDKey)
+	if len(values) == 0 || values[0] == "" {
+		return "", status.Error(codes.Unauthenticated, "metadata "+UserIDKey+" is required")
+	}
+
+	return values[0], nil
+}
+
+func validatePeriod(startAt, endAt *timestamppb.Timestamp) error {
+	if err := startAt.CheckValid(); err != nil {
+		return status.Error(codes.InvalidArgument, "start_at: "+err.Error())
+	}
+	if err := endAt.CheckValid(); err != nil {
+		return status.Error(codes.InvalidArgument, "end_at: "+err.Error())
+	}
+	return nil
+}
+
+func fromPbTimestamps(timestamps []*timestamppb.Timestamp) ([]time.Time, error) {
+	res := make([]time.Time, 0, len(timestamps))
+	for _, ts := range timestamps {
+		if err := ts.CheckValid(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "ex_dates: "+err.Error())
+		}
+		res = append(res, ts.AsTime())
+	}
+	return res, nil
+}
+
+// fromPbReminders returns the reminders together with notify_before if it is set.
+func fromPbReminders(notifyBefore *durationpb.Duration, reminders []*durationpb.Duration) ([]time.Duration, error) {
+	if notifyBefore != nil {
+		reminders = append([]*durationpb.Duration{notifyBefore}, reminders...)
+	}
+
+	res := make([]time.Duration, 0, len(reminders))
+	for _, reminder := range reminders {
+		if err := reminder.CheckValid(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "reminders: "+err.Error())
+		}
+		res = append(res, reminder.AsDuration())
+	}
+	return res, nil
+}
+
+// toStatusError hides the text of internal errors from clients and logs it instead.
+func (s *Server) toStatusError(err error) error {
+	code := errcode.Of(err)
+	if !code.Public() {
+		s.logger.Error("request failed: " + err.Error())
+	}
+	return status.Error(code.GRPCCode, code.Message(err))
+}
@@ -0,0 +1,602 @@
+package internalgrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/app"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/logger"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func newTestClient(t *testing.T) pb.EventServiceClient {
+	t.Helper()
+
+	logg := logger.New(logger.LevelError, io.Discard)
+	server := NewServer(logg, app.New(logg, memorystorage.New()), "", "")
+
+	lis := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = server.serve(lis)
+	}()
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop(context.Background())
+	})
+
+	return pb.NewEventServiceClient(conn)
+}
+
+func withUser(userID string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), UserIDKey, userID)
+}
+
+func TestServer_Create(t *testing.T) {
+	client := newTestClient(t)
+	startAt, _ := time.Parse(time.RFC3339, "2022-01-10T10:00:00Z")
+
+	t.Run(
+		"when user id is missing, returns unauthenticated", func(t *testing.T) {
+			_, err := client.Create(context.Background(), &pb.CreateRequest{
+				Title:   "test",
+				StartAt: timestamppb.New(startAt),
+				EndAt:   timestamppb.New(startAt.Add(time.Hour)),
+			})
+			require.Equal(t, codes.Unauthenticated, status.Code(err))
+		},
+	)
+
+	t.Run(
+		"when period is invalid, returns invalid argument", func(t *testing.T) {
+			_, err := client.Create(withUser("user"), &pb.CreateRequest{
+				Title:   "test",
+				StartAt: timestamppb.New(startAt),
+				EndAt:   timestamppb.New(startAt.Add(-time.Hour)),
+			})
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+		},
+	)
+
+	t.Run(
+		"when date is free, creates event", func(t *testing.T) {
+			res, err := client.Create(withUser("user"), &pb.CreateRequest{
+				Title:        "test",
+				StartAt:      timestamppb.New(startAt),
+				EndAt:        timestamppb.New(startAt.Add(time.Hour)),
+				NotifyBefore: durationpb.New(time.Hour),
+			})
+			require.NoError(t, err)
+			require.NotEmpty(t, res.GetId())
+		},
+	)
+
+	t.Run(
+		"when date is busy, returns already exists", func(t *testing.T) {
+			_, err := client.Create(withUser("user"), &pb.CreateRequest{
+				Title:   "test",
+				StartAt: timestamppb.New(startAt),
+				EndAt:   timestamppb.New(startAt.Add(time.Hour)),
+			})
+			require.Equal(t, codes.AlreadyExists, status.Code(err))
+		},
+	)
+
+	t.Run(
+		"when reminders are given, returns them in the event", func(t *testing.T) {
+			startAt := startAt.AddDate(0, 0, 1)
+			_, err := client.Create(withUser("user"), &pb.CreateRequest{
+				Title:        "test",
+				StartAt:      timestamppb.New(startAt),
+				EndAt:        timestamppb.New(startAt.Add(time.Hour)),
+				NotifyBefore: durationpb.New(15 * time.Minute),
+				Reminders:    []*durationpb.Duration{durationpb.New(24 * time.Hour)},
+			})
+			require.NoError(t, err)
+
+			list, err := client.ListDay(withUser("user"), &pb.ListRequest{Date: timestamppb.New(startAt)})
+			require.NoError(t, err)
+			require.Len(t, list.GetEvents(), 1)
+			reminders := list.GetEvents()[0].GetReminders()
+			require.Len(t, reminders, 2)
+			require.Equal(t, 24*time.Hour, reminders[0].AsDuration())
+			require.Equal(t, 15*time.Minute, reminders[1].AsDuration())
+		},
+	)
+}
+
+func TestServer_UpdateAndDelete(t *testing.T) {
+	client := newTestClient(t)
+	startAt, _ := time.Parse(time.RFC3339, "2022-01-10T10:00:00Z")
+
+	t.Run(
+		"when event does not exists, returns not found", func(t *testing.T) {
+			_, err := client.Update(withUser("user"), &pb.UpdateRequest{
+				Id:      "nonexistent",
+				Title:   "test",
+				StartAt: timestamppb.New(startAt),
+				EndAt:   timestamppb.New(startAt.Add(time.Hour)),
+			})
+			require.Equal(t, codes.NotFound, status.Code(err))
+
+			_, err = client.Delete(withUser("user"), &pb.DeleteRequest{Id: "nonexistent"})
+			require.Equal(t, codes.NotFound, status.Code(err))
+		},
+	)
+
+	t.Run(
+		"when event exists, updates and deletes it", func(t *testing.T) {
+			created, err := client.Create(withUser("user"), &pb.CreateRequest{
+				Title:   "test",
+				StartAt: timestamppb.New(startAt),
+				EndAt:   timestamppb.New(startAt.Add(time.Hour)),
+			})
+			require.NoError(t, err)
+
+			_, err = client.Update(withUser("user"), &pb.UpdateRequest{
+				Id:      created.GetId(),
+				Title:   "updated",
+				StartAt: timestamppb.New(startAt.Add(24 * time.Hour)),
+				EndAt:   timestamppb.New(startAt.Add(25 * time.Hour)),
+			})
+			require.NoError(t, err)
+
+			list, err := client.ListDay(withUser("user"), &pb.ListRequest{Date: timestamppb.New(startAt.Add(24 * time.Hour))})
+			require.NoError(t, err)
+			require.Len(t, list.GetEvents(), 1)
+			require.Equal(t, "updated", list.GetEvents()[0].GetTitle())
+
+			_, err = client.Delete(withUser("user"), &pb.DeleteRequest{Id: created.GetId()})
+			require.NoError(t, err)
+		},
+	)
+
+	t.Run(
+		"when version is stale, returns failed precondition", func(t *testing.T) {
+			startAt := startAt.AddDate(0, 0, 7)
+			created, err := client.Create(withUser("user"), &pb.CreateRequest{
+				Title:   "test",
+				StartAt: timestamppb.New(startAt),
+				EndAt:   timestamppb.New(startAt.Add(time.Hour)),
+			})
+			require.NoError(t, err)
+
+			update := &pb.UpdateRequest{
+				Id:      created.GetId(),
+				Version: 1,
+				Title:   "updated",
+				StartAt: timestamppb.New(startAt),
+				EndAt:   timestamppb.New(startAt.Add(time.Hour)),
+			}
+			updated, err := client.Update(withUser("user"), update)
+			require.NoError(t, err)
+			require.Equal(t, int64(2), updated.GetVersion())
+
+			_, err = client.Update(withUser("user"), update)
+			require.Equal(t, codes.FailedPrecondition, status.Code(err))
+			_, err = client.Delete(withUser("user"), &pb.DeleteRequest{Id: created.GetId(), Version: 1})
+			require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+			list, err := client.ListDay(withUser("user"), &pb.ListRequest{Date: timestamppb.New(startAt)})
+			require.NoError(t, err)
+			require.Len(t, list.GetEvents(), 1)
+			require.Equal(t, int64(2), list.GetEvents()[0].GetVersion())
+		},
+	)
+}
+
+func TestServer_Respond(t *testing.T) {
+	client := newTestClient(t)
+	startAt, _ := time.Parse(time.RFC3339, "2022-01-10T10:00:00Z")
+
+	created, err := client.Create(withUser("user"), &pb.CreateRequest{
+		Title:     "test",
+		StartAt:   timestamppb.New(startAt),
+		EndAt:     timestamppb.New(startAt.Add(time.Hour)),
+		Attendees: []string{"guest"},
+	})
+	require.NoError(t, err)
+
+	t.Run(
+		"when attendee responds, returns next version", func(t *testing.T) {
+			res, err := client.Respond(withUser("guest"), &pb.RespondRequest{Id: created.GetId(), Status: "accepted"})
+			require.NoError(t, err)
+			require.Equal(t, int64(2), res.GetVersion())
+
+			list, err := client.ListDay(withUser("guest"), &pb.ListRequest{Date: timestamppb.New(startAt)})
+			require.NoError(t, err)
+			require.Len(t, list.GetEvents(), 1)
+			require.Len(t, list.GetEvents()[0].GetAttendees(), 1)
+			require.Equal(t, "accepted", list.GetEvents()[0].GetAttendees()[0].GetStatus())
+		},
+	)
+
+	t.Run(
+		"when user is not invited, returns not found", func(t *testing.T) {
+			_, err := client.Respond(withUser("other"), &pb.RespondRequest{Id: created.GetId(), Status: "accepted"})
+			require.Equal(t, codes.NotFound, status.Code(err))
+		},
+	)
+
+	t.Run(
+		"when status is unknown, returns invalid argument", func(t *testing.T) {
+			_, err := client.Respond(withUser("guest"), &pb.RespondRequest{Id: created.GetId(), Status: "maybe"})
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+		},
+	)
+}
+
+func TestServer_FreeBusy(t *testing.T) {
+	client := newTestClient(t)
+	startAt, _ := time.Parse(time.RFC3339, "2022-01-10T10:00:00Z")
+
+	_, err := client.Create(withUser("alice"), &pb.CreateRequest{
+		Title:     "test",
+		StartAt:   timestamppb.New(startAt),
+		EndAt:     timestamppb.New(startAt.Add(time.Hour)),
+		Attendees: []string{"bob"},
+	})
+	require.NoError(t, err)
+
+	t.Run(
+		"when period is valid, returns busy intervals in the order of users", func(t *testing.T) {
+			res, err := client.FreeBusy(withUser("carol"), &pb.FreeBusyRequest{
+				UserIds: []string{"carol", "alice", "carol"},
+				From:    timestamppb.New(startAt.Truncate(24 * time.Hour)),
+				To:      timestamppb.New(startAt.Truncate(24 * time.Hour).Add(24 * time.Hour)),
+			})
+			require.NoError(t, err)
+			require.Len(t, res.GetUsers(), 2)
+			require.Equal(t, "carol", res.GetUsers()[0].GetUserId())
+			require.Empty(t, res.GetUsers()[0].GetBusy())
+			require.Equal(t, "alice", res.GetUsers()[1].GetUserId())
+			require.Len(t, res.GetUsers()[1].GetBusy(), 1)
+			require.Equal(t, startAt, res.GetUsers()[1].GetBusy()[0].GetStartAt().AsTime())
+		},
+	)
+
+	t.Run(
+		"when period is reversed, returns invalid argument", func(t *testing.T) {
+			_, err := client.FreeBusy(withUser("carol"), &pb.FreeBusyRequest{
+				UserIds: []string{"alice"},
+				From:    timestamppb.New(startAt),
+				To:      timestamppb.New(startAt.Add(-time.Hour)),
+			})
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+		},
+	)
+}
+
+func TestServer_FindSlots(t *testing.T) {
+	client := newTestClient(t)
+	startAt, _ := time.Parse(time.RFC3339, "2022-01-10T09:00:00Z")
+
+	_, err := client.Create(withUser("alice"), &pb.CreateRequest{
+		Title:   "test",
+		StartAt: timestamppb.New(startAt),
+		EndAt:   timestamppb.New(startAt.Add(time.Hour)),
+	})
+	require.NoError(t, err)
+
+	t.Run(
+		"when participants are free, returns slots", func(t *testing.T) {
+			res, err := client.FindSlots(withUser("alice"), &pb.FindSlotsRequest{
+				Participants: []*pb.SlotParticipant{{UserId: "alice"}, {UserId: "bob", Timezone: "Europe/Berlin"}},
+				Duration:     durationpb.New(time.Hour),
+				From:         timestamppb.New(startAt.Truncate(24 * time.Hour)),
+				To:           timestamppb.New(startAt.Truncate(24 * time.Hour).Add(24 * time.Hour)),
+				WorkingHours: &pb.WorkingHours{Start: "09:00", End: "18:00"},
+				Limit:        1,
+			})
+			require.NoError(t, err)
+			require.Len(t, res.GetSlots(), 1)
+			require.Equal(t, startAt.Add(time.Hour), res.GetSlots()[0].GetStartAt().AsTime())
+		},
+	)
+
+	t.Run(
+		"when duration is missing, returns invalid argument", func(t *testing.T) {
+			_, err := client.FindSlots(withUser("alice"), &pb.FindSlotsRequest{
+				Participants: []*pb.SlotParticipant{{UserId: "alice"}},
+				From:         timestamppb.New(startAt),
+				To:           timestamppb.New(startAt.Add(time.Hour)),
+			})
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+		},
+	)
+}
+
+func TestServer_Calendars(t *testing.T) {
+	client := newTestClient(t)
+	startAt, _ := time.Parse(time.RFC3339, "2022-01-10T10:00:00Z")
+	period := &pb.ListCalendarEventsRequest{
+		From: timestamppb.New(startAt.Truncate(24 * time.Hour)),
+		To:   timestamppb.New(startAt.Truncate(24 * time.Hour).Add(24 * time.Hour)),
+	}
+
+	calendar, err := client.CreateCalendar(withUser("alice"), &pb.CreateCalendarRequest{Name: "team"})
+	require.NoError(t, err)
+	period.CalendarId = calendar.GetId()
+
+	t.Run(
+		"when calendar is not shared, returns permission denied", func(t *testing.T) {
+			_, err := client.Create(withUser("bob"), &pb.CreateRequest{
+				Title:      "test",
+				StartAt:    timestamppb.New(startAt),
+				EndAt:      timestamppb.New(startAt.Add(time.Hour)),
+				CalendarId: calendar.GetId(),
+			})
+			require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+			_, err = client.ListCalendarEvents(withUser("bob"), period)
+			require.Equal(t, codes.PermissionDenied, status.Code(err))
+		},
+	)
+
+	t.Run(
+		"when write access is granted, creates events in the calendar", func(t *testing.T) {
+			_, err := client.ShareCalendar(withUser("alice"), &pb.ShareCalendarRequest{
+				CalendarId: calendar.GetId(),
+				UserId:     "bob",
+				Access:     "write",
+			})
+			require.NoError(t, err)
+
+			created, err := client.Create(withUser("bob"), &pb.CreateRequest{
+				Title:      "test",
+				StartAt:    timestamppb.New(startAt),
+				EndAt:      timestamppb.New(startAt.Add(time.Hour)),
+				CalendarId: calendar.GetId(),
+			})
+			require.NoError(t, err)
+
+			list, err := client.ListCalendarEvents(withUser("bob"), period)
+			require.NoError(t, err)
+			require.Len(t, list.GetEvents(), 1)
+			require.Equal(t, created.GetId(), list.GetEvents()[0].GetId())
+			require.Equal(t, "alice", list.GetEvents()[0].GetOwnerId())
+
+			calendars, err := client.ListCalendars(withUser("alice"), &pb.ListCalendarsRequest{})
+			require.NoError(t, err)
+			require.Len(t, calendars.GetCalendars(), 1)
+			require.Equal(t, "write", calendars.GetCalendars()[0].GetAccess())
+			require.Len(t, calendars.GetCalendars()[0].GetGrants(), 1)
+		},
+	)
+
+	t.Run(
+		"when grantee deletes calendar, returns permission denied", func(t *testing.T) {
+			_, err := client.DeleteCalendar(withUser("bob"), &pb.DeleteCalendarRequest{Id: calendar.GetId()})
+			require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+			_, err = client.DeleteCalendar(withUser("alice"), &pb.DeleteCalendarRequest{Id: calendar.GetId()})
+			require.NoError(t, err)
+		},
+	)
+}
+
+func TestServer_List(t *testing.T) {
+	client := newTestClient(t)
+	startAt, _ := time.Parse(time.RFC3339, "2022-01-10T10:00:00Z")
+
+	for _, offset := range []time.Duration{0, 3 * 24 * time.Hour, 20 * 24 * time.Hour} {
+		_, err := client.Create(withUser("user"), &pb.CreateRequest{
+			Title:   "test",
+			StartAt: timestamppb.New(startAt.Add(offset)),
+			EndAt:   timestamppb.New(startAt.Add(offset + time.Hour)),
+		})
+		require.NoError(t, err)
+	}
+
+	date := timestamppb.New(startAt.Truncate(24 * time.Hour))
+
+	day, err := client.ListDay(withUser("user"), &pb.ListRequest{Date: date})
+	require.NoError(t, err)
+	require.Len(t, day.GetEvents(), 1)
+
+	week, err := client.ListWeek(withUser("user"), &pb.ListRequest{Date: date})
+	require.NoError(t, err)
+	require.Len(t, week.GetEvents(), 2)
+
+	month, err := client.ListMonth(withUser("user"), &pb.ListRequest{Date: date})
+	require.NoError(t, err)
+	require.Len(t, month.GetEvents(), 3)
+
+	other, err := client.ListMonth(withUser("other"), &pb.ListRequest{Date: date})
+	require.NoError(t, err)
+	require.Empty(t, other.GetEvents())
+
+	_, err = client.ListDay(withUser("user"), &pb.ListRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_Search(t *testing.T) {
+	client := newTestClient(t)
+	startAt, _ := time.Parse(time.RFC3339, "2022-01-10T10:00:00Z")
+
+	for i, title := range []string{"Design review", "Review retro"} {
+		_, err := client.Create(withUser("alice"), &pb.CreateRequest{
+			Title:   title,
+			StartAt: timestamppb.New(startAt.AddDate(0, 0, i)),
+			EndAt:   timestamppb.New(startAt.AddDate(0, 0, i).Add(time.Hour)),
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run(
+		"when text matches, returns the page and the total", func(t *testing.T) {
+			res, err := client.Search(withUser("alice"), &pb.SearchRequest{
+				Query:        "review",
+				From:         timestamppb.New(startAt.AddDate(0, 0, 1)),
+				HasReminders: wrapperspb.Bool(false),
+			})
+			require.NoError(t, err)
+			require.Equal(t, int32(1), res.GetTotal())
+			require.Len(t, res.GetEvents(), 1)
+			require.Equal(t, "Review retro", res.GetEvents()[0].GetTitle())
+		},
+	)
+
+	t.Run(
+		"when sort is unknown, returns invalid argument", func(t *testing.T) {
+			_, err := client.Search(withUser("alice"), &pb.SearchRequest{Sort: "title"})
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+		},
+	)
+}
+
+func TestServer_ListPageAndExport(t *testing.T) {
+	client := newTestClient(t)
+	startAt, _ := time.Parse(time.RFC3339, "2022-01-10T10:00:00Z")
+
+	for i := 0; i < 3; i++ {
+		_, err := client.Create(withUser("alice"), &pb.CreateRequest{
+			Title:   "test",
+			StartAt: timestamppb.New(startAt.Add(time.Duration(i) * time.Hour)),
+			EndAt:   timestamppb.New(startAt.Add(time.Duration(i+1) * time.Hour)),
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run(
+		"when next page token is followed, returns the rest of the events", func(t *testing.T) {
+			first, err := client.ListPage(withUser("alice"), &pb.ListPageRequest{PageSize: 2})
+			require.NoError(t, err)
+			require.Len(t, first.GetEvents(), 2)
+			require.NotEmpty(t, first.GetNextPageToken())
+
+			second, err := client.ListPage(withUser("alice"), &pb.ListPageRequest{
+				PageSize:  2,
+				PageToken: first.GetNextPageToken(),
+			})
+			require.NoError(t, err)
+			require.Len(t, second.GetEvents(), 1)
+			require.Equal(t, startAt.Add(2*time.Hour), second.GetEvents()[0].GetStartAt().AsTime())
+			require.Empty(t, second.GetNextPageToken())
+		},
+	)
+
+	t.Run(
+		"when page token is malformed, returns invalid argument", func(t *testing.T) {
+			_, err := client.ListPage(withUser("alice"), &pb.ListPageRequest{PageToken: "garbage"})
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+		},
+	)
+
+	t.Run(
+		"when events are exported, streams all of them in order", func(t *testing.T) {
+			stream, err := client.Export(withUser("alice"), &pb.ExportRequest{From: timestamppb.New(startAt.Add(time.Hour))})
+			require.NoError(t, err)
+
+			var starts []time.Time
+			for {
+				event, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(t, err)
+				starts = append(starts, event.GetStartAt().AsTime())
+			}
+			require.Equal(t, []time.Time{startAt.Add(time.Hour), startAt.Add(2 * time.Hour)}, starts)
+		},
+	)
+
+	t.Run(
+		"when user is missing, export returns unauthenticated", func(t *testing.T) {
+			stream, err := client.Export(context.Background(), &pb.ExportRequest{})
+			require.NoError(t, err)
+			_, err = stream.Recv()
+			require.Equal(t, codes.Unauthenticated, status.Code(err))
+		},
+	)
+}
+
+func TestServer_HistoryAndRestore(t *testing.T) {
+	client := newTestClient(t)
+	startAt, _ := time.Parse(time.RFC3339, "2022-01-10T10:00:00Z")
+
+	created, err := client.Create(withUser("alice"), &pb.CreateRequest{
+		Title:   "draft",
+		StartAt: timestamppb.New(startAt),
+		EndAt:   timestamppb.New(startAt.Add(time.Hour)),
+	})
+	require.NoError(t, err)
+	_, err = client.Delete(withUser("alice"), &pb.DeleteRequest{Id: created.GetId()})
+	require.NoError(t, err)
+
+	t.Run(
+		"when event is deleted, restores it from the history", func(t *testing.T) {
+			history, err := client.History(withUser("alice"), &pb.HistoryRequest{Id: created.GetId()})
+			require.NoError(t, err)
+			require.Len(t, history.GetChanges(), 2)
+			require.Equal(t, "create", history.GetChanges()[0].GetKind())
+			require.Nil(t, history.GetChanges()[0].GetBefore())
+			require.Equal(t, "draft", history.GetChanges()[0].GetAfter().GetTitle())
+			require.Equal(t, "delete", history.GetChanges()[1].GetKind())
+
+			res, err := client.Restore(withUser("alice"), &pb.RestoreRequest{
+				Id:       created.GetId(),
+				ChangeId: history.GetChanges()[0].GetId(),
+			})
+			require.NoError(t, err)
+			require.Equal(t, int64(1), res.GetVersion())
+		},
+	)
+
+	t.Run(
+		"when user has no access, returns permission denied", func(t *testing.T) {
+			_, err := client.History(withUser("bob"), &pb.HistoryRequest{Id: created.GetId()})
+			require.Equal(t, codes.PermissionDenied, status.Code(err))
+		},
+	)
+}
+
+func TestServer_toStatusError(t *testing.T) {
+	var logs bytes.Buffer
+	server := NewServer(logger.New(logger.LevelError, &logs), nil, "", "")
+
+	t.Run(
+		"when error is unavailable, hides its text from client and logs it", func(t *testing.T) {
+			err := server.toStatusError(fmt.Errorf("%w: dial tcp 10.0.0.5:5432", storage.ErrUnavailable))
+			require.Equal(t, codes.Unavailable, status.Code(err))
+			require.NotContains(t, status.Convert(err).Message(), "10.0.0.5")
+			require.Contains(t, logs.String(), "10.0.0.5")
+		},
+	)
+
+	t.Run(
+		"when error is for client, returns its text", func(t *testing.T) {
+			err := server.toStatusError(fmt.Errorf("event 1: %w", storage.ErrNotFound))
+			require.Equal(t, codes.NotFound, status.Code(err))
+			require.Equal(t, "event 1: not found", status.Convert(err).Message())
+		},
+	)
+}
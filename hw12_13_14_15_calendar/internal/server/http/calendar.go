@@ -0,0 +1,54 @@
+package internalhttp
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/ics"
+)
+
+// CalendarHandler serves GET /users/{id}/calendar.ics, users may export only their own calendar.
+type CalendarHandler struct {
+	app Application
+}
+
+func (h *CalendarHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get(UserIDHeader)
+	if userID == "" {
+		writeError(w, http.StatusUnauthorized, "user_id_required", "header "+UserIDHeader+" is required")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/users"), "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "calendar.ics" {
+		writeError(w, http.StatusNotFound, "not_found", "route not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method "+r.Method+" is not allowed")
+		return
+	}
+	if parts[0] != userID {
+		writeError(w, http.StatusForbidden, "forbidden", "calendar of another user is not available")
+		return
+	}
+
+	events, err := h.app.GetUserEvents(r.Context(), userID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if err := ics.Encode(buf, events, time.Now()); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="calendar.ics"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
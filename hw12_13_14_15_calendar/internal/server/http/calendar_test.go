@@ -0,0 +1,54 @@
+package internalhttp
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/ics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarHandler(t *testing.T) {
+	handler := newTestHandler()
+
+	rec := doRequest(t, handler, http.MethodPost, "/events", "user",
+		`{"title": "standup", "startAt": "2022-01-10T10:00:00Z", "endAt": "2022-01-10T10:15:00Z", "rrule": "FREQ=DAILY"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	t.Run(
+		"when user header is missing, returns unauthorized", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/users/user/calendar.ics", "", "")
+			require.Equal(t, http.StatusUnauthorized, rec.Code)
+		},
+	)
+
+	t.Run(
+		"when calendar belongs to another user, returns forbidden", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/users/user/calendar.ics", "another", "")
+			require.Equal(t, http.StatusForbidden, rec.Code)
+			require.Equal(t, "forbidden", decodeError(t, rec).Code)
+		},
+	)
+
+	t.Run(
+		"when route is unknown, returns not found", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/users/user/calendar.json", "user", "")
+			require.Equal(t, http.StatusNotFound, rec.Code)
+		},
+	)
+
+	t.Run(
+		"when calendar belongs to user, returns events in iCalendar format", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/users/user/calendar.ics", "user", "")
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/calendar"))
+
+			events, err := ics.Decode(rec.Body)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			require.Equal(t, "standup", events[0].Title)
+			require.Equal(t, "FREQ=DAILY", events[0].RRule)
+		},
+	)
+}
@@ -0,0 +1,180 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// CalendarsHandler serves /calendars: the calendars of the user, their events and grants to other users.
+type CalendarsHandler struct {
+	app Application
+}
+
+type CalendarRequest struct {
+	Name string `json:"name"`
+}
+
+// GrantRequest is the access given to another user: free-busy, read, write or none.
+type GrantRequest struct {
+	Access string `json:"access"`
+}
+
+type CalendarResponse struct {
+	ID      string `json:"id"`
+	OwnerID string `json:"ownerId"`
+	Name    string `json:"name"`
+	// Access is the access of the requesting user.
+	Access string          `json:"access"`
+	Grants []GrantResponse `json:"grants"`
+}
+
+type GrantResponse struct {
+	UserID string `json:"userId"`
+	Access string `json:"access"`
+}
+
+type CreateCalendarResponse struct {
+	ID string `json:"id"`
+}
+
+type ListCalendarsResponse struct {
+	Calendars []CalendarResponse `json:"calendars"`
+}
+
+func (h *CalendarsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get(UserIDHeader)
+	if userID == "" {
+		writeError(w, http.StatusUnauthorized, "user_id_required", "header "+UserIDHeader+" is required")
+		return
+	}
+
+	var parts []string
+	if path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/calendars"), "/"); path != "" {
+		parts = strings.Split(path, "/")
+	}
+
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		h.list(w, r, userID)
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		h.create(w, r, userID)
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		h.delete(w, r, userID, parts[0])
+	case len(parts) == 2 && parts[1] == "events" && r.Method == http.MethodGet:
+		h.events(w, r, userID, parts[0])
+	case len(parts) == 3 && parts[1] == "grants" && r.Method == http.MethodPut:
+		h.share(w, r, userID, parts[0], parts[2])
+	case len(parts) == 3 && parts[1] == "grants" && r.Method == http.MethodDelete:
+		h.revoke(w, r, userID, parts[0], parts[2])
+	case len(parts) <= 1 || (len(parts) == 2 && parts[1] == "events") || (len(parts) == 3 && parts[1] == "grants"):
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method "+r.Method+" is not allowed")
+	default:
+		writeError(w, http.StatusNotFound, "not_found", "route not found")
+	}
+}
+
+func (h *CalendarsHandler) list(w http.ResponseWriter, r *http.Request, userID string) {
+	calendars, err := h.app.GetCalendars(r.Context(), userID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	res := ListCalendarsResponse{Calendars: make([]CalendarResponse, 0, len(calendars))}
+	for _, calendar := range calendars {
+		item := CalendarResponse{
+			ID:      calendar.ID.String(),
+			OwnerID: string(calendar.OwnerID),
+			Name:    calendar.Name,
+			Access:  string(calendar.AccessOf(storage.UserID(userID))),
+			Grants:  make([]GrantResponse, 0, len(calendar.Grants)),
+		}
+		for _, grant := range calendar.Grants {
+			item.Grants = append(item.Grants, GrantResponse{UserID: string(grant.UserID), Access: string(grant.Access)})
+		}
+		res.Calendars = append(res.Calendars, item)
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *CalendarsHandler) create(w http.ResponseWriter, r *http.Request, userID string) {
+	var req CalendarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "invalid request body: "+err.Error())
+		return
+	}
+
+	id, err := h.app.CreateCalendar(r.Context(), userID, req.Name)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, CreateCalendarResponse{ID: id.String()})
+}
+
+func (h *CalendarsHandler) delete(w http.ResponseWriter, r *http.Request, userID, id string) {
+	if err := h.app.DeleteCalendar(r.Context(), userID, id); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// events serves GET /calendars/{id}/events?from=...&to=..., from and to are RFC3339 instants.
+func (h *CalendarsHandler) events(w http.ResponseWriter, r *http.Request, userID, id string) {
+	query := r.URL.Query()
+	from, err := time.Parse(time.RFC3339, query.Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "from must be in RFC3339 format")
+		return
+	}
+	to, err := time.Parse(time.RFC3339, query.Get("to"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "to must be in RFC3339 format")
+		return
+	}
+
+	events, err := h.app.ListCalendarEvents(r.Context(), userID, id, from, to)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	res := ListEventsResponse{Events: make([]EventResponse, 0, len(events))}
+	for _, event := range events {
+		res.Events = append(res.Events, toEventResponse(event))
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *CalendarsHandler) share(w http.ResponseWriter, r *http.Request, userID, id, granteeID string) {
+	var req GrantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.app.ShareCalendar(r.Context(), userID, id, granteeID, req.Access); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CalendarsHandler) revoke(w http.ResponseWriter, r *http.Request, userID, id, granteeID string) {
+	if err := h.app.ShareCalendar(r.Context(), userID, id, granteeID, "none"); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
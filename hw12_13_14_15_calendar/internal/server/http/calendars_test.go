@@ -0,0 +1,99 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarsHandler(t *testing.T) {
+	handler := newTestHandler()
+
+	rec := doRequest(t, handler, http.MethodPost, "/calendars", "alice", `{"name": "work"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created CreateCalendarResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+
+	rec = doRequest(t, handler, http.MethodPost, "/events", "alice",
+		`{"title": "review", "startAt": "2022-01-10T10:00:00Z", "endAt": "2022-01-10T11:00:00Z", "calendarId": "`+
+			created.ID+`"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	eventsURL := "/calendars/" + created.ID + "/events?from=2022-01-10T00:00:00Z&to=2022-01-11T00:00:00Z"
+
+	t.Run(
+		"when calendar is not shared, returns forbidden", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, eventsURL, "bob", "")
+			require.Equal(t, http.StatusForbidden, rec.Code)
+			require.Equal(t, "forbidden", decodeError(t, rec).Code)
+		},
+	)
+
+	t.Run(
+		"when calendar is shared, returns its events", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPut, "/calendars/"+created.ID+"/grants/bob", "alice", `{"access": "read"}`)
+			require.Equal(t, http.StatusNoContent, rec.Code)
+
+			rec = doRequest(t, handler, http.MethodGet, eventsURL, "bob", "")
+			require.Equal(t, http.StatusOK, rec.Code)
+			var res ListEventsResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+			require.Len(t, res.Events, 1)
+			require.Equal(t, "review", res.Events[0].Title)
+			require.Equal(t, created.ID, res.Events[0].CalendarID)
+
+			rec = doRequest(t, handler, http.MethodGet, "/calendars", "bob", "")
+			require.Equal(t, http.StatusOK, rec.Code)
+			var calendars ListCalendarsResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&calendars))
+			require.Equal(t, []CalendarResponse{{
+				ID:      created.ID,
+				OwnerID: "alice",
+				Name:    "work",
+				Access:  "read",
+				Grants:  []GrantResponse{{UserID: "bob", Access: "read"}},
+			}}, calendars.Calendars)
+		},
+	)
+
+	t.Run(
+		"when grantee writes without write access, returns forbidden", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events", "bob",
+				`{"title": "sync", "startAt": "2022-01-10T12:00:00Z", "endAt": "2022-01-10T13:00:00Z", "calendarId": "`+
+					created.ID+`"}`)
+			require.Equal(t, http.StatusForbidden, rec.Code)
+
+			rec = doRequest(t, handler, http.MethodDelete, "/calendars/"+created.ID, "bob", "")
+			require.Equal(t, http.StatusForbidden, rec.Code)
+		},
+	)
+
+	t.Run(
+		"when grant is revoked, returns forbidden", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodDelete, "/calendars/"+created.ID+"/grants/bob", "alice", "")
+			require.Equal(t, http.StatusNoContent, rec.Code)
+
+			rec = doRequest(t, handler, http.MethodGet, eventsURL, "bob", "")
+			require.Equal(t, http.StatusForbidden, rec.Code)
+		},
+	)
+
+	t.Run(
+		"when route is unknown, returns not found", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/calendars/"+created.ID+"/settings", "alice", "")
+			require.Equal(t, http.StatusNotFound, rec.Code)
+		},
+	)
+
+	t.Run(
+		"when owner deletes calendar, returns no content", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodDelete, "/calendars/"+created.ID, "alice", "")
+			require.Equal(t, http.StatusNoContent, rec.Code)
+
+			rec = doRequest(t, handler, http.MethodGet, eventsURL, "alice", "")
+			require.Equal(t, http.StatusNotFound, rec.Code)
+		},
+	)
+}
@@ -0,0 +1,66 @@
+package internalhttp
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// FreeBusyHandler serves GET /freebusy?users=a,b&from=...&to=..., from and to are RFC3339 instants.
+type FreeBusyHandler struct {
+	app Application
+}
+
+type FreeBusyResponse struct {
+	// Busy are the merged busy intervals by user id.
+	Busy map[string][]IntervalResponse `json:"busy"`
+}
+
+type IntervalResponse struct {
+	StartAt time.Time `json:"startAt"`
+	EndAt   time.Time `json:"endAt"`
+}
+
+func (h *FreeBusyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(UserIDHeader) == "" {
+		writeError(w, http.StatusUnauthorized, "user_id_required", "header "+UserIDHeader+" is required")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method "+r.Method+" is not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := time.Parse(time.RFC3339, query.Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "from must be in RFC3339 format")
+		return
+	}
+	to, err := time.Parse(time.RFC3339, query.Get("to"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "to must be in RFC3339 format")
+		return
+	}
+
+	var users []string
+	if s := query.Get("users"); s != "" {
+		users = strings.Split(s, ",")
+	}
+
+	busy, err := h.app.FreeBusy(r.Context(), users, from, to)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	res := FreeBusyResponse{Busy: make(map[string][]IntervalResponse, len(busy))}
+	for userID, intervals := range busy {
+		res.Busy[userID] = make([]IntervalResponse, 0, len(intervals))
+		for _, interval := range intervals {
+			res.Busy[userID] = append(res.Busy[userID], IntervalResponse{StartAt: interval.StartAt, EndAt: interval.EndAt})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,57 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeBusyHandler(t *testing.T) {
+	handler := newTestHandler()
+
+	rec := doRequest(t, handler, http.MethodPost, "/events", "alice",
+		`{"title": "secret", "startAt": "2022-01-10T10:00:00Z", "endAt": "2022-01-10T11:00:00Z", "attendees": ["bob"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	rec = doRequest(t, handler, http.MethodPost, "/events", "alice",
+		`{"title": "lunch", "startAt": "2022-01-10T11:00:00Z", "endAt": "2022-01-10T12:00:00Z"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	t.Run(
+		"when period is valid, returns merged busy intervals by user", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet,
+				"/freebusy?users=alice,bob,carol&from=2022-01-10T00:00:00Z&to=2022-01-11T00:00:00Z", "carol", "")
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.NotContains(t, rec.Body.String(), "secret")
+
+			var res FreeBusyResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+			at := func(hour int) time.Time {
+				return time.Date(2022, time.January, 10, hour, 0, 0, 0, time.UTC)
+			}
+			require.Equal(t, map[string][]IntervalResponse{
+				"alice": {{StartAt: at(10), EndAt: at(12)}},
+				"bob":   {{StartAt: at(10), EndAt: at(11)}},
+				"carol": {},
+			}, res.Busy)
+		},
+	)
+
+	t.Run(
+		"when users are missing, returns validation error", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet,
+				"/freebusy?from=2022-01-10T00:00:00Z&to=2022-01-11T00:00:00Z", "carol", "")
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, "validation_error", decodeError(t, rec).Code)
+		},
+	)
+
+	t.Run(
+		"when period is malformed, returns validation error", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/freebusy?users=alice&from=2022-01-10&to=2022-01-11", "carol", "")
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+		},
+	)
+}
@@ -0,0 +1,444 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/app"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/server/errcode"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+const UserIDHeader = "X-User-Id"
+
+const dateLayout = "2006-01-02"
+
+type EventRequest struct {
+	Title        string      `json:"title"`
+	Description  string      `json:"description"`
+	StartAt      time.Time   `json:"startAt"`
+	EndAt        time.Time   `json:"endAt"`
+	NotifyBefore Duration    `json:"notifyBefore"`
+	Reminders    []Duration  `json:"reminders"`
+	RRule        string      `json:"rrule"`
+	ExDates      []time.Time `json:"exDates"`
+	// Timezone is the IANA timezone the series is expanded in, empty means UTC.
+	Timezone string `json:"timezone"`
+	// Attendees are ids of the invited users.
+	Attendees []string `json:"attendees"`
+	// CalendarID is the calendar of a new event, the default calendar of the user if empty.
+	CalendarID string `json:"calendarId"`
+}
+
+// reminders returns the reminder offsets, notifyBefore is a single reminder kept for compatibility.
+func (r EventRequest) reminders() []time.Duration {
+	res := make([]time.Duration, 0, len(r.Reminders)+1)
+	if r.NotifyBefore != 0 {
+		res = append(res, time.Duration(r.NotifyBefore))
+	}
+	for _, reminder := range r.Reminders {
+		res = append(res, time.Duration(reminder))
+	}
+	return res
+}
+
+type EventResponse struct {
+	ID          string      `json:"id"`
+	Title       string      `json:"title"`
+	StartAt     time.Time   `json:"startAt"`
+	EndAt       time.Time   `json:"endAt"`
+	Description string      `json:"description"`
+	OwnerID     string      `json:"ownerId"`
+	CalendarID  string      `json:"calendarId,omitempty"`
+	Version     int64       `json:"version"`
+	NotifyAt    *time.Time  `json:"notifyAt,omitempty"`
+	Reminders   []Duration  `json:"reminders,omitempty"`
+	RRule       string      `json:"rrule,omitempty"`
+	ExDates     []time.Time `json:"exDates,omitempty"`
+	Timezone    string      `json:"timezone,omitempty"`
+	Attendees   []Attendee  `json:"attendees,omitempty"`
+}
+
+type Attendee struct {
+	UserID string `json:"userId"`
+	Status string `json:"status"`
+}
+
+// RSVPRequest is the response of an invited user: needs-action, accepted, declined or tentative.
+type RSVPRequest struct {
+	Status string `json:"status"`
+}
+
+type CreateEventResponse struct {
+	ID string `json:"id"`
+}
+
+type ListEventsResponse struct {
+	Events []EventResponse `json:"events"`
+}
+
+type EventsPageResponse struct {
+	Events []EventResponse `json:"events"`
+	// NextPageToken continues the listing, it is empty on the last page.
+	NextPageToken string `json:"nextPageToken,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Duration is a time.Duration encoded in JSON as a string like "1h30m".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"15m\": %w", err)
+	}
+	if s == "" {
+		*d = 0
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+type EventsHandler struct {
+	app Application
+}
+
+func (h *EventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get(UserIDHeader)
+	if userID == "" {
+		writeError(w, http.StatusUnauthorized, "user_id_required", "header "+UserIDHeader+" is required")
+		return
+	}
+
+	id, action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/events"), "/"), ""
+	if i := strings.IndexByte(id, '/'); i >= 0 {
+		id, action = id[:i], id[i+1:]
+	}
+	if action != "" && action != "rsvp" && action != "history" && action != "restore" {
+		writeError(w, http.StatusNotFound, "not_found", "route not found")
+		return
+	}
+
+	switch {
+	case action == "rsvp" && r.Method == http.MethodPost:
+		h.rsvp(w, r, userID, id)
+	case action == "history" && r.Method == http.MethodGet:
+		h.history(w, r, userID, id)
+	case action == "restore" && r.Method == http.MethodPost:
+		h.restore(w, r, userID, id)
+	case action != "":
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method "+r.Method+" is not allowed")
+	case id == "" && r.Method == http.MethodGet:
+		h.list(w, r, userID)
+	case id == "" && r.Method == http.MethodPost:
+		h.create(w, r, userID)
+	case id != "" && r.Method == http.MethodGet:
+		h.get(w, r, userID, id)
+	case id != "" && r.Method == http.MethodPut:
+		h.update(w, r, userID, id)
+	case id != "" && r.Method == http.MethodDelete:
+		h.delete(w, r, userID, id)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method "+r.Method+" is not allowed")
+	}
+}
+
+func (h *EventsHandler) create(w http.ResponseWriter, r *http.Request, userID string) {
+	var req EventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "invalid request body: "+err.Error())
+		return
+	}
+
+	id, err := h.app.CreateEvent(
+		r.Context(),
+		req.Title,
+		req.Description,
+		userID,
+		req.CalendarID,
+		req.StartAt,
+		req.EndAt,
+		req.reminders(),
+		req.RRule,
+		req.Timezone,
+		req.ExDates,
+		req.Attendees,
+	)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, CreateEventResponse{ID: id.String()})
+}
+
+func (h *EventsHandler) get(w http.ResponseWriter, r *http.Request, userID, id string) {
+	event, err := h.app.GetEvent(r.Context(), userID, id)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(event.Version))
+	writeJSON(w, http.StatusOK, toEventResponse(*event))
+}
+
+func (h *EventsHandler) update(w http.ResponseWriter, r *http.Request, userID, id string) {
+	version, err := parseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "If-Match: "+err.Error())
+		return
+	}
+	var req EventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "invalid request body: "+err.Error())
+		return
+	}
+
+	version, err = h.app.UpdateEvent(
+		r.Context(),
+		id,
+		version,
+		req.Title,
+		req.Description,
+		userID,
+		req.StartAt,
+		req.EndAt,
+		req.reminders(),
+		req.RRule,
+		req.Timezone,
+		req.ExDates,
+		req.Attendees,
+	)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(version))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *EventsHandler) delete(w http.ResponseWriter, r *http.Request, userID, id string) {
+	version, err := parseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "If-Match: "+err.Error())
+		return
+	}
+
+	if err := h.app.DeleteEvent(r.Context(), userID, id, version); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *EventsHandler) rsvp(w http.ResponseWriter, r *http.Request, userID, id string) {
+	var req RSVPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "invalid request body: "+err.Error())
+		return
+	}
+
+	version, err := h.app.RespondToInvitation(r.Context(), id, userID, req.Status)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(version))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *EventsHandler) list(w http.ResponseWriter, r *http.Request, userID string) {
+	query := r.URL.Query()
+	if query.Get("period") == "" && (query.Get("from") != "" || query.Get("to") != "" || query.Get("pageToken") != "") {
+		h.page(w, r, userID)
+		return
+	}
+
+	timezone := query.Get("timezone")
+	loc, err := app.LoadLocation(timezone)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "timezone: "+err.Error())
+		return
+	}
+
+	date, err := parseDate(query.Get("date"), loc)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "date: "+err.Error())
+		return
+	}
+
+	var events []storage.Event
+	switch query.Get("period") {
+	case "day":
+		events, err = h.app.ListDay(r.Context(), userID, date, timezone)
+	case "week":
+		events, err = h.app.ListWeek(r.Context(), userID, date, timezone)
+	case "month":
+		events, err = h.app.ListMonth(r.Context(), userID, date, timezone)
+	default:
+		writeError(w, http.StatusBadRequest, "validation_error", "period must be one of day, week, month")
+		return
+	}
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	res := ListEventsResponse{Events: make([]EventResponse, 0, len(events))}
+	for _, event := range events {
+		res.Events = append(res.Events, toEventResponse(event))
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// page serves GET /events?from=...&to=...&limit=...&pageToken=..., from and to are optional RFC3339 instants.
+// Series are listed once as stored, the next page is requested with nextPageToken of the response.
+func (h *EventsHandler) page(w http.ResponseWriter, r *http.Request, userID string) {
+	query := r.URL.Query()
+
+	var from, to time.Time
+	var err error
+	if s := query.Get("from"); s != "" {
+		if from, err = time.Parse(time.RFC3339, s); err != nil {
+			writeError(w, http.StatusBadRequest, "validation_error", "from must be in RFC3339 format")
+			return
+		}
+	}
+	if s := query.Get("to"); s != "" {
+		if to, err = time.Parse(time.RFC3339, s); err != nil {
+			writeError(w, http.StatusBadRequest, "validation_error", "to must be in RFC3339 format")
+			return
+		}
+	}
+	var limit int
+	if s := query.Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil {
+			writeError(w, http.StatusBadRequest, "validation_error", "limit must be an integer")
+			return
+		}
+	}
+
+	events, next, err := h.app.ListEventsPage(r.Context(), userID, from, to, query.Get("pageToken"), limit)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	res := EventsPageResponse{Events: make([]EventResponse, 0, len(events)), NextPageToken: next}
+	for _, event := range events {
+		res.Events = append(res.Events, toEventResponse(event))
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// parseDate parses a calendar date in the location or an RFC3339 instant.
+func parseDate(s string, loc *time.Location) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("is required")
+	}
+	if date, err := time.ParseInLocation(dateLayout, s, loc); err == nil {
+		return date, nil
+	}
+	date, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be in %s or RFC3339 format", dateLayout)
+	}
+	return date, nil
+}
+
+// etag returns the strong entity tag of the event version.
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// parseIfMatch returns the version from If-Match header, zero if the header is empty or "*".
+func parseIfMatch(header string) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	value, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, fmt.Errorf("must be a single entity tag like %s", etag(1))
+	}
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("unknown entity tag %s", header)
+	}
+	return version, nil
+}
+
+func toEventResponse(event storage.Event) EventResponse {
+	res := EventResponse{
+		ID:          event.ID.String(),
+		Title:       event.Title,
+		StartAt:     event.StartAt,
+		EndAt:       event.EndAt,
+		Description: event.Description,
+		OwnerID:     string(event.OwnerID),
+		CalendarID:  event.CalendarID.String(),
+		Version:     event.Version,
+		RRule:       event.RRule,
+		ExDates:     event.ExDates,
+		Timezone:    event.Timezone,
+	}
+	for _, attendee := range event.Attendees {
+		res.Attendees = append(res.Attendees, Attendee{UserID: string(attendee.UserID), Status: string(attendee.Status)})
+	}
+	for _, reminder := range event.Reminders {
+		res.Reminders = append(res.Reminders, Duration(reminder.Offset))
+		if !reminder.Sent && (res.NotifyAt == nil || reminder.NotifyAt.Before(*res.NotifyAt)) {
+			notifyAt := reminder.NotifyAt
+			res.NotifyAt = &notifyAt
+		}
+	}
+	return res
+}
+
+// writeAppError hides the text of internal errors from clients, the logging middleware logs it instead.
+func writeAppError(w http.ResponseWriter, err error) {
+	code := errcode.Of(err)
+	if recorder, ok := w.(*StatusRecorder); ok && !code.Public() {
+		recorder.Err = err
+	}
+	writeError(w, code.HTTPStatus, code.Name, code.Message(err))
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,501 @@
+package internalhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/app"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/logger"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHandler() http.Handler {
+	logg := logger.New(logger.LevelError, io.Discard)
+	return NewServer(logg, app.New(logg, memorystorage.New()), "", "").handler()
+}
+
+func doRequest(t *testing.T, handler http.Handler, method, target, userID, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader io.Reader
+	if body != "" {
+		reader = bytes.NewBufferString(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if userID != "" {
+		req.Header.Set(UserIDHeader, userID)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) ErrorBody {
+	t.Helper()
+
+	var res ErrorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	return res.Error
+}
+
+func TestEventsHandler_Create(t *testing.T) {
+	handler := newTestHandler()
+
+	t.Run(
+		"when user header is missing, returns unauthorized", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events", "", `{}`)
+			require.Equal(t, http.StatusUnauthorized, rec.Code)
+			require.Equal(t, "user_id_required", decodeError(t, rec).Code)
+		},
+	)
+
+	t.Run(
+		"when body is invalid, returns validation error", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events", "user", `{"title": "test", "notifyBefore": 10}`)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, "validation_error", decodeError(t, rec).Code)
+		},
+	)
+
+	t.Run(
+		"when period is invalid, returns validation error", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events", "user",
+				`{"title": "test", "startAt": "2022-01-10T11:00:00Z", "endAt": "2022-01-10T10:00:00Z"}`)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, "validation_error", decodeError(t, rec).Code)
+		},
+	)
+
+	t.Run(
+		"when date is free, creates event", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events", "user",
+				`{"title": "test", "startAt": "2022-01-10T10:00:00Z", "endAt": "2022-01-10T11:00:00Z", "notifyBefore": "1h"}`)
+			require.Equal(t, http.StatusCreated, rec.Code)
+
+			var res CreateEventResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+			require.NotEmpty(t, res.ID)
+		},
+	)
+
+	t.Run(
+		"when date is busy, returns conflict", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events", "user",
+				`{"title": "test", "startAt": "2022-01-10T10:00:00Z", "endAt": "2022-01-10T11:00:00Z"}`)
+			require.Equal(t, http.StatusConflict, rec.Code)
+			require.Equal(t, "date_busy", decodeError(t, rec).Code)
+		},
+	)
+
+	t.Run(
+		"when reminders are given, returns them in the event", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events", "user",
+				`{"title": "test", "startAt": "2022-01-11T10:00:00Z", "endAt": "2022-01-11T11:00:00Z",
+				"notifyBefore": "15m", "reminders": ["24h", "15m"]}`)
+			require.Equal(t, http.StatusCreated, rec.Code)
+
+			rec = doRequest(t, handler, http.MethodGet, "/events?period=day&date=2022-01-11", "user", "")
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var list ListEventsResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+			require.Len(t, list.Events, 1)
+			require.Equal(t, []Duration{Duration(24 * time.Hour), Duration(15 * time.Minute)}, list.Events[0].Reminders)
+		},
+	)
+
+	t.Run(
+		"when timezone is given, expands series in it across DST change", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events", "user",
+				`{"title": "standup", "startAt": "2022-03-21T09:00:00+01:00", "endAt": "2022-03-21T10:00:00+01:00",
+				"rrule": "FREQ=WEEKLY;COUNT=3", "timezone": "Europe/Berlin"}`)
+			require.Equal(t, http.StatusCreated, rec.Code)
+
+			rec = doRequest(t, handler, http.MethodGet, "/events?period=day&date=2022-04-04", "user", "")
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var list ListEventsResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+			require.Len(t, list.Events, 1)
+			require.Equal(t, time.Date(2022, time.April, 4, 7, 0, 0, 0, time.UTC), list.Events[0].StartAt.UTC())
+			require.Equal(t, "Europe/Berlin", list.Events[0].Timezone)
+		},
+	)
+
+	t.Run(
+		"when reminder is negative, returns validation error", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events", "user",
+				`{"title": "test", "startAt": "2022-01-12T10:00:00Z", "endAt": "2022-01-12T11:00:00Z", "reminders": ["-1h"]}`)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, "validation_error", decodeError(t, rec).Code)
+		},
+	)
+}
+
+func TestEventsHandler_UpdateAndDelete(t *testing.T) {
+	handler := newTestHandler()
+
+	t.Run(
+		"when event does not exists, returns not found", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPut, "/events/nonexistent", "user",
+				`{"title": "test", "startAt": "2022-01-10T10:00:00Z", "endAt": "2022-01-10T11:00:00Z"}`)
+			require.Equal(t, http.StatusNotFound, rec.Code)
+			require.Equal(t, "not_found", decodeError(t, rec).Code)
+
+			rec = doRequest(t, handler, http.MethodDelete, "/events/nonexistent", "user", "")
+			require.Equal(t, http.StatusNotFound, rec.Code)
+			require.Equal(t, "not_found", decodeError(t, rec).Code)
+		},
+	)
+
+	t.Run(
+		"when event exists, updates and deletes it", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events", "user",
+				`{"title": "test", "startAt": "2022-01-10T10:00:00Z", "endAt": "2022-01-10T11:00:00Z"}`)
+			require.Equal(t, http.StatusCreated, rec.Code)
+			var created CreateEventResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+
+			rec = doRequest(t, handler, http.MethodPut, "/events/"+created.ID, "user",
+				`{"title": "updated", "startAt": "2022-01-11T10:00:00Z", "endAt": "2022-01-11T11:00:00Z"}`)
+			require.Equal(t, http.StatusNoContent, rec.Code)
+
+			rec = doRequest(t, handler, http.MethodGet, "/events?period=day&date=2022-01-11", "user", "")
+			require.Equal(t, http.StatusOK, rec.Code)
+			var list ListEventsResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+			require.Len(t, list.Events, 1)
+			require.Equal(t, "updated", list.Events[0].Title)
+
+			rec = doRequest(t, handler, http.MethodDelete, "/events/"+created.ID, "user", "")
+			require.Equal(t, http.StatusNoContent, rec.Code)
+		},
+	)
+}
+
+func TestEventsHandler_Version(t *testing.T) {
+	handler := newTestHandler()
+	body := `{"title": "test", "startAt": "2022-01-10T10:00:00Z", "endAt": "2022-01-10T11:00:00Z"}`
+	rec := doRequest(t, handler, http.MethodPost, "/events", "user", body)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created CreateEventResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+
+	doIfMatch := func(method, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/events/"+created.ID, bytes.NewBufferString(body))
+		req.Header.Set(UserIDHeader, "user")
+		req.Header.Set("If-Match", ifMatch)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run(
+		"when event is requested, returns its version as etag", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/events/"+created.ID, "user", "")
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Equal(t, `"1"`, rec.Header().Get("ETag"))
+
+			var event EventResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&event))
+			require.Equal(t, int64(1), event.Version)
+		},
+	)
+
+	t.Run(
+		"when if-match is current, updates event and returns next etag", func(t *testing.T) {
+			rec := doIfMatch(http.MethodPut, `"1"`, body)
+			require.Equal(t, http.StatusNoContent, rec.Code)
+			require.Equal(t, `"2"`, rec.Header().Get("ETag"))
+		},
+	)
+
+	t.Run(
+		"when if-match is stale, returns precondition failed", func(t *testing.T) {
+			rec := doIfMatch(http.MethodPut, `"1"`, body)
+			require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+			require.Equal(t, "version_mismatch", decodeError(t, rec).Code)
+
+			rec = doIfMatch(http.MethodDelete, `"1"`, "")
+			require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+		},
+	)
+
+	t.Run(
+		"when if-match is malformed, returns validation error", func(t *testing.T) {
+			rec := doIfMatch(http.MethodPut, "2", body)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, "validation_error", decodeError(t, rec).Code)
+		},
+	)
+
+	t.Run(
+		"when if-match is any, deletes event", func(t *testing.T) {
+			rec := doIfMatch(http.MethodDelete, "*", "")
+			require.Equal(t, http.StatusNoContent, rec.Code)
+		},
+	)
+}
+
+func TestEventsHandler_RSVP(t *testing.T) {
+	handler := newTestHandler()
+	body := `{"title": "test", "startAt": "2022-01-10T10:00:00Z", "endAt": "2022-01-10T11:00:00Z", "attendees": ["guest"]}`
+	rec := doRequest(t, handler, http.MethodPost, "/events", "user", body)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created CreateEventResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+
+	t.Run(
+		"when event is requested, returns attendees", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/events/"+created.ID, "guest", "")
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var event EventResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&event))
+			require.Equal(t, []Attendee{{UserID: "guest", Status: "needs-action"}}, event.Attendees)
+		},
+	)
+
+	t.Run(
+		"when attendee responds, returns next etag", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events/"+created.ID+"/rsvp", "guest", `{"status": "accepted"}`)
+			require.Equal(t, http.StatusNoContent, rec.Code)
+			require.Equal(t, `"2"`, rec.Header().Get("ETag"))
+		},
+	)
+
+	t.Run(
+		"when user is not invited, returns not found", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events/"+created.ID+"/rsvp", "other", `{"status": "accepted"}`)
+			require.Equal(t, http.StatusNotFound, rec.Code)
+		},
+	)
+
+	t.Run(
+		"when status is unknown, returns validation error", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events/"+created.ID+"/rsvp", "guest", `{"status": "maybe"}`)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, "validation_error", decodeError(t, rec).Code)
+		},
+	)
+
+	t.Run(
+		"when action is unknown, returns not found", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events/"+created.ID+"/share", "guest", "")
+			require.Equal(t, http.StatusNotFound, rec.Code)
+		},
+	)
+}
+
+func TestEventsHandler_List(t *testing.T) {
+	handler := newTestHandler()
+
+	for _, body := range []string{
+		`{"title": "a", "startAt": "2022-01-10T10:00:00Z", "endAt": "2022-01-10T11:00:00Z"}`,
+		`{"title": "b", "startAt": "2022-01-13T10:00:00Z", "endAt": "2022-01-13T11:00:00Z"}`,
+		`{"title": "c", "startAt": "2022-01-30T10:00:00Z", "endAt": "2022-01-30T11:00:00Z"}`,
+	} {
+		rec := doRequest(t, handler, http.MethodPost, "/events", "user", body)
+		require.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	tests := []struct {
+		period   string
+		expected int
+	}{
+		{period: "day", expected: 1},
+		{period: "week", expected: 2},
+		{period: "month", expected: 3},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.period, func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/events?period="+tc.period+"&date=2022-01-10", "user", "")
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var list ListEventsResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+			require.Len(t, list.Events, tc.expected)
+		})
+	}
+
+	t.Run(
+		"when period is unknown, returns validation error", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/events?period=year&date=2022-01-10", "user", "")
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, "validation_error", decodeError(t, rec).Code)
+		},
+	)
+
+	t.Run(
+		"when timezone is given, lists the day in the timezone", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet,
+				"/events?period=day&date=2022-01-31&timezone=Pacific/Kiritimati", "user", "")
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var list ListEventsResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+			require.Len(t, list.Events, 1, "2022-01-30 10:00 UTC is 2022-01-31 00:00 in Kiritimati")
+			require.Equal(t, "c", list.Events[0].Title)
+		},
+	)
+
+	t.Run(
+		"when timezone is unknown, returns validation error", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/events?period=day&date=2022-01-10&timezone=Mars/Olympus", "user", "")
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, "validation_error", decodeError(t, rec).Code)
+		},
+	)
+
+	t.Run(
+		"when date is invalid, returns validation error", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/events?period=day&date=10.01.2022", "user", "")
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, "validation_error", decodeError(t, rec).Code)
+		},
+	)
+}
+
+func TestEventsHandler_Page(t *testing.T) {
+	handler := newTestHandler()
+
+	for _, body := range []string{
+		`{"title": "a", "startAt": "2022-01-10T10:00:00Z", "endAt": "2022-01-10T11:00:00Z"}`,
+		`{"title": "b", "startAt": "2022-01-13T10:00:00Z", "endAt": "2022-01-13T11:00:00Z"}`,
+		`{"title": "c", "startAt": "2022-01-30T10:00:00Z", "endAt": "2022-01-30T11:00:00Z"}`,
+	} {
+		rec := doRequest(t, handler, http.MethodPost, "/events", "user", body)
+		require.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	page := func(target string) EventsPageResponse {
+		rec := doRequest(t, handler, http.MethodGet, target, "user", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var res EventsPageResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+		return res
+	}
+
+	t.Run(
+		"when next page token is followed, returns the rest of the events", func(t *testing.T) {
+			first := page("/events?from=2022-01-01T00:00:00Z&to=2022-02-01T00:00:00Z&limit=2")
+			require.Len(t, first.Events, 2)
+			require.Equal(t, "a", first.Events[0].Title)
+			require.NotEmpty(t, first.NextPageToken)
+
+			second := page("/events?from=2022-01-01T00:00:00Z&to=2022-02-01T00:00:00Z&limit=2&pageToken=" +
+				url.QueryEscape(first.NextPageToken))
+			require.Len(t, second.Events, 1)
+			require.Equal(t, "c", second.Events[0].Title)
+			require.Empty(t, second.NextPageToken)
+		},
+	)
+
+	t.Run(
+		"when token or limit is malformed, returns validation error", func(t *testing.T) {
+			for _, target := range []string{
+				"/events?from=2022-01-01T00:00:00Z&pageToken=garbage",
+				"/events?from=2022-01-01T00:00:00Z&limit=many",
+				"/events?from=2022-01-01",
+			} {
+				rec := doRequest(t, handler, http.MethodGet, target, "user", "")
+				require.Equal(t, http.StatusBadRequest, rec.Code, target)
+				require.Equal(t, "validation_error", decodeError(t, rec).Code, target)
+			}
+		},
+	)
+}
+
+func TestEventsHandler_HistoryAndRestore(t *testing.T) {
+	handler := newTestHandler()
+
+	rec := doRequest(t, handler, http.MethodPost, "/events", "user",
+		`{"title": "draft", "startAt": "2022-01-10T10:00:00Z", "endAt": "2022-01-10T11:00:00Z"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created CreateEventResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+
+	rec = doRequest(t, handler, http.MethodDelete, "/events/"+created.ID, "user", "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	t.Run(
+		"when event is deleted, returns its history", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/events/"+created.ID+"/history", "user", "")
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var res HistoryResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+			require.Len(t, res.Changes, 2)
+			require.Equal(t, "create", res.Changes[0].Kind)
+			require.Equal(t, "user", res.Changes[0].ActorID)
+			require.Nil(t, res.Changes[0].Before)
+			require.Equal(t, "draft", res.Changes[0].After.Title)
+			require.Equal(t, "delete", res.Changes[1].Kind)
+			require.Nil(t, res.Changes[1].After)
+		},
+	)
+
+	t.Run(
+		"when change is restored, brings the event back", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/events/"+created.ID+"/restore", "user", `{"changeId": 1}`)
+			require.Equal(t, http.StatusNoContent, rec.Code)
+			require.Equal(t, `"1"`, rec.Header().Get("ETag"))
+
+			rec = doRequest(t, handler, http.MethodGet, "/events/"+created.ID, "user", "")
+			require.Equal(t, http.StatusOK, rec.Code)
+		},
+	)
+
+	t.Run(
+		"when user has no access, returns forbidden", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/events/"+created.ID+"/history", "other", "")
+			require.Equal(t, http.StatusForbidden, rec.Code)
+
+			rec = doRequest(t, handler, http.MethodPut, "/events/"+created.ID+"/history", "user", "")
+			require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		},
+	)
+}
+
+func TestWriteAppError(t *testing.T) {
+	serve := func(err error) (*httptest.ResponseRecorder, string) {
+		var logs bytes.Buffer
+		handler := loggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeAppError(w, err)
+		}), logger.New(logger.LevelError, &logs))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+		return rec, logs.String()
+	}
+
+	t.Run(
+		"when error is internal, hides its text from client and logs it", func(t *testing.T) {
+			rec, logs := serve(errors.New("pq: password authentication failed"))
+			require.Equal(t, http.StatusInternalServerError, rec.Code)
+			require.NotContains(t, rec.Body.String(), "password")
+			require.Equal(t, "internal_error", decodeError(t, rec).Code)
+			require.Contains(t, logs, "password authentication failed")
+		},
+	)
+
+	t.Run(
+		"when error is for client, returns its text", func(t *testing.T) {
+			rec, logs := serve(fmt.Errorf("event 1: %w", storage.ErrNotFound))
+			require.Equal(t, http.StatusNotFound, rec.Code)
+			require.Equal(t, "event 1: not found", decodeError(t, rec).Message)
+			require.Empty(t, logs)
+		},
+	)
+}
@@ -0,0 +1,77 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// RestoreRequest selects the change whose state the event is brought back to.
+type RestoreRequest struct {
+	ChangeID int64 `json:"changeId"`
+}
+
+type ChangeResponse struct {
+	ID      int64     `json:"id"`
+	Kind    string    `json:"kind"`
+	ActorID string    `json:"actorId"`
+	At      time.Time `json:"at"`
+	// Before is missing for a created event, After is missing for a deleted one.
+	Before *EventResponse `json:"before,omitempty"`
+	After  *EventResponse `json:"after,omitempty"`
+}
+
+type HistoryResponse struct {
+	Changes []ChangeResponse `json:"changes"`
+}
+
+// history serves GET /events/{id}/history, the history of a deleted event is available too.
+func (h *EventsHandler) history(w http.ResponseWriter, r *http.Request, userID, id string) {
+	changes, err := h.app.GetEventHistory(r.Context(), userID, id)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	res := HistoryResponse{Changes: make([]ChangeResponse, 0, len(changes))}
+	for _, change := range changes {
+		res.Changes = append(res.Changes, ChangeResponse{
+			ID:      change.ID,
+			Kind:    string(change.Kind),
+			ActorID: string(change.ActorID),
+			At:      change.At,
+			Before:  toSnapshotResponse(change.Before),
+			After:   toSnapshotResponse(change.After),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// restore serves POST /events/{id}/restore, the ETag of the response is the new version of the event.
+func (h *EventsHandler) restore(w http.ResponseWriter, r *http.Request, userID, id string) {
+	var req RestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "invalid request body: "+err.Error())
+		return
+	}
+
+	version, err := h.app.RestoreEvent(r.Context(), userID, id, req.ChangeID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(version))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toSnapshotResponse(event *storage.Event) *EventResponse {
+	if event == nil {
+		return nil
+	}
+	res := toEventResponse(*event)
+	return &res
+}
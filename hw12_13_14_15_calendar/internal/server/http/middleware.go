@@ -26,12 +26,17 @@ func loggingMiddleware(next http.Handler, logger Logger) http.Handler {
 			r.Header.Get("user-agent"),
 		)
 		logger.Info(msg)
+		if recorder.Err != nil {
+			logger.Error(fmt.Sprintf("%s %s: %s", r.Method, r.URL.Path, recorder.Err))
+		}
 	})
 }
 
 type StatusRecorder struct {
 	http.ResponseWriter
 	Status int
+	// Err is the internal error hidden from the client.
+	Err error
 }
 
 func (r *StatusRecorder) WriteHeader(status int) {
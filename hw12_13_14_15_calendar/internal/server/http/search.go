@@ -0,0 +1,93 @@
+package internalhttp
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/app"
+)
+
+// SearchHandler serves GET /search, all parameters are optional: q, from and to as RFC3339 instants,
+// hasReminders, calendarId, sort (start, -start or relevance), offset and limit.
+type SearchHandler struct {
+	app Application
+}
+
+type SearchResponse struct {
+	Events []EventResponse `json:"events"`
+	// Total is the number of all matching events, not only of the page.
+	Total int `json:"total"`
+}
+
+func (h *SearchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get(UserIDHeader)
+	if userID == "" {
+		writeError(w, http.StatusUnauthorized, "user_id_required", "header "+UserIDHeader+" is required")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method "+r.Method+" is not allowed")
+		return
+	}
+
+	query, msg := parseSearchQuery(r)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, "validation_error", msg)
+		return
+	}
+
+	events, total, err := h.app.SearchEvents(r.Context(), userID, query)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	res := SearchResponse{Events: make([]EventResponse, 0, len(events)), Total: total}
+	for _, event := range events {
+		res.Events = append(res.Events, toEventResponse(event))
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// parseSearchQuery returns the query of the request or the message describing the invalid parameter.
+func parseSearchQuery(r *http.Request) (app.SearchQuery, string) {
+	values := r.URL.Query()
+	query := app.SearchQuery{
+		Text:       values.Get("q"),
+		CalendarID: values.Get("calendarId"),
+		Sort:       values.Get("sort"),
+	}
+
+	var err error
+	if s := values.Get("from"); s != "" {
+		if query.From, err = time.Parse(time.RFC3339, s); err != nil {
+			return query, "from must be in RFC3339 format"
+		}
+	}
+	if s := values.Get("to"); s != "" {
+		if query.To, err = time.Parse(time.RFC3339, s); err != nil {
+			return query, "to must be in RFC3339 format"
+		}
+	}
+	if s := values.Get("hasReminders"); s != "" {
+		hasReminders, err := strconv.ParseBool(s)
+		if err != nil {
+			return query, "hasReminders must be true or false"
+		}
+		query.HasReminders = &hasReminders
+	}
+	if s := values.Get("offset"); s != "" {
+		if query.Offset, err = strconv.Atoi(s); err != nil {
+			return query, "offset must be an integer"
+		}
+	}
+	if s := values.Get("limit"); s != "" {
+		if query.Limit, err = strconv.Atoi(s); err != nil {
+			return query, "limit must be an integer"
+		}
+	}
+
+	return query, ""
+}
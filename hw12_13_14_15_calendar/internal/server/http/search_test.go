@@ -0,0 +1,65 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchHandler(t *testing.T) {
+	handler := newTestHandler()
+
+	rec := doRequest(t, handler, http.MethodPost, "/events", "alice",
+		`{"title": "Design review", "startAt": "2022-01-10T10:00:00Z", "endAt": "2022-01-10T11:00:00Z",
+		"reminders": ["1h"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	rec = doRequest(t, handler, http.MethodPost, "/events", "alice",
+		`{"title": "Review retro", "startAt": "2022-01-11T10:00:00Z", "endAt": "2022-01-11T11:00:00Z"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	search := func(target string) SearchResponse {
+		rec := doRequest(t, handler, http.MethodGet, target, "alice", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var res SearchResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+		return res
+	}
+
+	t.Run(
+		"when text matches, returns the page and the total", func(t *testing.T) {
+			res := search("/search?q=review&sort=-start&limit=1")
+			require.Equal(t, 2, res.Total)
+			require.Len(t, res.Events, 1)
+			require.Equal(t, "Review retro", res.Events[0].Title)
+		},
+	)
+
+	t.Run(
+		"when filters are set, applies them", func(t *testing.T) {
+			res := search("/search?hasReminders=true")
+			require.Equal(t, 1, res.Total)
+			require.Equal(t, "Design review", res.Events[0].Title)
+
+			res = search("/search?from=2022-01-11T00:00:00Z")
+			require.Equal(t, 1, res.Total)
+			require.Equal(t, "Review retro", res.Events[0].Title)
+		},
+	)
+
+	t.Run(
+		"when parameter is malformed, returns validation error", func(t *testing.T) {
+			for _, target := range []string{
+				"/search?from=2022-01-10",
+				"/search?hasReminders=maybe",
+				"/search?limit=ten",
+				"/search?sort=title",
+			} {
+				rec := doRequest(t, handler, http.MethodGet, target, "alice", "")
+				require.Equal(t, http.StatusBadRequest, rec.Code, target)
+				require.Equal(t, "validation_error", decodeError(t, rec).Code, target)
+			}
+		},
+	)
+}
@@ -6,6 +6,9 @@ import (
 	"net"
 	"net/http"
 	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/app"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
 )
 
 type Server struct {
@@ -23,7 +26,51 @@ type Logger interface {
 	Error(msg string)
 }
 
-type Application interface { // TODO
+type Application interface {
+	CreateEvent(
+		ctx context.Context,
+		title, description, userID, calendarID string,
+		startAt, endAt time.Time,
+		reminders []time.Duration,
+		rrule, timezone string,
+		exDates []time.Time,
+		attendees []string,
+	) (storage.EventID, error)
+	UpdateEvent(
+		ctx context.Context,
+		eventID string,
+		version int64,
+		title, description, userID string,
+		startAt, endAt time.Time,
+		reminders []time.Duration,
+		rrule, timezone string,
+		exDates []time.Time,
+		attendees []string,
+	) (int64, error)
+	DeleteEvent(ctx context.Context, userID, id string, version int64) error
+	RespondToInvitation(ctx context.Context, eventID, userID, status string) (int64, error)
+	GetEvent(ctx context.Context, userID, id string) (*storage.Event, error)
+	GetEventHistory(ctx context.Context, userID, eventID string) ([]storage.Change, error)
+	RestoreEvent(ctx context.Context, userID, eventID string, changeID int64) (int64, error)
+	ListDay(ctx context.Context, ownerID string, date time.Time, timezone string) ([]storage.Event, error)
+	ListWeek(ctx context.Context, ownerID string, date time.Time, timezone string) ([]storage.Event, error)
+	ListMonth(ctx context.Context, ownerID string, date time.Time, timezone string) ([]storage.Event, error)
+	GetUserEvents(ctx context.Context, ownerID string) ([]storage.Event, error)
+	ListEventsPage(
+		ctx context.Context,
+		userID string,
+		from, to time.Time,
+		token string,
+		pageSize int,
+	) ([]storage.Event, string, error)
+	FreeBusy(ctx context.Context, userIDs []string, from, to time.Time) (map[string][]app.Interval, error)
+	FindSlots(ctx context.Context, query app.SlotQuery) ([]app.Interval, error)
+	CreateCalendar(ctx context.Context, userID, name string) (storage.CalendarID, error)
+	GetCalendars(ctx context.Context, userID string) ([]storage.Calendar, error)
+	ShareCalendar(ctx context.Context, userID, calendarID, granteeID, access string) error
+	DeleteCalendar(ctx context.Context, userID, calendarID string) error
+	ListCalendarEvents(ctx context.Context, userID, calendarID string, from, to time.Time) ([]storage.Event, error)
+	SearchEvents(ctx context.Context, userID string, query app.SearchQuery) ([]storage.Event, int, error)
 }
 
 func NewServer(logger Logger, app Application, host, port string) *Server {
@@ -36,12 +83,9 @@ func NewServer(logger Logger, app Application, host, port string) *Server {
 }
 
 func (s *Server) Start(ctx context.Context) error {
-	mux := http.NewServeMux()
-	mux.Handle("/health", loggingMiddleware(HealthCheckHandler{}, s.logger))
-
 	s.server = &http.Server{
 		Addr:         net.JoinHostPort(s.host, s.port),
-		Handler:      mux,
+		Handler:      s.handler(),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
@@ -54,6 +98,23 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
+func (s *Server) handler() http.Handler {
+	events := &EventsHandler{app: s.app}
+
+	mux := http.NewServeMux()
+	mux.Handle("/health", loggingMiddleware(HealthCheckHandler{}, s.logger))
+	mux.Handle("/events", loggingMiddleware(events, s.logger))
+	mux.Handle("/events/", loggingMiddleware(events, s.logger))
+	mux.Handle("/freebusy", loggingMiddleware(&FreeBusyHandler{app: s.app}, s.logger))
+	mux.Handle("/slots", loggingMiddleware(&SlotsHandler{app: s.app}, s.logger))
+	mux.Handle("/search", loggingMiddleware(&SearchHandler{app: s.app}, s.logger))
+	mux.Handle("/calendars", loggingMiddleware(&CalendarsHandler{app: s.app}, s.logger))
+	mux.Handle("/calendars/", loggingMiddleware(&CalendarsHandler{app: s.app}, s.logger))
+	mux.Handle("/users/", loggingMiddleware(&CalendarHandler{app: s.app}, s.logger))
+
+	return mux
+}
+
 func (s *Server) Stop(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
 }
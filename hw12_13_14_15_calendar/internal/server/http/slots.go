@@ -0,0 +1,93 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/app"
+)
+
+// SlotsHandler serves POST /slots, it finds the time where all participants are free.
+type SlotsHandler struct {
+	app Application
+}
+
+type FindSlotsRequest struct {
+	Participants []ParticipantRequest `json:"participants"`
+	Duration     Duration             `json:"duration"`
+	From         time.Time            `json:"from"`
+	To           time.Time            `json:"to"`
+	// WorkingHours are applied in the timezone of every participant, the whole day by default.
+	WorkingHours WorkingHoursRequest `json:"workingHours"`
+	// Gap is the minimum time between the slot and other meetings.
+	Gap   Duration `json:"gap"`
+	Limit int      `json:"limit"`
+}
+
+type ParticipantRequest struct {
+	UserID string `json:"userId"`
+	// Timezone is an IANA name like "Europe/Berlin", UTC by default.
+	Timezone string `json:"timezone"`
+}
+
+// WorkingHoursRequest is a daily wall clock period like {"start": "09:00", "end": "18:00"}.
+type WorkingHoursRequest struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type FindSlotsResponse struct {
+	Slots []IntervalResponse `json:"slots"`
+}
+
+func (h *SlotsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(UserIDHeader) == "" {
+		writeError(w, http.StatusUnauthorized, "user_id_required", "header "+UserIDHeader+" is required")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method "+r.Method+" is not allowed")
+		return
+	}
+
+	var req FindSlotsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "invalid request body: "+err.Error())
+		return
+	}
+
+	hours, err := app.ParseWorkingHours(req.WorkingHours.Start, req.WorkingHours.End)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	query := app.SlotQuery{
+		Duration:     time.Duration(req.Duration),
+		From:         req.From,
+		To:           req.To,
+		WorkingHours: hours,
+		Gap:          time.Duration(req.Gap),
+		Limit:        req.Limit,
+	}
+	for _, participant := range req.Participants {
+		query.Participants = append(query.Participants, app.Participant{
+			UserID:   participant.UserID,
+			Timezone: participant.Timezone,
+		})
+	}
+
+	slots, err := h.app.FindSlots(r.Context(), query)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	res := FindSlotsResponse{Slots: make([]IntervalResponse, 0, len(slots))}
+	for _, slot := range slots {
+		res.Slots = append(res.Slots, IntervalResponse{StartAt: slot.StartAt, EndAt: slot.EndAt})
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,64 @@
+package internalhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlotsHandler(t *testing.T) {
+	handler := newTestHandler()
+
+	rec := doRequest(t, handler, http.MethodPost, "/events", "alice",
+		`{"title": "standup", "startAt": "2022-01-10T09:00:00Z", "endAt": "2022-01-10T10:00:00Z"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	t.Run(
+		"when participants are free, returns slots", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/slots", "alice", `{
+				"participants": [{"userId": "alice"}, {"userId": "bob", "timezone": "Europe/Berlin"}],
+				"duration": "1h",
+				"from": "2022-01-10T00:00:00Z",
+				"to": "2022-01-11T00:00:00Z",
+				"workingHours": {"start": "09:00", "end": "18:00"},
+				"gap": "30m",
+				"limit": 2
+			}`)
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var res FindSlotsResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+			at := func(hour, minute int) time.Time {
+				return time.Date(2022, time.January, 10, hour, minute, 0, 0, time.UTC)
+			}
+			require.Equal(t, []IntervalResponse{
+				{StartAt: at(10, 30), EndAt: at(11, 30)},
+				{StartAt: at(11, 30), EndAt: at(12, 30)},
+			}, res.Slots)
+		},
+	)
+
+	t.Run(
+		"when working hours are malformed, returns validation error", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodPost, "/slots", "alice", `{
+				"participants": [{"userId": "alice"}],
+				"duration": "1h",
+				"from": "2022-01-10T00:00:00Z",
+				"to": "2022-01-11T00:00:00Z",
+				"workingHours": {"start": "9am", "end": "18:00"}
+			}`)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, "validation_error", decodeError(t, rec).Code)
+		},
+	)
+
+	t.Run(
+		"when method is not post, returns method not allowed", func(t *testing.T) {
+			rec := doRequest(t, handler, http.MethodGet, "/slots", "alice", "")
+			require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		},
+	)
+}
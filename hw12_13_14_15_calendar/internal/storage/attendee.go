@@ -0,0 +1,83 @@
+package storage
+
+import "fmt"
+
+// AttendeeStatus is the participation status of an invited user, RFC 5545 PARTSTAT values.
+type AttendeeStatus string
+
+const (
+	StatusNeedsAction AttendeeStatus = "needs-action"
+	StatusAccepted    AttendeeStatus = "accepted"
+	StatusDeclined    AttendeeStatus = "declined"
+	StatusTentative   AttendeeStatus = "tentative"
+)
+
+// ParseAttendeeStatus returns the status by name.
+func ParseAttendeeStatus(s string) (AttendeeStatus, error) {
+	switch status := AttendeeStatus(s); status {
+	case StatusNeedsAction, StatusAccepted, StatusDeclined, StatusTentative:
+		return status, nil
+	default:
+		return "", fmt.Errorf("unknown attendee status %q", s)
+	}
+}
+
+// Attendee is a user invited to the event by the owner.
+type Attendee struct {
+	UserID UserID
+	Status AttendeeStatus
+}
+
+// Members returns the owner and all attendees, the event is listed in their calendars.
+func (e Event) Members() []UserID {
+	members := make([]UserID, 0, len(e.Attendees)+1)
+	members = append(members, e.OwnerID)
+	for _, attendee := range e.Attendees {
+		members = append(members, attendee.UserID)
+	}
+	return members
+}
+
+// Participants returns the owner and attendees who have not declined,
+// the event takes their time and they are reminded of it.
+func (e Event) Participants() []UserID {
+	participants := make([]UserID, 0, len(e.Attendees)+1)
+	participants = append(participants, e.OwnerID)
+	for _, attendee := range e.Attendees {
+		if attendee.Status != StatusDeclined {
+			participants = append(participants, attendee.UserID)
+		}
+	}
+	return participants
+}
+
+// IsParticipant reports whether the user is the owner or an attendee who has not declined.
+func (e Event) IsParticipant(userID UserID) bool {
+	for _, participant := range e.Participants() {
+		if participant == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Attendee returns the attendee by user id.
+func (e Event) Attendee(userID UserID) (Attendee, bool) {
+	for _, attendee := range e.Attendees {
+		if attendee.UserID == userID {
+			return attendee, true
+		}
+	}
+	return Attendee{}, false
+}
+
+// SharesParticipant reports whether the events have a common participant,
+// only such events can make a date busy.
+func (e Event) SharesParticipant(other Event) bool {
+	for _, participant := range e.Participants() {
+		if other.IsParticipant(participant) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package cachestorage
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+type entry struct {
+	key   string
+	value interface{}
+	// users are the users whose events are in the value.
+	users     []storage.UserID
+	expiresAt time.Time
+}
+
+// lru keeps up to capacity entries evicting the least recently used one, it is not safe for concurrent use.
+type lru struct {
+	capacity int
+	queue    *list.List
+	items    map[string]*list.Element
+	byUser   map[storage.UserID]map[string]struct{}
+}
+
+func newLRU(capacity int) *lru {
+	return &lru{
+		capacity: capacity,
+		queue:    list.New(),
+		items:    make(map[string]*list.Element, capacity),
+		byUser:   map[storage.UserID]map[string]struct{}{},
+	}
+}
+
+func (c *lru) get(key string, now time.Time) (interface{}, bool) {
+	item, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := item.Value.(*entry)
+	if !now.Before(e.expiresAt) {
+		c.remove(key)
+		return nil, false
+	}
+	c.queue.MoveToFront(item)
+	return e.value, true
+}
+
+func (c *lru) set(e *entry) {
+	c.remove(e.key)
+	if c.capacity <= 0 {
+		return
+	}
+	if c.queue.Len() >= c.capacity {
+		c.remove(c.queue.Back().Value.(*entry).key)
+	}
+
+	c.items[e.key] = c.queue.PushFront(e)
+	for _, user := range e.users {
+		keys, ok := c.byUser[user]
+		if !ok {
+			keys = map[string]struct{}{}
+			c.byUser[user] = keys
+		}
+		keys[e.key] = struct{}{}
+	}
+}
+
+func (c *lru) remove(key string) {
+	item, ok := c.items[key]
+	if !ok {
+		return
+	}
+	c.queue.Remove(item)
+	delete(c.items, key)
+	for _, user := range item.Value.(*entry).users {
+		delete(c.byUser[user], key)
+		if len(c.byUser[user]) == 0 {
+			delete(c.byUser, user)
+		}
+	}
+}
+
+// removeUser removes the entries having the events of the user.
+func (c *lru) removeUser(user storage.UserID) {
+	for key := range c.byUser[user] {
+		c.remove(key)
+	}
+}
+
+func (c *lru) clear() {
+	c.queue.Init()
+	c.items = make(map[string]*list.Element, c.capacity)
+	c.byUser = map[storage.UserID]map[string]struct{}{}
+}
@@ -0,0 +1,232 @@
+package cachestorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/app"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+type Stats struct {
+	Hits   int64
+	Misses int64
+}
+
+// Storage caches the events found by id and the events of the users found by period. The writes made
+// through the storage invalidate the entries of the event and of its members, other writes are seen
+// when the entries expire after ttl. FindCurrentByID is not cached, so the writes are based on the stored
+// state even if the event was changed by another process.
+type Storage struct {
+	// the methods which are not overridden are not cached.
+	app.Storage
+
+	ttl time.Duration
+	now func() time.Time
+
+	mu    *sync.Mutex
+	cache *lru
+	// gen is incremented on every invalidation, a value read before it must not be cached.
+	gen uint64
+
+	hits   int64
+	misses int64
+}
+
+func New(storage app.Storage, ttl time.Duration, capacity int) *Storage {
+	return &Storage{
+		Storage: storage,
+		ttl:     ttl,
+		now:     time.Now,
+		mu:      &sync.Mutex{},
+		cache:   newLRU(capacity),
+	}
+}
+
+func (s *Storage) Stats() Stats {
+	return Stats{
+		Hits:   atomic.LoadInt64(&s.hits),
+		Misses: atomic.LoadInt64(&s.misses),
+	}
+}
+
+func (s *Storage) FindByID(ctx context.Context, eventID storage.EventID) (*storage.Event, error) {
+	key := "event/" + eventID.String()
+	value, gen, ok := s.get(key)
+	if ok {
+		return cloneEvent(value.(*storage.Event)), nil
+	}
+
+	event, err := s.Storage.FindByID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	// the entry is invalidated by the id, the writes of the members don't change it
+	s.set(gen, key, cloneEvent(event), nil)
+	return event, nil
+}
+
+func (s *Storage) FindAllByUserIDAndPeriod(
+	ctx context.Context,
+	ownerID storage.UserID,
+	from, to time.Time,
+) ([]storage.Event, error) {
+	key := fmt.Sprintf("period/%s/%s/%s", ownerID, from.Format(time.RFC3339Nano), to.Format(time.RFC3339Nano))
+	value, gen, ok := s.get(key)
+	if ok {
+		return cloneEvents(value.([]storage.Event)), nil
+	}
+
+	events, err := s.Storage.FindAllByUserIDAndPeriod(ctx, ownerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	s.set(gen, key, cloneEvents(events), []storage.UserID{ownerID})
+	return events, nil
+}
+
+func (s *Storage) Save(ctx context.Context, event *storage.Event) error {
+	return s.save(ctx, event, func() error {
+		return s.Storage.Save(ctx, event)
+	})
+}
+
+func (s *Storage) SaveWithChange(ctx context.Context, event *storage.Event, change *storage.Change) error {
+	return s.save(ctx, event, func() error {
+		return s.Storage.SaveWithChange(ctx, event, change)
+	})
+}
+
+// save invalidates the event and the users it is cached for before and after the save.
+func (s *Storage) save(ctx context.Context, event *storage.Event, fn func() error) error {
+	members := event.Members()
+	if event.Version != 0 {
+		old, err := s.members(ctx, event.ID)
+		if err != nil {
+			return err
+		}
+		members = append(members, old...)
+	}
+
+	err := fn()
+	// a failed save may be caused by a stale cached version, so the event is invalidated anyway
+	s.invalidate(event.ID, members)
+	return err
+}
+
+func (s *Storage) Delete(ctx context.Context, event *storage.Event) error {
+	return s.delete(ctx, event, func() error {
+		return s.Storage.Delete(ctx, event)
+	})
+}
+
+func (s *Storage) DeleteWithChange(ctx context.Context, event *storage.Event, change *storage.Change) error {
+	return s.delete(ctx, event, func() error {
+		return s.Storage.DeleteWithChange(ctx, event, change)
+	})
+}
+
+func (s *Storage) delete(ctx context.Context, event *storage.Event, fn func() error) error {
+	members, err := s.members(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	s.invalidate(event.ID, append(members, event.Members()...))
+	return err
+}
+
+func (s *Storage) DeleteAllEndedBefore(ctx context.Context, before time.Time) (int, error) {
+	count, err := s.Storage.DeleteAllEndedBefore(ctx, before)
+	s.purge()
+	return count, err
+}
+
+func (s *Storage) DeleteCalendar(ctx context.Context, calendarID storage.CalendarID) error {
+	err := s.Storage.DeleteCalendar(ctx, calendarID)
+	s.purge()
+	return err
+}
+
+func (s *Storage) DeleteCalendarWithChanges(
+	ctx context.Context,
+	calendarID storage.CalendarID,
+	changes []*storage.Change,
+) error {
+	err := s.Storage.DeleteCalendarWithChanges(ctx, calendarID, changes)
+	s.purge()
+	return err
+}
+
+// members returns the members of the stored event, nil if there is no event.
+func (s *Storage) members(ctx context.Context, eventID storage.EventID) ([]storage.UserID, error) {
+	event, err := s.Storage.FindByID(ctx, eventID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return event.Members(), nil
+}
+
+// get returns the cached value or the generation to cache the value read from the storage.
+func (s *Storage) get(key string) (interface{}, uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.cache.get(key, s.now())
+	if ok {
+		atomic.AddInt64(&s.hits, 1)
+	} else {
+		atomic.AddInt64(&s.misses, 1)
+	}
+	return value, s.gen, ok
+}
+
+// set caches the value unless there was an invalidation since the value was read.
+func (s *Storage) set(gen uint64, key string, value interface{}, users []storage.UserID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if gen != s.gen {
+		return
+	}
+	s.cache.set(&entry{key: key, value: value, users: users, expiresAt: s.now().Add(s.ttl)})
+}
+
+func (s *Storage) invalidate(eventID storage.EventID, members []storage.UserID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gen++
+	s.cache.remove("event/" + eventID.String())
+	for _, member := range members {
+		s.cache.removeUser(member)
+	}
+}
+
+func (s *Storage) purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gen++
+	s.cache.clear()
+}
+
+func cloneEvent(event *storage.Event) *storage.Event {
+	clone := *event
+	clone.Attendees = append([]storage.Attendee(nil), event.Attendees...)
+	clone.Reminders = append([]storage.Reminder(nil), event.Reminders...)
+	clone.ExDates = append([]time.Time(nil), event.ExDates...)
+	return &clone
+}
+
+func cloneEvents(events []storage.Event) []storage.Event {
+	clones := make([]storage.Event, 0, len(events))
+	for i := range events {
+		clones = append(clones, *cloneEvent(&events[i]))
+	}
+	return clones
+}
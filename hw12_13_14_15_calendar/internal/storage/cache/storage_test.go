@@ -0,0 +1,203 @@
+package cachestorage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	week := day.AddDate(0, 0, 7)
+
+	newStorage := func(capacity int) (*Storage, *storage.Event) {
+		s := New(memorystorage.New(), time.Minute, capacity)
+		event := &storage.Event{
+			ID:        "review",
+			Title:     "review",
+			OwnerID:   "alice",
+			StartAt:   day.Add(10 * time.Hour),
+			EndAt:     day.Add(11 * time.Hour),
+			Attendees: []storage.Attendee{{UserID: "bob"}},
+		}
+		require.NoError(t, s.Save(ctx, event))
+		return s, event
+	}
+	findWeek := func(s *Storage, userID storage.UserID) []storage.Event {
+		events, err := s.FindAllByUserIDAndPeriod(ctx, userID, day, week)
+		require.NoError(t, err)
+		return events
+	}
+
+	t.Run(
+		"when event is read twice, reads the storage once", func(t *testing.T) {
+			s, event := newStorage(10)
+			found, err := s.FindByID(ctx, event.ID)
+			require.NoError(t, err)
+			found.Title = "changed"
+			found.Attendees[0].UserID = "carol"
+
+			found, err = s.FindByID(ctx, event.ID)
+			require.NoError(t, err)
+			require.Equal(t, "review", found.Title, "cached event can't be changed by the caller")
+			require.Equal(t, storage.UserID("bob"), found.Attendees[0].UserID)
+			require.Equal(t, Stats{Hits: 1, Misses: 1}, s.Stats())
+
+			_, err = s.FindByID(ctx, "unknown")
+			require.ErrorIs(t, err, storage.ErrNotFound)
+		},
+	)
+
+	t.Run(
+		"when event is saved, invalidates its entries and the entries of its members", func(t *testing.T) {
+			s, event := newStorage(10)
+			_, err := s.FindByID(ctx, event.ID)
+			require.NoError(t, err)
+			require.Len(t, findWeek(s, "alice"), 1)
+			require.Len(t, findWeek(s, "bob"), 1)
+			require.Empty(t, findWeek(s, "carol"))
+
+			event.Title = "design review"
+			event.Attendees = []storage.Attendee{{UserID: "carol"}}
+			require.NoError(t, s.Save(ctx, event))
+
+			found, err := s.FindByID(ctx, event.ID)
+			require.NoError(t, err)
+			require.Equal(t, "design review", found.Title)
+			require.Equal(t, "design review", findWeek(s, "alice")[0].Title)
+			require.Empty(t, findWeek(s, "bob"), "removed attendee is invalidated")
+			require.Len(t, findWeek(s, "carol"), 1)
+			require.Equal(t, Stats{Misses: 8}, s.Stats())
+
+			require.Len(t, findWeek(s, "carol"), 1)
+			require.Equal(t, int64(1), s.Stats().Hits)
+		},
+	)
+
+	t.Run(
+		"when event is deleted, invalidates its entries", func(t *testing.T) {
+			s, event := newStorage(10)
+			require.Len(t, findWeek(s, "bob"), 1)
+			_, err := s.FindByID(ctx, event.ID)
+			require.NoError(t, err)
+
+			require.NoError(t, s.Delete(ctx, event))
+			require.Empty(t, findWeek(s, "bob"))
+			_, err = s.FindByID(ctx, event.ID)
+			require.ErrorIs(t, err, storage.ErrNotFound)
+
+			require.NoError(t, s.Save(ctx, &storage.Event{ID: "old", OwnerID: "bob", StartAt: day, EndAt: day.Add(time.Hour)}))
+			require.Len(t, findWeek(s, "bob"), 1)
+			deleted, err := s.DeleteAllEndedBefore(ctx, week)
+			require.NoError(t, err)
+			require.Equal(t, 1, deleted)
+			require.Empty(t, findWeek(s, "bob"))
+		},
+	)
+
+	t.Run(
+		"when entry expires or is evicted, reads the storage again", func(t *testing.T) {
+			s, event := newStorage(1)
+			now := day
+			s.now = func() time.Time {
+				return now
+			}
+
+			_, err := s.FindByID(ctx, event.ID)
+			require.NoError(t, err)
+			now = now.Add(time.Minute)
+			_, err = s.FindByID(ctx, event.ID)
+			require.NoError(t, err)
+			require.Equal(t, Stats{Misses: 2}, s.Stats())
+
+			findWeek(s, "alice")
+			_, err = s.FindByID(ctx, event.ID)
+			require.NoError(t, err)
+			require.Equal(t, Stats{Misses: 4}, s.Stats())
+		},
+	)
+
+	t.Run(
+		"when value is read before invalidation, does not cache it", func(t *testing.T) {
+			s, event := newStorage(10)
+			_, gen, ok := s.get("event/review")
+			require.False(t, ok)
+			s.invalidate(event.ID, nil)
+			s.set(gen, "event/review", event, nil)
+
+			_, _, ok = s.get("event/review")
+			require.False(t, ok)
+		},
+	)
+
+	t.Run(
+		"when event is changed by another process, reads the stored one for the write", func(t *testing.T) {
+			inner := memorystorage.New()
+			s, other := New(inner, time.Minute, 10), New(inner, time.Minute, 10)
+			event := &storage.Event{ID: "review", Title: "review", OwnerID: "alice"}
+			require.NoError(t, s.Save(ctx, event))
+			_, err := s.FindByID(ctx, event.ID)
+			require.NoError(t, err)
+
+			changed, err := other.FindByID(ctx, event.ID)
+			require.NoError(t, err)
+			changed.Title = "changed"
+			require.NoError(t, other.Save(ctx, changed))
+
+			cached, err := s.FindByID(ctx, event.ID)
+			require.NoError(t, err)
+			require.Equal(t, int64(1), cached.Version, "other writes are seen after ttl")
+
+			current, err := s.FindCurrentByID(ctx, event.ID)
+			require.NoError(t, err)
+			require.Equal(t, int64(2), current.Version)
+			require.Equal(t, "changed", current.Title)
+
+			current.Title = "changed again"
+			require.NoError(t, s.Save(ctx, current))
+		},
+	)
+
+	t.Run(
+		"when used concurrently, reads the latest saved version", func(t *testing.T) {
+			s, event := newStorage(10)
+			wg := &sync.WaitGroup{}
+			for i := 0; i < 10; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 20; j++ {
+						_, _ = s.FindByID(ctx, event.ID)
+						_, _ = s.FindAllByUserIDAndPeriod(ctx, "bob", day, week)
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					for {
+						found, err := s.FindByID(ctx, event.ID)
+						if err != nil {
+							t.Error(err)
+							return
+						}
+						found.Title = "changed"
+						if s.Save(ctx, found) == nil {
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			found, err := s.FindByID(ctx, event.ID)
+			require.NoError(t, err)
+			require.Equal(t, int64(11), found.Version)
+			require.Equal(t, int64(11), findWeek(s, "bob")[0].Version)
+		},
+	)
+}
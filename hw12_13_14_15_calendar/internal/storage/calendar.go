@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+type CalendarID string
+
+func (id CalendarID) String() string {
+	return string(id)
+}
+
+// Access is the level of access to a calendar granted to another user, each level includes the lower ones.
+type Access string
+
+const (
+	AccessNone     Access = ""
+	AccessFreeBusy Access = "free-busy"
+	AccessRead     Access = "read"
+	AccessWrite    Access = "write"
+)
+
+var accessLevels = map[Access]int{AccessNone: 0, AccessFreeBusy: 1, AccessRead: 2, AccessWrite: 3}
+
+// ParseAccess returns the access by name, "none" and empty name mean no access.
+func ParseAccess(s string) (Access, error) {
+	if s == "none" {
+		return AccessNone, nil
+	}
+	access := Access(s)
+	if _, ok := accessLevels[access]; !ok {
+		return AccessNone, fmt.Errorf("unknown access %q", s)
+	}
+	return access, nil
+}
+
+// Allows reports whether the access includes the required one.
+func (a Access) Allows(required Access) bool {
+	return accessLevels[a] >= accessLevels[required]
+}
+
+// Grant gives the user access to a calendar of another user.
+type Grant struct {
+	UserID UserID
+	Access Access
+}
+
+// Calendar groups the events of the owner, like work or personal ones, and is shared by grants.
+type Calendar struct {
+	ID      CalendarID
+	OwnerID UserID
+	Name    string
+	// Grants are given to other users, the owner has full access.
+	Grants []Grant
+	// CreatedAt is set by the storage on the first Save.
+	CreatedAt time.Time
+}
+
+// AccessOf returns the access of the user to the calendar.
+func (c Calendar) AccessOf(userID UserID) Access {
+	if userID == c.OwnerID {
+		return AccessWrite
+	}
+	for _, grant := range c.Grants {
+		if grant.UserID == userID {
+			return grant.Access
+		}
+	}
+	return AccessNone
+}
@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the storage implementations, callers match them with errors.Is.
+var (
+	// ErrNotFound is returned when the requested record does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when the record contradicts the stored ones.
+	ErrConflict = errors.New("conflict")
+	// ErrVersionMismatch is returned when the record was changed since it was read.
+	ErrVersionMismatch = errors.New("version mismatch")
+	// ErrUnavailable is returned when the storage can't be reached, the operation may be retried.
+	ErrUnavailable = errors.New("storage unavailable")
+)
+
+// ErrDateBusy is returned by Save when the event overlaps another event of the owner.
+var ErrDateBusy = fmt.Errorf("date is busy: %w", ErrConflict)
@@ -1,6 +1,9 @@
 package storage
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type EventID string
 
@@ -17,5 +20,40 @@ type Event struct {
 	EndAt       time.Time
 	Description string
 	OwnerID     UserID
-	NotifyAt    time.Time
+	// CalendarID is the calendar of the owner the event belongs to, empty for the default calendar.
+	CalendarID CalendarID
+	// Attendees are users invited by the owner, the owner is not an attendee.
+	Attendees []Attendee
+	// Reminders are scheduled notifications, one per offset before the start.
+	Reminders []Reminder
+	// RRule is an RFC 5545 recurrence rule, empty for a single event.
+	RRule string
+	// Timezone is the IANA timezone the series is expanded in like TZID of DTSTART in RFC 5545,
+	// so the occurrences keep their wall clock time across DST changes. Empty means the location of StartAt.
+	Timezone string
+	// ExDates are the starts of occurrences excluded from the series.
+	ExDates []time.Time
+	// RecurrenceEndAt is the end of the last occurrence, zero for an endless series.
+	RecurrenceEndAt time.Time
+	// Version is incremented by the storage on every Save, zero for a new event.
+	Version int64
+	// CreatedAt and UpdatedAt are set by the storage on Save.
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// CheckVersion returns ErrVersionMismatch if the stored event has another version.
+func CheckVersion(stored Event, version int64) error {
+	if stored.Version != version {
+		return fmt.Errorf("%w: event %s has version %d, not %d", ErrVersionMismatch, stored.ID, stored.Version, version)
+	}
+	return nil
+}
+
+// EndedBefore reports whether the event, or every occurrence of the series, ended before the date.
+func (e Event) EndedBefore(date time.Time) bool {
+	if e.RRule == "" {
+		return e.EndAt.Before(date)
+	}
+	return !e.RecurrenceEndAt.IsZero() && e.RecurrenceEndAt.Before(date)
 }
@@ -0,0 +1,13 @@
+//go:build windows
+// +build windows
+
+package filestorage
+
+import (
+	"errors"
+	"os"
+)
+
+func lockDir(path string) (*os.File, error) {
+	return nil, errors.New("file storage is not supported on windows")
+}
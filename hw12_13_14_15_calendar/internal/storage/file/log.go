@@ -0,0 +1,163 @@
+package filestorage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage/memory"
+)
+
+type op string
+
+const (
+	opPutEvent          op = "putEvent"
+	opDeleteEvent       op = "deleteEvent"
+	opDeleteEndedBefore op = "deleteEndedBefore"
+	opPutCalendar       op = "putCalendar"
+	opDeleteCalendar    op = "deleteCalendar"
+	opPutChange         op = "putChange"
+	opDeleteOutbox      op = "deleteOutbox"
+)
+
+// record is a line of the log, it holds the resulting state of a write rather than its arguments,
+// so applying a record twice gives the same state as applying it once.
+type record struct {
+	Op         op                 `json:"op"`
+	Event      *storage.Event     `json:"event,omitempty"`
+	EventID    storage.EventID    `json:"eventId,omitempty"`
+	Before     *time.Time         `json:"before,omitempty"`
+	Calendar   *storage.Calendar  `json:"calendar,omitempty"`
+	CalendarID storage.CalendarID `json:"calendarId,omitempty"`
+	Change     *storage.Change    `json:"change,omitempty"`
+	// Changes are appended to the history together with the write of the events.
+	Changes []*storage.Change `json:"changes,omitempty"`
+	// Messages are put to the outbox together with the event of the completed reminder.
+	Messages []storage.OutboxMessage `json:"messages,omitempty"`
+	OutboxID int64                   `json:"outboxId,omitempty"`
+}
+
+// state is the snapshot being replayed.
+type state struct {
+	events       map[storage.EventID]storage.Event
+	calendars    map[storage.CalendarID]storage.Calendar
+	changes      map[int64]storage.Change
+	outbox       map[int64]storage.OutboxMessage
+	lastChangeID int64
+	lastOutboxID int64
+}
+
+func newState(snapshot memorystorage.Snapshot) *state {
+	st := &state{
+		events:       make(map[storage.EventID]storage.Event, len(snapshot.Events)),
+		calendars:    make(map[storage.CalendarID]storage.Calendar, len(snapshot.Calendars)),
+		changes:      make(map[int64]storage.Change, len(snapshot.Changes)),
+		outbox:       make(map[int64]storage.OutboxMessage, len(snapshot.Outbox)),
+		lastChangeID: snapshot.LastChangeID,
+		lastOutboxID: snapshot.LastOutboxID,
+	}
+	for _, event := range snapshot.Events {
+		st.events[event.ID] = event
+	}
+	for _, calendar := range snapshot.Calendars {
+		st.calendars[calendar.ID] = calendar
+	}
+	for _, change := range snapshot.Changes {
+		st.changes[change.ID] = change
+	}
+	for _, msg := range snapshot.Outbox {
+		st.outbox[msg.ID] = msg
+	}
+	return st
+}
+
+func (st *state) apply(r record) error {
+	switch r.Op {
+	case opPutEvent:
+		if r.Event == nil {
+			return fmt.Errorf("%s: no event", r.Op)
+		}
+		st.events[r.Event.ID] = *r.Event
+		for _, msg := range r.Messages {
+			st.outbox[msg.ID] = msg
+			if msg.ID > st.lastOutboxID {
+				st.lastOutboxID = msg.ID
+			}
+		}
+	case opDeleteEvent:
+		delete(st.events, r.EventID)
+	case opDeleteEndedBefore:
+		if r.Before == nil {
+			return fmt.Errorf("%s: no time", r.Op)
+		}
+		for id, event := range st.events {
+			if event.EndedBefore(*r.Before) {
+				delete(st.events, id)
+			}
+		}
+	case opPutCalendar:
+		if r.Calendar == nil {
+			return fmt.Errorf("%s: no calendar", r.Op)
+		}
+		st.calendars[r.Calendar.ID] = *r.Calendar
+	case opDeleteCalendar:
+		for id, event := range st.events {
+			if event.CalendarID == r.CalendarID {
+				delete(st.events, id)
+			}
+		}
+		delete(st.calendars, r.CalendarID)
+	case opPutChange:
+		if r.Change == nil {
+			return fmt.Errorf("%s: no change", r.Op)
+		}
+		st.putChange(*r.Change)
+	case opDeleteOutbox:
+		delete(st.outbox, r.OutboxID)
+	default:
+		return fmt.Errorf("unknown operation %q", r.Op)
+	}
+
+	for _, change := range r.Changes {
+		st.putChange(*change)
+	}
+	return nil
+}
+
+func (st *state) putChange(change storage.Change) {
+	st.changes[change.ID] = change
+	if change.ID > st.lastChangeID {
+		st.lastChangeID = change.ID
+	}
+}
+
+func (st *state) snapshot() memorystorage.Snapshot {
+	snapshot := memorystorage.Snapshot{
+		Events:       make([]storage.Event, 0, len(st.events)),
+		Calendars:    make([]storage.Calendar, 0, len(st.calendars)),
+		Changes:      make([]storage.Change, 0, len(st.changes)),
+		Outbox:       make([]storage.OutboxMessage, 0, len(st.outbox)),
+		LastChangeID: st.lastChangeID,
+		LastOutboxID: st.lastOutboxID,
+	}
+	for _, event := range st.events {
+		snapshot.Events = append(snapshot.Events, event)
+	}
+	for _, calendar := range st.calendars {
+		snapshot.Calendars = append(snapshot.Calendars, calendar)
+	}
+	for _, change := range st.changes {
+		snapshot.Changes = append(snapshot.Changes, change)
+	}
+	sort.Slice(snapshot.Changes, func(i, j int) bool {
+		return snapshot.Changes[i].ID < snapshot.Changes[j].ID
+	})
+	for _, msg := range st.outbox {
+		snapshot.Outbox = append(snapshot.Outbox, msg)
+	}
+	sort.Slice(snapshot.Outbox, func(i, j int) bool {
+		return snapshot.Outbox[i].ID < snapshot.Outbox[j].ID
+	})
+	return snapshot
+}
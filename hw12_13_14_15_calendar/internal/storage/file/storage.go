@@ -0,0 +1,437 @@
+package filestorage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage/memory"
+)
+
+const (
+	snapshotFile = "snapshot.json"
+	logFile      = "log.jsonl"
+	lockFile     = "lock"
+)
+
+var (
+	ErrBroken = errors.New("storage is broken")
+	ErrLocked = errors.New("storage is locked")
+)
+
+// Storage keeps the state in memory and persists every write to an append-only log in the directory.
+// After snapshotEvery records the state is written to a snapshot and the log is truncated. On connect
+// the snapshot is loaded and the log is replayed. The directory is locked by the process connected to it,
+// another process fails to connect with ErrLocked.
+type Storage struct {
+	// reads go straight to the memory storage, writes are overridden to be logged.
+	*memorystorage.Storage
+
+	dir           string
+	snapshotEvery int
+
+	// mu serializes the writes, so the log has them in the order they are applied.
+	mu      *sync.Mutex
+	lock    *os.File
+	log     *os.File
+	records int
+	// broken is the failure to log a write which is already applied in memory.
+	broken error
+}
+
+func New(dir string, snapshotEvery int) *Storage {
+	return &Storage{
+		dir:           dir,
+		snapshotEvery: snapshotEvery,
+		mu:            &sync.Mutex{},
+	}
+}
+
+// Connect loads the state from the directory, a torn record at the end of the log is dropped.
+func (s *Storage) Connect(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log != nil {
+		return nil
+	}
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return err
+	}
+	lock, err := lockDir(filepath.Join(s.dir, lockFile))
+	if err != nil {
+		return err
+	}
+
+	if err := s.load(); err != nil {
+		lock.Close()
+		return err
+	}
+	s.lock = lock
+	return nil
+}
+
+func (s *Storage) load() error {
+	snapshot, err := s.readSnapshot()
+	if err != nil {
+		return err
+	}
+	st := newState(snapshot)
+
+	log, err := os.OpenFile(filepath.Join(s.dir, logFile), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	records, size, err := replay(log, st)
+	if err == nil {
+		// the torn record is overwritten by the next write
+		err = truncate(log, size)
+	}
+	if err != nil {
+		log.Close()
+		return fmt.Errorf("replay %s: %w", logFile, err)
+	}
+
+	s.Storage = memorystorage.Restore(st.snapshot())
+	s.log = log
+	s.records = records
+	return nil
+}
+
+// Close writes the snapshot and closes the log.
+func (s *Storage) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return nil
+	}
+	var err error
+	if s.broken == nil {
+		err = s.compact()
+	}
+	if closeErr := s.log.Close(); err == nil {
+		err = closeErr
+	}
+	// the lock is released after the snapshot is written
+	if closeErr := s.lock.Close(); err == nil {
+		err = closeErr
+	}
+	s.log, s.lock = nil, nil
+	return err
+}
+
+func (s *Storage) Save(ctx context.Context, event *storage.Event) error {
+	return s.write(func() (*record, error) {
+		if err := s.Storage.Save(ctx, event); err != nil {
+			return nil, err
+		}
+		return &record{Op: opPutEvent, Event: event}, nil
+	})
+}
+
+// SaveWithChange logs the event and the change as a single record, so they are restored together.
+func (s *Storage) SaveWithChange(ctx context.Context, event *storage.Event, change *storage.Change) error {
+	return s.write(func() (*record, error) {
+		if err := s.Storage.SaveWithChange(ctx, event, change); err != nil {
+			return nil, err
+		}
+		return &record{Op: opPutEvent, Event: event, Changes: []*storage.Change{change}}, nil
+	})
+}
+
+func (s *Storage) Delete(ctx context.Context, event *storage.Event) error {
+	return s.write(func() (*record, error) {
+		if err := s.Storage.Delete(ctx, event); err != nil {
+			return nil, err
+		}
+		return &record{Op: opDeleteEvent, EventID: event.ID}, nil
+	})
+}
+
+// DeleteWithChange logs the deletion and the change as a single record, so they are restored together.
+func (s *Storage) DeleteWithChange(ctx context.Context, event *storage.Event, change *storage.Change) error {
+	return s.write(func() (*record, error) {
+		if err := s.Storage.DeleteWithChange(ctx, event, change); err != nil {
+			return nil, err
+		}
+		return &record{Op: opDeleteEvent, EventID: event.ID, Changes: []*storage.Change{change}}, nil
+	})
+}
+
+func (s *Storage) DeleteAllEndedBefore(ctx context.Context, before time.Time) (int, error) {
+	var count int
+	err := s.write(func() (*record, error) {
+		var err error
+		if count, err = s.Storage.DeleteAllEndedBefore(ctx, before); err != nil || count == 0 {
+			return nil, err
+		}
+		return &record{Op: opDeleteEndedBefore, Before: &before}, nil
+	})
+	return count, err
+}
+
+func (s *Storage) SaveReminder(ctx context.Context, reminder storage.Reminder) error {
+	return s.write(func() (*record, error) {
+		if err := s.Storage.SaveReminder(ctx, reminder); err != nil {
+			return nil, err
+		}
+		return s.eventRecord(ctx, reminder.EventID)
+	})
+}
+
+// CompleteReminder logs the event and the outbox messages as a single record, so they are restored together.
+func (s *Storage) CompleteReminder(
+	ctx context.Context,
+	due, next storage.Reminder,
+	messages []storage.OutboxMessage,
+) error {
+	return s.write(func() (*record, error) {
+		if err := s.Storage.CompleteReminder(ctx, due, next, messages); err != nil {
+			return nil, err
+		}
+		r, err := s.eventRecord(ctx, due.EventID)
+		if err != nil || r == nil {
+			return nil, err
+		}
+
+		outbox, err := s.Storage.FindOutbox(ctx, math.MaxInt32)
+		if err != nil {
+			return nil, err
+		}
+		keys := make(map[string]struct{}, len(messages))
+		for _, msg := range messages {
+			keys[msg.Key] = struct{}{}
+		}
+		for _, msg := range outbox {
+			if _, ok := keys[msg.Key]; ok {
+				r.Messages = append(r.Messages, msg)
+			}
+		}
+		return r, nil
+	})
+}
+
+func (s *Storage) DeleteOutbox(ctx context.Context, id int64) error {
+	return s.write(func() (*record, error) {
+		if err := s.Storage.DeleteOutbox(ctx, id); err != nil {
+			return nil, err
+		}
+		return &record{Op: opDeleteOutbox, OutboxID: id}, nil
+	})
+}
+
+func (s *Storage) SaveCalendar(ctx context.Context, calendar *storage.Calendar) error {
+	return s.write(func() (*record, error) {
+		if err := s.Storage.SaveCalendar(ctx, calendar); err != nil {
+			return nil, err
+		}
+		return &record{Op: opPutCalendar, Calendar: calendar}, nil
+	})
+}
+
+func (s *Storage) DeleteCalendar(ctx context.Context, calendarID storage.CalendarID) error {
+	return s.write(func() (*record, error) {
+		if err := s.Storage.DeleteCalendar(ctx, calendarID); err != nil {
+			return nil, err
+		}
+		return &record{Op: opDeleteCalendar, CalendarID: calendarID}, nil
+	})
+}
+
+// DeleteCalendarWithChanges logs the deletion and the changes as a single record, so they are restored together.
+func (s *Storage) DeleteCalendarWithChanges(
+	ctx context.Context,
+	calendarID storage.CalendarID,
+	changes []*storage.Change,
+) error {
+	return s.write(func() (*record, error) {
+		if err := s.Storage.DeleteCalendarWithChanges(ctx, calendarID, changes); err != nil {
+			return nil, err
+		}
+		return &record{Op: opDeleteCalendar, CalendarID: calendarID, Changes: changes}, nil
+	})
+}
+
+func (s *Storage) SaveChange(ctx context.Context, change *storage.Change) error {
+	return s.write(func() (*record, error) {
+		if err := s.Storage.SaveChange(ctx, change); err != nil {
+			return nil, err
+		}
+		return &record{Op: opPutChange, Change: change}, nil
+	})
+}
+
+// eventRecord returns the record of the stored event, nil if there is no event.
+func (s *Storage) eventRecord(ctx context.Context, eventID storage.EventID) (*record, error) {
+	event, err := s.Storage.FindByID(ctx, eventID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record{Op: opPutEvent, Event: event}, nil
+}
+
+// write applies the write to the memory storage and appends the record it returns to the log,
+// nil record means nothing has changed.
+func (s *Storage) write(fn func() (*record, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return fmt.Errorf("%w: not connected", ErrBroken)
+	}
+	if s.broken != nil {
+		return s.broken
+	}
+
+	r, err := fn()
+	if err != nil || r == nil {
+		return err
+	}
+	if err := s.append(*r); err != nil {
+		// the memory is ahead of the log now, the writes are refused until the state is reloaded
+		s.broken = fmt.Errorf("%w: %s", ErrBroken, err)
+		return s.broken
+	}
+
+	if s.snapshotEvery > 0 && s.records >= s.snapshotEvery {
+		// a failed compaction is retried on the next write, the log still has all the records
+		_ = s.compact()
+	}
+	return nil
+}
+
+func (s *Storage) append(r record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := s.log.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := s.log.Sync(); err != nil {
+		return err
+	}
+	s.records++
+	return nil
+}
+
+// compact writes the state to the snapshot and truncates the log. The snapshot is replaced atomically,
+// if the log is not truncated after that, its records are replayed over the snapshot with the same result.
+func (s *Storage) compact() error {
+	data, err := json.Marshal(s.Storage.Snapshot())
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(s.dir, snapshotFile+".tmp")
+	if err := writeFile(tmp, data); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, snapshotFile)); err != nil {
+		return err
+	}
+	if err := syncDir(s.dir); err != nil {
+		return err
+	}
+
+	if err := truncate(s.log, 0); err != nil {
+		return err
+	}
+	s.records = 0
+	return nil
+}
+
+func (s *Storage) readSnapshot() (memorystorage.Snapshot, error) {
+	var snapshot memorystorage.Snapshot
+	data, err := os.ReadFile(filepath.Join(s.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshot, nil
+	}
+	if err != nil {
+		return snapshot, err
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("read %s: %w", snapshotFile, err)
+	}
+	return snapshot, nil
+}
+
+// replay applies the records of the log to the state, returns the number of the records and the size
+// of the log without a torn record at the end.
+func replay(log io.Reader, st *state) (int, int64, error) {
+	reader := bufio.NewReader(log)
+	records := 0
+	var size int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// a line without the newline is a record which was being written when the process stopped
+			return records, size, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+
+		var r record
+		if err := json.Unmarshal(bytes.TrimSpace(line), &r); err != nil {
+			return 0, 0, fmt.Errorf("record %d: %w", records+1, err)
+		}
+		if err := st.apply(r); err != nil {
+			return 0, 0, fmt.Errorf("record %d: %w", records+1, err)
+		}
+		records++
+		size += int64(len(line))
+	}
+}
+
+// truncate cuts the file to the size and moves the offset to its end.
+func truncate(f *os.File, size int64) error {
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+	_, err := f.Seek(size, io.SeekStart)
+	return err
+}
+
+func writeFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir makes the rename in the directory durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
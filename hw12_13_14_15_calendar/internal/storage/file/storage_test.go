@@ -0,0 +1,184 @@
+package filestorage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func connect(t *testing.T, dir string, snapshotEvery int) *Storage {
+	t.Helper()
+	s := New(dir, snapshotEvery)
+	require.NoError(t, s.Connect(context.Background()))
+	return s
+}
+
+// crash releases the directory without writing the snapshot, like a killed process.
+func crash(t *testing.T, s *Storage) {
+	t.Helper()
+	require.NoError(t, s.log.Close())
+	require.NoError(t, s.lock.Close())
+}
+
+// requireSameState compares the snapshots as json, the times lose their locations in the files.
+func requireSameState(t *testing.T, expected, actual *Storage) {
+	t.Helper()
+	expectedJSON, err := json.Marshal(expected.Snapshot())
+	require.NoError(t, err)
+	actualJSON, err := json.Marshal(actual.Snapshot())
+	require.NoError(t, err)
+	require.JSONEq(t, string(expectedJSON), string(actualJSON))
+}
+
+func fill(t *testing.T, s *Storage) {
+	t.Helper()
+	ctx := context.Background()
+	startAt := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+
+	work := &storage.Calendar{ID: "work", OwnerID: "alice", Name: "work"}
+	require.NoError(t, s.SaveCalendar(ctx, work))
+	for _, event := range []*storage.Event{
+		{ID: "old", OwnerID: "alice", StartAt: startAt.AddDate(-1, 0, 0), EndAt: startAt.AddDate(-1, 0, 0).Add(time.Hour)},
+		{ID: "work", OwnerID: "alice", CalendarID: work.ID, StartAt: startAt, EndAt: startAt.Add(time.Hour)},
+		{
+			ID:        "review",
+			Title:     "review",
+			OwnerID:   "alice",
+			StartAt:   startAt.Add(2 * time.Hour),
+			EndAt:     startAt.Add(3 * time.Hour),
+			Attendees: []storage.Attendee{{UserID: "bob"}},
+			Reminders: []storage.Reminder{{EventID: "review", Offset: time.Hour, NotifyAt: startAt.Add(time.Hour)}},
+		},
+	} {
+		require.NoError(t, s.SaveWithChange(ctx, event, &storage.Change{Kind: storage.ChangeCreate}))
+	}
+
+	review, err := s.FindByID(ctx, "review")
+	require.NoError(t, err)
+	review.Title = "design review"
+	require.NoError(t, s.Save(ctx, review))
+
+	due := review.Reminders[0]
+	sent := due
+	sent.Sent = true
+	require.NoError(t, s.CompleteReminder(ctx, due, sent, []storage.OutboxMessage{
+		{Key: "review/alice", Body: []byte("alice")},
+		{Key: "review/bob", Body: []byte("bob")},
+	}))
+	outbox, err := s.FindOutbox(ctx, 1)
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteOutbox(ctx, outbox[0].ID))
+
+	deleted, err := s.DeleteAllEndedBefore(ctx, startAt.AddDate(0, -1, 0))
+	require.NoError(t, err)
+	require.Equal(t, 1, deleted)
+	require.NoError(t, s.DeleteCalendarWithChanges(ctx, work.ID, []*storage.Change{
+		{EventID: "work", Kind: storage.ChangeDelete},
+	}))
+}
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run(
+		"when process stops without close, restores the state from the log", func(t *testing.T) {
+			dir := t.TempDir()
+			s := connect(t, dir, 0)
+			fill(t, s)
+			crash(t, s)
+
+			restored := connect(t, dir, 0)
+			requireSameState(t, s, restored)
+
+			event, err := restored.FindByID(ctx, "review")
+			require.NoError(t, err)
+			require.Equal(t, "design review", event.Title)
+			require.Equal(t, int64(2), event.Version)
+			require.True(t, event.Reminders[0].Sent)
+			_, err = restored.FindByID(ctx, "work")
+			require.ErrorIs(t, err, storage.ErrNotFound)
+
+			outbox, err := restored.FindOutbox(ctx, 10)
+			require.NoError(t, err)
+			require.Len(t, outbox, 1)
+			require.Equal(t, "review/bob", outbox[0].Key)
+
+			changes, err := restored.FindChangesByEventID(ctx, "work")
+			require.NoError(t, err)
+			require.Len(t, changes, 2)
+			require.Equal(t, storage.ChangeDelete, changes[1].Kind)
+
+			change := &storage.Change{EventID: "review", Kind: storage.ChangeUpdate}
+			require.NoError(t, restored.SaveChange(ctx, change))
+			require.Equal(t, int64(5), change.ID, "ids continue after the restored ones")
+		},
+	)
+
+	t.Run(
+		"when log reaches the limit, writes snapshot and truncates log", func(t *testing.T) {
+			dir := t.TempDir()
+			s := connect(t, dir, 4)
+			fill(t, s)
+
+			require.FileExists(t, filepath.Join(dir, snapshotFile))
+			require.Less(t, s.records, 4)
+
+			require.NoError(t, s.Close(ctx))
+			info, err := os.Stat(filepath.Join(dir, logFile))
+			require.NoError(t, err)
+			require.Zero(t, info.Size())
+			requireSameState(t, s, connect(t, dir, 4))
+		},
+	)
+
+	t.Run(
+		"when last record is torn, drops it", func(t *testing.T) {
+			dir := t.TempDir()
+			s := connect(t, dir, 0)
+			fill(t, s)
+			crash(t, s)
+
+			f, err := os.OpenFile(filepath.Join(dir, logFile), os.O_APPEND|os.O_WRONLY, 0o600)
+			require.NoError(t, err)
+			_, err = f.WriteString(`{"op":"deleteEvent","eventId":"rev`)
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+
+			restored := connect(t, dir, 0)
+			requireSameState(t, s, restored)
+
+			require.NoError(t, restored.Delete(ctx, &storage.Event{ID: "review"}))
+			require.NoError(t, restored.Close(ctx))
+			_, err = connect(t, dir, 0).FindByID(ctx, "review")
+			require.ErrorIs(t, err, storage.ErrNotFound)
+		},
+	)
+
+	t.Run(
+		"when record in the middle is corrupted, returns error", func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, logFile), []byte("{\n{}\n"), 0o600))
+			require.Error(t, New(dir, 0).Connect(ctx))
+		},
+	)
+
+	t.Run(
+		"when directory is used by another storage, fails to connect until it is closed", func(t *testing.T) {
+			dir := t.TempDir()
+			s := connect(t, dir, 0)
+			require.NoError(t, s.Save(ctx, &storage.Event{ID: "event", OwnerID: "alice"}))
+
+			require.ErrorIs(t, New(dir, 0).Connect(ctx), ErrLocked)
+
+			require.NoError(t, s.Close(ctx))
+			_, err := connect(t, dir, 0).FindByID(ctx, "event")
+			require.NoError(t, err)
+		},
+	)
+}
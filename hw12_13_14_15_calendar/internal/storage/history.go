@@ -0,0 +1,37 @@
+package storage
+
+import "time"
+
+// ChangeKind is the operation recorded in the event history.
+type ChangeKind string
+
+const (
+	ChangeCreate  ChangeKind = "create"
+	ChangeUpdate  ChangeKind = "update"
+	ChangeDelete  ChangeKind = "delete"
+	ChangeRestore ChangeKind = "restore"
+)
+
+// Change is an immutable entry of the event history.
+type Change struct {
+	// ID is assigned by the storage, later changes have greater ids.
+	ID      int64
+	EventID EventID
+	Kind    ChangeKind
+	// ActorID is the user who made the change.
+	ActorID UserID
+	// At is set by the storage.
+	At time.Time
+	// Before is the event before the change, nil for a created event.
+	Before *Event
+	// After is the event after the change, nil for a deleted event.
+	After *Event
+}
+
+// Snapshot returns the state of the event recorded by the change, the deleted one for a delete.
+func (c Change) Snapshot() *Event {
+	if c.After != nil {
+		return c.After
+	}
+	return c.Before
+}
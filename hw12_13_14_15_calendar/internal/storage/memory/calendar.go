@@ -0,0 +1,112 @@
+package memorystorage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// SaveCalendar creates or renames the calendar and replaces its grants.
+func (s *Storage) SaveCalendar(ctx context.Context, calendar *storage.Calendar) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, ok := s.calendars[calendar.ID]; ok {
+		calendar.CreatedAt = old.CreatedAt
+	} else {
+		calendar.CreatedAt = time.Now()
+	}
+
+	saved := *calendar
+	saved.Grants = make([]storage.Grant, len(calendar.Grants))
+	copy(saved.Grants, calendar.Grants)
+	s.calendars[calendar.ID] = saved
+	return nil
+}
+
+func (s *Storage) FindCalendarByID(ctx context.Context, calendarID storage.CalendarID) (*storage.Calendar, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if calendar, ok := s.calendars[calendarID]; ok {
+		return &calendar, nil
+	}
+
+	return nil, fmt.Errorf("calendar %s: %w", calendarID, storage.ErrNotFound)
+}
+
+// FindCalendarsByUserID returns the calendars owned by the user or shared with them.
+func (s *Storage) FindCalendarsByUserID(ctx context.Context, userID storage.UserID) ([]storage.Calendar, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	calendars := make([]storage.Calendar, 0)
+	for _, calendar := range s.calendars {
+		if calendar.AccessOf(userID) != storage.AccessNone {
+			calendars = append(calendars, calendar)
+		}
+	}
+	sort.Slice(calendars, func(i, j int) bool {
+		if !calendars[i].CreatedAt.Equal(calendars[j].CreatedAt) {
+			return calendars[i].CreatedAt.Before(calendars[j].CreatedAt)
+		}
+		return calendars[i].ID < calendars[j].ID
+	})
+	return calendars, nil
+}
+
+// DeleteCalendar removes the calendar together with its events.
+func (s *Storage) DeleteCalendar(ctx context.Context, calendarID storage.CalendarID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteCalendar(calendarID)
+	return nil
+}
+
+// DeleteCalendarWithChanges deletes the calendar like DeleteCalendar and appends the changes
+// to the histories of its events atomically.
+func (s *Storage) DeleteCalendarWithChanges(
+	ctx context.Context,
+	calendarID storage.CalendarID,
+	changes []*storage.Change,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteCalendar(calendarID)
+	for _, change := range changes {
+		s.saveChange(change)
+	}
+	return nil
+}
+
+func (s *Storage) deleteCalendar(calendarID storage.CalendarID) {
+	for id, event := range s.items {
+		if event.CalendarID == calendarID {
+			s.remove(id)
+		}
+	}
+	delete(s.calendars, calendarID)
+}
+
+// FindAllByCalendarIDAndPeriod returns the events and the series of the calendar which overlap [from, to).
+func (s *Storage) FindAllByCalendarIDAndPeriod(
+	ctx context.Context,
+	calendarID storage.CalendarID,
+	from, to time.Time,
+) ([]storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	events := make([]storage.Event, 0)
+	calendar, ok := s.calendars[calendarID]
+	if !ok {
+		return events, nil
+	}
+	// events of the calendar belong to its owner, so they are in the owner's index
+	for _, event := range s.findOverlapping(calendar.OwnerID, from, to) {
+		if event.CalendarID == calendarID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
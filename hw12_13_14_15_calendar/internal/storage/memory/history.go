@@ -0,0 +1,51 @@
+package memorystorage
+
+import (
+	"context"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// SaveChange appends the change to the event history, the snapshots are copied so the entry can't be altered.
+func (s *Storage) SaveChange(ctx context.Context, change *storage.Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saveChange(change)
+	return nil
+}
+
+func (s *Storage) saveChange(change *storage.Change) {
+	s.lastChangeID++
+	change.ID = s.lastChangeID
+	change.At = time.Now()
+
+	saved := *change
+	saved.Before = cloneEvent(change.Before)
+	saved.After = cloneEvent(change.After)
+	s.history[change.EventID] = append(s.history[change.EventID], saved)
+}
+
+// FindChangesByEventID returns the history of the event from the oldest change.
+func (s *Storage) FindChangesByEventID(ctx context.Context, eventID storage.EventID) ([]storage.Change, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	changes := make([]storage.Change, 0, len(s.history[eventID]))
+	for _, change := range s.history[eventID] {
+		change.Before = cloneEvent(change.Before)
+		change.After = cloneEvent(change.After)
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+func cloneEvent(event *storage.Event) *storage.Event {
+	if event == nil {
+		return nil
+	}
+	clone := *event
+	clone.Attendees = append([]storage.Attendee(nil), event.Attendees...)
+	clone.Reminders = append([]storage.Reminder(nil), event.Reminders...)
+	clone.ExDates = append([]time.Time(nil), event.ExDates...)
+	return &clone
+}
@@ -0,0 +1,91 @@
+package memorystorage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// endOfTime is the end of an endless series in the index.
+var endOfTime = time.Date(9999, time.December, 31, 23, 59, 59, 0, time.UTC)
+
+type interval struct {
+	start time.Time
+	end   time.Time
+	id    storage.EventID
+}
+
+func eventInterval(event storage.Event) interval {
+	end := event.SeriesEnd()
+	if end.IsZero() {
+		end = endOfTime
+	}
+	return interval{start: event.StartAt, end: end, id: event.ID}
+}
+
+// intervalIndex keeps intervals sorted by start together with the running maximum of ends,
+// so the intervals overlapping a period are bounded by two binary searches.
+type intervalIndex struct {
+	items  []interval
+	maxEnd []time.Time
+}
+
+func (x *intervalIndex) insert(iv interval) {
+	i := sort.Search(len(x.items), func(i int) bool {
+		return x.items[i].start.After(iv.start)
+	})
+
+	x.items = append(x.items, interval{})
+	copy(x.items[i+1:], x.items[i:])
+	x.items[i] = iv
+
+	x.maxEnd = append(x.maxEnd, time.Time{})
+	x.updateMaxEnd(i)
+}
+
+func (x *intervalIndex) remove(iv interval) {
+	i := sort.Search(len(x.items), func(i int) bool {
+		return !x.items[i].start.Before(iv.start)
+	})
+	for ; i < len(x.items) && x.items[i].start.Equal(iv.start); i++ {
+		if x.items[i].id != iv.id {
+			continue
+		}
+		x.items = append(x.items[:i], x.items[i+1:]...)
+		x.maxEnd = x.maxEnd[:len(x.maxEnd)-1]
+		x.updateMaxEnd(i)
+		return
+	}
+}
+
+// overlapping returns ids of the intervals overlapping [from, to) ordered by start.
+func (x *intervalIndex) overlapping(from, to time.Time) []storage.EventID {
+	hi := sort.Search(len(x.items), func(i int) bool {
+		return !x.items[i].start.Before(to)
+	})
+	lo := sort.Search(hi, func(i int) bool {
+		return x.maxEnd[i].After(from)
+	})
+
+	var ids []storage.EventID
+	for _, iv := range x.items[lo:hi] {
+		if iv.end.After(from) {
+			ids = append(ids, iv.id)
+		}
+	}
+	return ids
+}
+
+func (x *intervalIndex) len() int {
+	return len(x.items)
+}
+
+func (x *intervalIndex) updateMaxEnd(from int) {
+	for i := from; i < len(x.items); i++ {
+		x.maxEnd[i] = x.items[i].end
+		if i > 0 && x.maxEnd[i-1].After(x.maxEnd[i]) {
+			x.maxEnd[i] = x.maxEnd[i-1]
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package memorystorage
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervalIndex(t *testing.T) {
+	base := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time {
+		return base.Add(time.Duration(hour) * time.Hour)
+	}
+
+	index := &intervalIndex{}
+	index.insert(interval{start: at(9), end: at(18), id: "workday"})
+	index.insert(interval{start: at(10), end: at(11), id: "meeting"})
+	index.insert(interval{start: at(18), end: at(19), id: "dinner"})
+	index.insert(interval{start: at(1), end: at(2), id: "night"})
+
+	t.Run(
+		"when period is inside a long interval, returns it", func(t *testing.T) {
+			require.Equal(t, []storage.EventID{"workday"}, index.overlapping(at(12), at(13)))
+		},
+	)
+
+	t.Run(
+		"when intervals touch the period, does not return them", func(t *testing.T) {
+			require.Empty(t, index.overlapping(at(2), at(9)))
+			require.Empty(t, index.overlapping(at(19), at(20)))
+		},
+	)
+
+	t.Run(
+		"when interval is removed, does not return it", func(t *testing.T) {
+			index.remove(interval{start: at(9), end: at(18), id: "workday"})
+			require.Empty(t, index.overlapping(at(12), at(13)))
+			require.Equal(t, []storage.EventID{"meeting", "dinner"}, index.overlapping(at(10), at(19)))
+			require.Equal(t, 3, index.len())
+		},
+	)
+}
+
+func TestIntervalIndex_MatchesFullScan(t *testing.T) {
+	base := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	rnd := rand.New(rand.NewSource(1))
+
+	index := &intervalIndex{}
+	var all []interval
+	for i := 0; i < 500; i++ {
+		start := base.Add(time.Duration(rnd.Intn(10000)) * time.Minute)
+		iv := interval{
+			start: start,
+			end:   start.Add(time.Duration(1+rnd.Intn(600)) * time.Minute),
+			id:    storage.EventID(rune('a'+i%26)) + storage.EventID(time.Duration(i).String()),
+		}
+		index.insert(iv)
+		all = append(all, iv)
+	}
+	for _, iv := range all[:100] {
+		index.remove(iv)
+	}
+	all = all[100:]
+
+	for i := 0; i < 200; i++ {
+		from := base.Add(time.Duration(rnd.Intn(10000)) * time.Minute)
+		to := from.Add(time.Duration(1+rnd.Intn(300)) * time.Minute)
+
+		var expected []string
+		for _, iv := range all {
+			if storage.Overlaps(iv.start, iv.end, from, to) {
+				expected = append(expected, iv.id.String())
+			}
+		}
+		var actual []string
+		for _, id := range index.overlapping(from, to) {
+			actual = append(actual, id.String())
+		}
+		sort.Strings(expected)
+		sort.Strings(actual)
+		require.Equal(t, expected, actual)
+	}
+}
@@ -0,0 +1,88 @@
+package memorystorage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// CompleteReminder replaces the due reminder with the next one and puts the messages to the outbox atomically,
+// messages with the keys already in the outbox are skipped. It returns storage.ErrConflict if the reminder
+// is not in the due state anymore, for example the event was rescheduled after the reminder was read.
+func (s *Storage) CompleteReminder(
+	ctx context.Context,
+	due, next storage.Reminder,
+	messages []storage.OutboxMessage,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.items[due.EventID]
+	if !ok {
+		return fmt.Errorf("event %s: %w", due.EventID, storage.ErrNotFound)
+	}
+	if !hasReminder(event, due) {
+		return fmt.Errorf("%w: reminder of event %s has changed", storage.ErrConflict, due.EventID)
+	}
+	s.saveReminder(next)
+
+	now := time.Now()
+	for _, msg := range messages {
+		if s.hasOutboxKey(msg.Key) {
+			continue
+		}
+		s.lastOutboxID++
+		msg.ID = s.lastOutboxID
+		msg.CreatedAt = now
+		msg.Body = append([]byte(nil), msg.Body...)
+		s.outbox = append(s.outbox, msg)
+	}
+	return nil
+}
+
+func hasReminder(event storage.Event, reminder storage.Reminder) bool {
+	for _, r := range event.Reminders {
+		if r.Offset == reminder.Offset {
+			return r.Sent == reminder.Sent &&
+				r.OccurrenceAt.Equal(reminder.OccurrenceAt) &&
+				r.NotifyAt.Equal(reminder.NotifyAt)
+		}
+	}
+	return false
+}
+
+// FindOutbox returns up to limit oldest messages of the outbox.
+func (s *Storage) FindOutbox(ctx context.Context, limit int) ([]storage.OutboxMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if limit > len(s.outbox) {
+		limit = len(s.outbox)
+	}
+	messages := make([]storage.OutboxMessage, limit)
+	copy(messages, s.outbox)
+	return messages, nil
+}
+
+// DeleteOutbox acknowledges the published message, an unknown id is ignored.
+func (s *Storage) DeleteOutbox(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, msg := range s.outbox {
+		if msg.ID == id {
+			s.outbox = append(s.outbox[:i], s.outbox[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *Storage) hasOutboxKey(key string) bool {
+	for _, msg := range s.outbox {
+		if msg.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// textIndex is an inverted index of the words of the event titles and descriptions.
+type textIndex map[string]map[storage.EventID]struct{}
+
+func (x textIndex) insert(event storage.Event) {
+	for _, word := range eventWords(event) {
+		ids, ok := x[word]
+		if !ok {
+			ids = map[storage.EventID]struct{}{}
+			x[word] = ids
+		}
+		ids[event.ID] = struct{}{}
+	}
+}
+
+func (x textIndex) remove(event storage.Event) {
+	for _, word := range eventWords(event) {
+		delete(x[word], event.ID)
+		if len(x[word]) == 0 {
+			delete(x, word)
+		}
+	}
+}
+
+// matching returns ids of the events having all the words.
+func (x textIndex) matching(words []string) []storage.EventID {
+	// intersect starting from the rarest word
+	sort.Slice(words, func(i, j int) bool {
+		return len(x[words[i]]) < len(x[words[j]])
+	})
+
+	ids := make([]storage.EventID, 0, len(x[words[0]]))
+	for id := range x[words[0]] {
+		found := true
+		for _, word := range words[1:] {
+			if _, ok := x[word][id]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func eventWords(event storage.Event) []string {
+	return storage.Words(event.Title + " " + event.Description)
+}
+
+// Search returns a page of the events matching the filter and the number of all matching events.
+func (s *Storage) Search(ctx context.Context, filter storage.SearchFilter) ([]storage.Event, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	words := storage.Words(filter.Text)
+	var candidates []storage.Event
+	if len(words) > 0 {
+		for _, id := range s.text.matching(words) {
+			candidates = append(candidates, s.items[id])
+		}
+	} else {
+		candidates = s.searchCandidates(filter)
+	}
+
+	events := make([]storage.Event, 0)
+	for _, event := range candidates {
+		if matches(event, filter) {
+			events = append(events, event)
+		}
+	}
+	sortSearchResults(events, filter.Sort, words)
+
+	total := len(events)
+	if filter.Offset >= total {
+		return []storage.Event{}, total, nil
+	}
+	events = events[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(events) {
+		events = events[:filter.Limit]
+	}
+	return events, total, nil
+}
+
+// searchCandidates returns the events of the searched calendar overlapping the period of the filter.
+func (s *Storage) searchCandidates(filter storage.SearchFilter) []storage.Event {
+	from, to := filter.From, filter.To
+	if to.IsZero() {
+		to = endOfTime
+	}
+
+	userID := filter.UserID
+	if filter.CalendarID != "" {
+		calendar, ok := s.calendars[filter.CalendarID]
+		if !ok {
+			return nil
+		}
+		// events of the calendar belong to its owner, so they are in the owner's index
+		userID = calendar.OwnerID
+	}
+	return s.findOverlapping(userID, from, to)
+}
+
+func matches(event storage.Event, filter storage.SearchFilter) bool {
+	if filter.CalendarID != "" && event.CalendarID != filter.CalendarID {
+		return false
+	}
+	if filter.CalendarID == "" && !isMember(event, filter.UserID) {
+		return false
+	}
+
+	if !filter.From.IsZero() || !filter.To.IsZero() {
+		from, to := filter.From, filter.To
+		if to.IsZero() {
+			to = endOfTime
+		}
+		iv := eventInterval(event)
+		if !storage.Overlaps(iv.start, iv.end, from, to) {
+			return false
+		}
+	}
+
+	if filter.HasReminders != nil && *filter.HasReminders != (len(event.Reminders) > 0) {
+		return false
+	}
+	return true
+}
+
+func isMember(event storage.Event, userID storage.UserID) bool {
+	for _, member := range event.Members() {
+		if member == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// sortSearchResults orders the events by start, the relevance puts the events having all the words
+// in the title first.
+func sortSearchResults(events []storage.Event, order storage.SearchSort, words []string) {
+	inTitle := make(map[storage.EventID]bool, len(events))
+	if order == storage.SortRelevance && len(words) > 0 {
+		for _, event := range events {
+			title := map[string]struct{}{}
+			for _, word := range storage.Words(event.Title) {
+				title[word] = struct{}{}
+			}
+			found := true
+			for _, word := range words {
+				if _, ok := title[word]; !ok {
+					found = false
+					break
+				}
+			}
+			inTitle[event.ID] = found
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if inTitle[a.ID] != inTitle[b.ID] {
+			return inTitle[a.ID]
+		}
+		if !a.StartAt.Equal(b.StartAt) {
+			if order == storage.SortStartDesc {
+				return a.StartAt.After(b.StartAt)
+			}
+			return a.StartAt.Before(b.StartAt)
+		}
+		return a.ID < b.ID
+	})
+}
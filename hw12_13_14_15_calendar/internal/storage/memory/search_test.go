@@ -0,0 +1,141 @@
+package memorystorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage_Search(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	at := func(days, hour int) time.Time {
+		return day.AddDate(0, 0, days).Add(time.Duration(hour) * time.Hour)
+	}
+	store := New()
+
+	work := storage.Calendar{ID: "work", OwnerID: "alice", Name: "work"}
+	require.NoError(t, store.SaveCalendar(ctx, &work))
+
+	save := func(event storage.Event) storage.Event {
+		event.OwnerID = "alice"
+		event.EndAt = event.StartAt.Add(time.Hour)
+		require.NoError(t, store.Save(ctx, &event))
+		return event
+	}
+	review := save(storage.Event{
+		ID:          "review",
+		Title:       "Design review",
+		Description: "Go through the search proposal",
+		StartAt:     at(0, 10),
+		CalendarID:  work.ID,
+		Reminders:   []storage.Reminder{{EventID: "review", Offset: time.Hour}},
+	})
+	search := save(storage.Event{
+		ID:          "search",
+		Title:       "Search proposal",
+		Description: "Draft the design",
+		StartAt:     at(1, 10),
+	})
+	lunch := save(storage.Event{
+		ID:        "lunch",
+		Title:     "Lunch",
+		StartAt:   at(0, 12),
+		Attendees: []storage.Attendee{{UserID: "bob"}},
+	})
+
+	ids := func(events []storage.Event) []storage.EventID {
+		res := make([]storage.EventID, 0, len(events))
+		for _, event := range events {
+			res = append(res, event.ID)
+		}
+		return res
+	}
+
+	t.Run(
+		"when text is set, returns events having all the words ignoring case", func(t *testing.T) {
+			events, total, err := store.Search(ctx, storage.SearchFilter{UserID: "alice", Text: "DESIGN, proposal"})
+			require.NoError(t, err)
+			require.Equal(t, 2, total)
+			require.Equal(t, []storage.EventID{review.ID, search.ID}, ids(events))
+
+			events, total, err = store.Search(ctx, storage.SearchFilter{UserID: "alice", Text: "design lunch"})
+			require.NoError(t, err)
+			require.Zero(t, total)
+			require.Empty(t, events)
+		},
+	)
+
+	t.Run(
+		"when sorted by relevance, returns title matches first", func(t *testing.T) {
+			events, _, err := store.Search(ctx, storage.SearchFilter{
+				UserID: "alice",
+				Text:   "search",
+				Sort:   storage.SortRelevance,
+			})
+			require.NoError(t, err)
+			require.Equal(t, []storage.EventID{search.ID, review.ID}, ids(events))
+		},
+	)
+
+	t.Run(
+		"when filters are set, applies all of them", func(t *testing.T) {
+			hasReminders := false
+			events, total, err := store.Search(ctx, storage.SearchFilter{
+				UserID:       "alice",
+				From:         at(0, 0),
+				To:           at(1, 0),
+				HasReminders: &hasReminders,
+			})
+			require.NoError(t, err)
+			require.Equal(t, 1, total)
+			require.Equal(t, []storage.EventID{lunch.ID}, ids(events))
+
+			events, _, err = store.Search(ctx, storage.SearchFilter{CalendarID: work.ID})
+			require.NoError(t, err)
+			require.Equal(t, []storage.EventID{review.ID}, ids(events))
+
+			events, _, err = store.Search(ctx, storage.SearchFilter{UserID: "bob"})
+			require.NoError(t, err)
+			require.Equal(t, []storage.EventID{lunch.ID}, ids(events))
+		},
+	)
+
+	t.Run(
+		"when page is requested, returns it with the total", func(t *testing.T) {
+			events, total, err := store.Search(ctx, storage.SearchFilter{
+				UserID: "alice",
+				Sort:   storage.SortStartDesc,
+				Offset: 1,
+				Limit:  1,
+			})
+			require.NoError(t, err)
+			require.Equal(t, 3, total)
+			require.Equal(t, []storage.EventID{lunch.ID}, ids(events))
+
+			events, total, err = store.Search(ctx, storage.SearchFilter{UserID: "alice", Offset: 3})
+			require.NoError(t, err)
+			require.Equal(t, 3, total)
+			require.Empty(t, events)
+		},
+	)
+
+	t.Run(
+		"when event is changed or deleted, updates the index", func(t *testing.T) {
+			search.Title = "Retro"
+			require.NoError(t, store.Save(ctx, &search))
+			events, _, err := store.Search(ctx, storage.SearchFilter{UserID: "alice", Text: "search"})
+			require.NoError(t, err)
+			require.Equal(t, []storage.EventID{review.ID}, ids(events))
+
+			require.NoError(t, store.Delete(ctx, &review))
+			events, _, err = store.Search(ctx, storage.SearchFilter{UserID: "alice", Text: "search"})
+			require.NoError(t, err)
+			require.Empty(t, events)
+			require.NotContains(t, store.text, "review")
+		},
+	)
+}
@@ -0,0 +1,83 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Snapshot is a copy of the whole state of the storage.
+type Snapshot struct {
+	Events       []storage.Event         `json:"events"`
+	Calendars    []storage.Calendar      `json:"calendars"`
+	Changes      []storage.Change        `json:"changes"`
+	Outbox       []storage.OutboxMessage `json:"outbox"`
+	LastChangeID int64                   `json:"lastChangeId"`
+	LastOutboxID int64                   `json:"lastOutboxId"`
+}
+
+// Snapshot returns the state ordered by ids, so equal states have equal snapshots.
+func (s *Storage) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := Snapshot{
+		Events:       make([]storage.Event, 0, len(s.items)),
+		Calendars:    make([]storage.Calendar, 0, len(s.calendars)),
+		Changes:      make([]storage.Change, 0),
+		Outbox:       append([]storage.OutboxMessage{}, s.outbox...),
+		LastChangeID: s.lastChangeID,
+		LastOutboxID: s.lastOutboxID,
+	}
+	for _, event := range s.items {
+		snapshot.Events = append(snapshot.Events, *cloneEvent(&event))
+	}
+	sort.Slice(snapshot.Events, func(i, j int) bool {
+		return snapshot.Events[i].ID < snapshot.Events[j].ID
+	})
+	for _, calendar := range s.calendars {
+		calendar.Grants = append([]storage.Grant(nil), calendar.Grants...)
+		snapshot.Calendars = append(snapshot.Calendars, calendar)
+	}
+	sort.Slice(snapshot.Calendars, func(i, j int) bool {
+		return snapshot.Calendars[i].ID < snapshot.Calendars[j].ID
+	})
+	for _, changes := range s.history {
+		for _, change := range changes {
+			change.Before = cloneEvent(change.Before)
+			change.After = cloneEvent(change.After)
+			snapshot.Changes = append(snapshot.Changes, change)
+		}
+	}
+	sort.Slice(snapshot.Changes, func(i, j int) bool {
+		return snapshot.Changes[i].ID < snapshot.Changes[j].ID
+	})
+	return snapshot
+}
+
+// Restore returns the storage with the state of the snapshot.
+func Restore(snapshot Snapshot) *Storage {
+	s := New()
+	for _, event := range snapshot.Events {
+		s.put(*cloneEvent(&event))
+	}
+	for _, calendar := range snapshot.Calendars {
+		calendar.Grants = append([]storage.Grant(nil), calendar.Grants...)
+		s.calendars[calendar.ID] = calendar
+	}
+	for _, change := range snapshot.Changes {
+		change.Before = cloneEvent(change.Before)
+		change.After = cloneEvent(change.After)
+		s.history[change.EventID] = append(s.history[change.EventID], change)
+	}
+	s.outbox = append(s.outbox, snapshot.Outbox...)
+	s.lastChangeID = snapshot.LastChangeID
+	s.lastOutboxID = snapshot.LastOutboxID
+	return s
+}
+
+// Close does nothing, the state lives as long as the storage.
+func (s *Storage) Close(ctx context.Context) error {
+	return nil
+}
@@ -2,6 +2,8 @@ package memorystorage
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -10,18 +12,72 @@ import (
 )
 
 type Storage struct {
-	mu    *sync.RWMutex
-	items map[storage.EventID]storage.Event
+	mu        *sync.RWMutex
+	items     map[storage.EventID]storage.Event
+	index     map[storage.UserID]*intervalIndex
+	calendars map[storage.CalendarID]storage.Calendar
+	text      textIndex
+	// history is append-only, lastChangeID is the id of the latest change.
+	history      map[storage.EventID][]storage.Change
+	lastChangeID int64
+	// outbox is ordered by ids, lastOutboxID is the id of the latest message.
+	outbox       []storage.OutboxMessage
+	lastOutboxID int64
 }
 
 func (s *Storage) NextID(ctx context.Context) (storage.EventID, error) {
 	return storage.EventID(uuid.NewString()), nil
 }
 
+// Save stores the event, returns storage.ErrDateBusy if it overlaps another event of the owner
+// or of an attendee who has not declined. A new event must have zero version, an update must have
+// the version of the stored event, otherwise storage.ErrConflict or storage.ErrVersionMismatch is returned,
+// or storage.ErrNotFound if there is no event.
 func (s *Storage) Save(ctx context.Context, event *storage.Event) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.items[event.ID] = *event
+	return s.save(event)
+}
+
+// SaveWithChange stores the event like Save and appends the change with the saved event as After
+// to its history atomically.
+func (s *Storage) SaveWithChange(ctx context.Context, event *storage.Event, change *storage.Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.save(event); err != nil {
+		return err
+	}
+	change.EventID, change.After = event.ID, event
+	s.saveChange(change)
+	return nil
+}
+
+func (s *Storage) save(event *storage.Event) error {
+	old, exists := s.items[event.ID]
+	switch {
+	case !exists && event.Version != 0:
+		return fmt.Errorf("event %s: %w", event.ID, storage.ErrNotFound)
+	case exists && event.Version == 0:
+		return fmt.Errorf("%w: event %s already exists", storage.ErrConflict, event.ID)
+	case exists:
+		if err := storage.CheckVersion(old, event.Version); err != nil {
+			return err
+		}
+	}
+
+	if err := s.checkBusy(event); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	event.CreatedAt = now
+	if exists {
+		event.CreatedAt = old.CreatedAt
+	}
+	event.UpdatedAt = now
+	event.Version++
+
+	s.put(*event)
 	return nil
 }
 
@@ -32,80 +88,301 @@ func (s *Storage) FindByID(ctx context.Context, eventID storage.EventID) (*stora
 		return &event, nil
 	}
 
-	return nil, nil
+	return nil, fmt.Errorf("event %s: %w", eventID, storage.ErrNotFound)
 }
 
+// FindCurrentByID is FindByID, the storage has no cache.
+func (s *Storage) FindCurrentByID(ctx context.Context, eventID storage.EventID) (*storage.Event, error) {
+	return s.FindByID(ctx, eventID)
+}
+
+// Delete removes the event, returns storage.ErrNotFound if there is no event
+// and storage.ErrVersionMismatch if it was changed since it was read.
 func (s *Storage) Delete(ctx context.Context, event *storage.Event) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.items, event.ID)
+	return s.delete(event)
+}
+
+// DeleteWithChange removes the event like Delete and appends the change to its history atomically.
+func (s *Storage) DeleteWithChange(ctx context.Context, event *storage.Event, change *storage.Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.delete(event); err != nil {
+		return err
+	}
+	change.EventID = event.ID
+	s.saveChange(change)
 	return nil
 }
 
+func (s *Storage) delete(event *storage.Event) error {
+	old, ok := s.items[event.ID]
+	if !ok {
+		return fmt.Errorf("event %s: %w", event.ID, storage.ErrNotFound)
+	}
+	if event.Version != 0 {
+		if err := storage.CheckVersion(old, event.Version); err != nil {
+			return err
+		}
+	}
+	s.remove(event.ID)
+	return nil
+}
+
+// FindAllByUserIDAndPeriod returns the events and the series which overlap [from, to).
 func (s *Storage) FindAllByUserIDAndPeriod(
 	ctx context.Context, ownerID storage.UserID,
 	from, to time.Time,
 ) ([]storage.Event, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.findOverlapping(ownerID, from, to), nil
+}
+
+// FindPageByUserIDAndPeriod returns up to limit events and series of the user which overlap [from, to)
+// and follow the cursor, ordered by start and id. Zero from and to mean an unbounded period.
+func (s *Storage) FindPageByUserIDAndPeriod(
+	ctx context.Context,
+	userID storage.UserID,
+	from, to time.Time,
+	after storage.Cursor,
+	limit int,
+) ([]storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if to.IsZero() {
+		to = endOfTime
+	}
+
 	events := make([]storage.Event, 0)
-	for _, event := range s.items {
-		if event.OwnerID != ownerID {
-			continue
+	for _, event := range s.findOverlapping(userID, from, to) {
+		if after.Before(event) {
+			events = append(events, event)
 		}
-		if s.inRange(event.StartAt, from, to) || s.inRange(event.EndAt, from, to) {
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return storage.CursorOf(events[i]).Before(events[j])
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// FindSeriesPageByUserIDAndPeriod returns up to limit series of the user which overlap [from, to)
+// and follow the cursor, ordered by start and id. Zero from and to mean an unbounded period.
+func (s *Storage) FindSeriesPageByUserIDAndPeriod(
+	ctx context.Context,
+	userID storage.UserID,
+	from, to time.Time,
+	after storage.Cursor,
+	limit int,
+) ([]storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if to.IsZero() {
+		to = endOfTime
+	}
+
+	events := make([]storage.Event, 0)
+	for _, event := range s.findOverlapping(userID, from, to) {
+		if event.RRule != "" && after.Before(event) {
 			events = append(events, event)
 		}
 	}
+	sort.Slice(events, func(i, j int) bool {
+		return storage.CursorOf(events[i]).Before(events[j])
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
 	return events, nil
 }
 
-func (s *Storage) HasByUserIDAndPeriod(ctx context.Context, ownerID storage.UserID, from, to time.Time) (bool, error) {
+// FindBusyByUserIDsAndPeriod returns the events and the series which overlap [from, to)
+// and take time of any of the users, declined invitations are skipped.
+func (s *Storage) FindBusyByUserIDsAndPeriod(
+	ctx context.Context,
+	userIDs []storage.UserID,
+	from, to time.Time,
+) ([]storage.Event, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	for _, event := range s.items {
-		if event.OwnerID != ownerID {
-			continue
+	events := make([]storage.Event, 0)
+	seen := map[storage.EventID]struct{}{}
+	for _, userID := range userIDs {
+		for _, event := range s.findOverlapping(userID, from, to) {
+			if _, ok := seen[event.ID]; ok || !event.IsParticipant(userID) {
+				continue
+			}
+			seen[event.ID] = struct{}{}
+			events = append(events, event)
 		}
-		if s.inRange(event.StartAt, from, to) || s.inRange(event.EndAt, from, to) {
-			return true, nil
+	}
+	return events, nil
+}
+
+func (s *Storage) FindAllByUserID(ctx context.Context, ownerID storage.UserID) ([]storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	events := make([]storage.Event, 0)
+	if index, ok := s.index[ownerID]; ok {
+		for _, iv := range index.items {
+			events = append(events, s.items[iv.id])
 		}
 	}
-	return false, nil
+	return events, nil
 }
 
-func (s *Storage) HasByUserIDAndPeriodForUpdate(
-	ctx context.Context,
-	forUpdate storage.EventID,
-	ownerID storage.UserID,
-	from time.Time,
-	to time.Time,
-) (bool, error) {
+func (s *Storage) FindDueReminders(ctx context.Context, until time.Time) ([]storage.Reminder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	reminders := make([]storage.Reminder, 0)
+	for _, event := range s.items {
+		for _, reminder := range event.Reminders {
+			if !reminder.Sent && !reminder.NotifyAt.After(until) {
+				reminders = append(reminders, reminder)
+			}
+		}
+	}
+	sort.Slice(reminders, func(i, j int) bool {
+		return reminders[i].NotifyAt.Before(reminders[j].NotifyAt)
+	})
+	return reminders, nil
+}
+
+// SaveReminder replaces the event reminder with the same offset.
+func (s *Storage) SaveReminder(ctx context.Context, reminder storage.Reminder) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.saveReminder(reminder)
+	return nil
+}
+
+func (s *Storage) saveReminder(reminder storage.Reminder) {
+	event, ok := s.items[reminder.EventID]
+	if !ok {
+		return
+	}
+	reminders := make([]storage.Reminder, len(event.Reminders))
+	copy(reminders, event.Reminders)
+	for i := range reminders {
+		if reminders[i].Offset == reminder.Offset {
+			reminders[i] = reminder
+		}
+	}
+	event.Reminders = reminders
+	s.items[event.ID] = event
+}
+
+func (s *Storage) CountAllEndedBefore(ctx context.Context, before time.Time) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	count := 0
 	for _, event := range s.items {
-		if event.OwnerID != ownerID {
-			continue
+		if event.EndedBefore(before) {
+			count++
 		}
-		if event.ID == forUpdate {
-			if s.inRange(from, event.StartAt, event.EndAt) && s.inRange(to, event.StartAt, event.EndAt) {
-				return false, nil
+	}
+	return count, nil
+}
+
+func (s *Storage) DeleteAllEndedBefore(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for id, event := range s.items {
+		if event.EndedBefore(before) {
+			s.remove(id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// checkBusy returns storage.ErrDateBusy if the event conflicts with an event of any participant.
+func (s *Storage) checkBusy(event *storage.Event) error {
+	end := event.SeriesEnd()
+	if end.IsZero() {
+		end = endOfTime
+	}
+
+	checked := map[storage.EventID]struct{}{event.ID: {}}
+	for _, participant := range event.Participants() {
+		for _, other := range s.findOverlapping(participant, event.StartAt, end) {
+			if _, ok := checked[other.ID]; ok || !other.IsParticipant(participant) {
+				continue
+			}
+			checked[other.ID] = struct{}{}
+
+			conflicts, err := event.Conflicts(other)
+			if err != nil {
+				return err
+			}
+			if conflicts {
+				return fmt.Errorf("user %s: %w", participant, storage.ErrDateBusy)
 			}
 		}
-		if s.inRange(event.StartAt, from, to) || s.inRange(event.EndAt, from, to) {
-			return true, nil
+	}
+	return nil
+}
+
+// findOverlapping returns the events of the user's calendar, both own and invited,
+// which overlap [from, to) ordered by start.
+func (s *Storage) findOverlapping(ownerID storage.UserID, from, to time.Time) []storage.Event {
+	events := make([]storage.Event, 0)
+	index, ok := s.index[ownerID]
+	if !ok {
+		return events
+	}
+	for _, id := range index.overlapping(from, to) {
+		events = append(events, s.items[id])
+	}
+	return events
+}
+
+func (s *Storage) put(event storage.Event) {
+	s.remove(event.ID)
+	s.items[event.ID] = event
+	s.text.insert(event)
+
+	for _, member := range event.Members() {
+		index, ok := s.index[member]
+		if !ok {
+			index = &intervalIndex{}
+			s.index[member] = index
 		}
+		index.insert(eventInterval(event))
 	}
-	return false, nil
 }
 
-func (s *Storage) inRange(date, from, to time.Time) bool {
-	return date.Equal(from) || date.Equal(to) || (date.After(from) && date.Before(to))
+func (s *Storage) remove(id storage.EventID) {
+	event, ok := s.items[id]
+	if !ok {
+		return
+	}
+	delete(s.items, id)
+	s.text.remove(event)
+
+	for _, member := range event.Members() {
+		if index, ok := s.index[member]; ok {
+			index.remove(eventInterval(event))
+			if index.len() == 0 {
+				delete(s.index, member)
+			}
+		}
+	}
 }
 
 func New() *Storage {
 	return &Storage{
-		mu:    &sync.RWMutex{},
-		items: map[storage.EventID]storage.Event{},
+		mu:        &sync.RWMutex{},
+		items:     map[storage.EventID]storage.Event{},
+		index:     map[storage.UserID]*intervalIndex{},
+		calendars: map[storage.CalendarID]storage.Calendar{},
+		text:      textIndex{},
+		history:   map[storage.EventID][]storage.Change{},
+		outbox:    []storage.OutboxMessage{},
 	}
 }
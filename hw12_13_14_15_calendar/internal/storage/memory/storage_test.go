@@ -3,6 +3,7 @@ package memorystorage
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,13 +12,16 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func TestStorage_Save(t *testing.T) {
-	items := make(map[storage.EventID]storage.Event)
-	mu := &sync.RWMutex{}
-	store := &Storage{
-		mu:    mu,
-		items: items,
+func newTestStorage(events ...storage.Event) *Storage {
+	store := New()
+	for _, event := range events {
+		store.put(event)
 	}
+	return store
+}
+
+func TestStorage_Save(t *testing.T) {
+	store := New()
 	aEvent := storage.Event{
 		ID:          storage.EventID(uuid.NewString()),
 		Title:       "test",
@@ -25,14 +29,87 @@ func TestStorage_Save(t *testing.T) {
 		EndAt:       time.Now().Add(30 * time.Second),
 		Description: "",
 		OwnerID:     storage.UserID(uuid.NewString()),
-		NotifyAt:    time.Time{},
 	}
 
 	err := store.Save(context.Background(), &aEvent)
 	require.NoError(t, err)
-	event, ok := items[aEvent.ID]
+	event, ok := store.items[aEvent.ID]
 	require.True(t, ok)
 	require.Equal(t, aEvent, event)
+	require.False(t, event.CreatedAt.IsZero())
+	require.Equal(t, event.CreatedAt, event.UpdatedAt)
+
+	t.Run(
+		"when event is updated, keeps created at", func(t *testing.T) {
+			updated := aEvent
+			updated.Title = "updated"
+			require.NoError(t, store.Save(context.Background(), &updated))
+			require.Equal(t, aEvent.CreatedAt, updated.CreatedAt)
+			require.False(t, updated.UpdatedAt.Before(aEvent.UpdatedAt))
+		},
+	)
+}
+
+func TestStorage_SaveVersion(t *testing.T) {
+	newEvent := func() storage.Event {
+		return storage.Event{
+			ID:      storage.EventID(uuid.NewString()),
+			Title:   "test",
+			StartAt: time.Now(),
+			EndAt:   time.Now().Add(time.Hour),
+			OwnerID: "user",
+		}
+	}
+
+	t.Run(
+		"when event is saved, increments version", func(t *testing.T) {
+			store := New()
+			aEvent := newEvent()
+			require.NoError(t, store.Save(context.Background(), &aEvent))
+			require.Equal(t, int64(1), aEvent.Version)
+
+			require.NoError(t, store.Save(context.Background(), &aEvent))
+			require.Equal(t, int64(2), aEvent.Version)
+			require.Equal(t, int64(2), store.items[aEvent.ID].Version)
+		},
+	)
+
+	t.Run(
+		"when version is stale, returns version mismatch error", func(t *testing.T) {
+			store := New()
+			aEvent := newEvent()
+			require.NoError(t, store.Save(context.Background(), &aEvent))
+			first, second := aEvent, aEvent
+
+			first.Title = "first"
+			require.NoError(t, store.Save(context.Background(), &first))
+			second.Title = "second"
+			require.ErrorIs(t, store.Save(context.Background(), &second), storage.ErrVersionMismatch)
+			require.ErrorIs(t, store.Delete(context.Background(), &second), storage.ErrVersionMismatch)
+			require.Equal(t, "first", store.items[aEvent.ID].Title)
+		},
+	)
+
+	t.Run(
+		"when new event has existing id, returns conflict error", func(t *testing.T) {
+			store := New()
+			aEvent := newEvent()
+			require.NoError(t, store.Save(context.Background(), &aEvent))
+
+			duplicate := aEvent
+			duplicate.Version = 0
+			require.ErrorIs(t, store.Save(context.Background(), &duplicate), storage.ErrConflict)
+		},
+	)
+
+	t.Run(
+		"when updated event does not exist, returns not found error", func(t *testing.T) {
+			aEvent := newEvent()
+			aEvent.Version = 1
+			require.ErrorIs(t, New().Save(context.Background(), &aEvent), storage.ErrNotFound)
+			require.ErrorIs(t, New().Delete(context.Background(), &aEvent), storage.ErrNotFound)
+		},
+	)
 }
 
 func TestStorage_FindByID(t *testing.T) {
@@ -45,12 +122,8 @@ func TestStorage_FindByID(t *testing.T) {
 				EndAt:       time.Now().Add(30 * time.Second),
 				Description: "",
 				OwnerID:     storage.UserID(uuid.NewString()),
-				NotifyAt:    time.Time{},
-			}
-			store := &Storage{
-				mu:    &sync.RWMutex{},
-				items: map[storage.EventID]storage.Event{aEvent.ID: aEvent},
 			}
+			store := newTestStorage(aEvent)
 
 			event, err := store.FindByID(context.Background(), aEvent.ID)
 			require.NoError(t, err)
@@ -59,7 +132,7 @@ func TestStorage_FindByID(t *testing.T) {
 	)
 
 	t.Run(
-		"when event does not exists, returns nil", func(t *testing.T) {
+		"when event does not exists, returns not found error", func(t *testing.T) {
 			aEvent := storage.Event{
 				ID:          storage.EventID(uuid.NewString()),
 				Title:       "test",
@@ -67,15 +140,11 @@ func TestStorage_FindByID(t *testing.T) {
 				EndAt:       time.Now().Add(30 * time.Second),
 				Description: "",
 				OwnerID:     storage.UserID(uuid.NewString()),
-				NotifyAt:    time.Time{},
-			}
-			store := &Storage{
-				mu:    &sync.RWMutex{},
-				items: make(map[storage.EventID]storage.Event),
 			}
+			store := newTestStorage()
 
 			event, err := store.FindByID(context.Background(), aEvent.ID)
-			require.NoError(t, err)
+			require.ErrorIs(t, err, storage.ErrNotFound)
 			require.Nil(t, event)
 		},
 	)
@@ -89,12 +158,8 @@ func TestStorage_FindByID(t *testing.T) {
 				EndAt:       time.Now().Add(30 * time.Second),
 				Description: "",
 				OwnerID:     storage.UserID(uuid.NewString()),
-				NotifyAt:    time.Time{},
-			}
-			store := &Storage{
-				mu:    &sync.RWMutex{},
-				items: map[storage.EventID]storage.Event{aEvent.ID: aEvent},
 			}
+			store := newTestStorage(aEvent)
 
 			wg := &sync.WaitGroup{}
 			wg.Add(10)
@@ -123,12 +188,8 @@ func TestStorage_Delete(t *testing.T) {
 				EndAt:       time.Now().Add(30 * time.Second),
 				Description: "",
 				OwnerID:     storage.UserID(uuid.NewString()),
-				NotifyAt:    time.Time{},
-			}
-			store := &Storage{
-				mu:    &sync.RWMutex{},
-				items: map[storage.EventID]storage.Event{aEvent.ID: aEvent},
 			}
+			store := newTestStorage(aEvent)
 			err := store.Delete(context.Background(), &aEvent)
 			require.NoError(t, err)
 
@@ -138,7 +199,7 @@ func TestStorage_Delete(t *testing.T) {
 	)
 
 	t.Run(
-		"when even does not exists, returns nil", func(t *testing.T) {
+		"when event does not exist, returns not found error", func(t *testing.T) {
 			aEvent := storage.Event{
 				ID:          storage.EventID(uuid.NewString()),
 				Title:       "test",
@@ -146,14 +207,10 @@ func TestStorage_Delete(t *testing.T) {
 				EndAt:       time.Now().Add(30 * time.Second),
 				Description: "",
 				OwnerID:     storage.UserID(uuid.NewString()),
-				NotifyAt:    time.Time{},
-			}
-			store := &Storage{
-				mu:    &sync.RWMutex{},
-				items: make(map[storage.EventID]storage.Event),
 			}
+			store := newTestStorage()
 			err := store.Delete(context.Background(), &aEvent)
-			require.NoError(t, err)
+			require.ErrorIs(t, err, storage.ErrNotFound)
 			require.Len(t, store.items, 0)
 		},
 	)
@@ -169,17 +226,9 @@ func TestStorage_FindAllByUserIDAndPeriod(t *testing.T) {
 		EndAt:       endAt,
 		Description: "",
 		OwnerID:     storage.UserID(uuid.NewString()),
-		NotifyAt:    time.Time{},
 	}
 
-	items := map[storage.EventID]storage.Event{
-		aEvent.ID: aEvent,
-	}
-	mu := &sync.RWMutex{}
-	store := &Storage{
-		mu:    mu,
-		items: items,
-	}
+	store := newTestStorage(aEvent)
 
 	t.Run(
 		"in range and existent user id", func(t *testing.T) {
@@ -213,82 +262,625 @@ func TestStorage_FindAllByUserIDAndPeriod(t *testing.T) {
 	)
 }
 
-func TestStorage_HasByUserIDAndPeriod(t *testing.T) {
-	startAt, _ := time.Parse(time.RFC3339, "2006-01-01T10:00:00Z")
-	endAt := startAt.Add(30 * time.Minute)
+func TestStorage_NextID(t *testing.T) {
+	store := newTestStorage()
+	id, err := store.NextID(context.Background())
+	require.NoError(t, err)
+
+	u, err := uuid.Parse(id.String())
+	require.NoError(t, err)
+	require.Equal(t, id.String(), u.String())
+}
+
+func TestNew(t *testing.T) {
+	expected := &Storage{
+		&sync.RWMutex{},
+		map[storage.EventID]storage.Event{},
+		map[storage.UserID]*intervalIndex{},
+		map[storage.CalendarID]storage.Calendar{},
+		textIndex{},
+		map[storage.EventID][]storage.Change{},
+		0,
+		[]storage.OutboxMessage{},
+		0,
+	}
+	require.Equal(t, expected, New())
+}
+
+func TestStorage_FindDueReminders(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2006-01-01T10:00:00Z")
 	aEvent := storage.Event{
-		ID:          storage.EventID(uuid.NewString()),
-		Title:       "test",
-		StartAt:     startAt,
-		EndAt:       endAt,
-		Description: "",
-		OwnerID:     storage.UserID(uuid.NewString()),
-		NotifyAt:    time.Time{},
+		ID: "event",
+		Reminders: []storage.Reminder{
+			{EventID: "event", Offset: 24 * time.Hour, NotifyAt: now.Add(-time.Hour)},
+			{EventID: "event", Offset: time.Hour, NotifyAt: now.Add(-time.Minute), Sent: true},
+			{EventID: "event", Offset: time.Minute, NotifyAt: now.Add(time.Minute)},
+		},
+	}
+	another := storage.Event{
+		ID:        "another",
+		Reminders: []storage.Reminder{{EventID: "another", NotifyAt: now}},
 	}
+	withoutReminders := storage.Event{ID: "without_reminders"}
+
+	store := newTestStorage(aEvent, another, withoutReminders)
+
+	reminders, err := store.FindDueReminders(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, []storage.Reminder{aEvent.Reminders[0], another.Reminders[0]}, reminders)
+}
 
-	items := map[storage.EventID]storage.Event{
-		aEvent.ID: aEvent,
+func TestStorage_SaveReminder(t *testing.T) {
+	aEvent := storage.Event{
+		ID: storage.EventID(uuid.NewString()),
+		Reminders: []storage.Reminder{
+			{Offset: time.Hour, NotifyAt: time.Now()},
+			{Offset: time.Minute, NotifyAt: time.Now()},
+		},
 	}
-	mu := &sync.RWMutex{}
-	store := &Storage{
-		mu:    mu,
-		items: items,
+	aEvent.Reminders[0].EventID = aEvent.ID
+	aEvent.Reminders[1].EventID = aEvent.ID
+	store := newTestStorage(aEvent)
+
+	sent := aEvent.Reminders[1]
+	sent.Sent = true
+	require.NoError(t, store.SaveReminder(context.Background(), sent))
+	require.Equal(t, []storage.Reminder{aEvent.Reminders[0], sent}, store.items[aEvent.ID].Reminders)
+	require.False(t, aEvent.Reminders[1].Sent, "saved event must not be changed")
+
+	require.NoError(t, store.SaveReminder(context.Background(), storage.Reminder{EventID: "nonexistent"}))
+	require.Len(t, store.items, 1)
+}
+
+func TestStorage_DeleteAllEndedBefore(t *testing.T) {
+	before, _ := time.Parse(time.RFC3339, "2006-01-01T10:00:00Z")
+	ended := storage.Event{ID: "ended", EndAt: before.Add(-time.Second)}
+	endsAtCutoff := storage.Event{ID: "ends_at_cutoff", EndAt: before}
+	store := newTestStorage(ended, endsAtCutoff)
+
+	count, err := store.CountAllEndedBefore(context.Background(), before)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Len(t, store.items, 2)
+
+	count, err = store.DeleteAllEndedBefore(context.Background(), before)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Equal(t, map[storage.EventID]storage.Event{endsAtCutoff.ID: endsAtCutoff}, store.items)
+}
+
+func TestStorage_FindAllByUserID(t *testing.T) {
+	startAt, _ := time.Parse(time.RFC3339, "2006-01-01T10:00:00Z")
+	ownerID := storage.UserID(uuid.NewString())
+	late := storage.Event{ID: "late", OwnerID: ownerID, StartAt: startAt.Add(time.Hour), EndAt: startAt.Add(2 * time.Hour)}
+	early := storage.Event{ID: "early", OwnerID: ownerID, StartAt: startAt, EndAt: startAt.Add(time.Hour)}
+	another := storage.Event{ID: "another", OwnerID: "another", StartAt: startAt, EndAt: startAt.Add(time.Hour)}
+	store := newTestStorage(late, early, another)
+
+	events, err := store.FindAllByUserID(context.Background(), ownerID)
+	require.NoError(t, err)
+	require.Equal(t, []storage.Event{early, late}, events)
+}
+
+func TestStorage_SaveOverlapping(t *testing.T) {
+	day := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	newEvent := func(id string, from, to time.Duration) storage.Event {
+		return storage.Event{ID: storage.EventID(id), OwnerID: "user", StartAt: day.Add(from), EndAt: day.Add(to)}
 	}
+	workday := newEvent("workday", 9*time.Hour, 18*time.Hour)
+	workday.Version = 1
 
 	t.Run(
-		"in range and existent user id", func(t *testing.T) {
-			ok, err := store.HasByUserIDAndPeriod(
-				context.Background(),
-				aEvent.OwnerID,
-				startAt.Add(10*time.Minute),
-				startAt.Add(24*time.Hour),
-			)
+		"when event is inside an existing event, returns date busy error", func(t *testing.T) {
+			store := newTestStorage(workday)
+			meeting := newEvent("meeting", 10*time.Hour, 11*time.Hour)
+			require.ErrorIs(t, store.Save(context.Background(), &meeting), storage.ErrDateBusy)
+		},
+	)
+
+	t.Run(
+		"when event touches an existing event, saves it", func(t *testing.T) {
+			store := newTestStorage(workday)
+			dinner := newEvent("dinner", 18*time.Hour, 19*time.Hour)
+			require.NoError(t, store.Save(context.Background(), &dinner))
+		},
+	)
+
+	t.Run(
+		"when event is moved, does not conflict with itself", func(t *testing.T) {
+			store := newTestStorage(workday)
+			moved := workday
+			moved.EndAt = moved.EndAt.Add(time.Hour)
+			require.NoError(t, store.Save(context.Background(), &moved))
+
+			events, err := store.FindAllByUserIDAndPeriod(context.Background(), "user", day, day.AddDate(0, 0, 1))
 			require.NoError(t, err)
-			require.True(t, ok)
+			require.Equal(t, []storage.Event{moved}, events)
 		},
 	)
 
 	t.Run(
-		"in range and nonexistent user", func(t *testing.T) {
-			ok, err := store.HasByUserIDAndPeriod(
-				context.Background(),
-				"nonexistent_user_id",
-				startAt.Add(10*time.Minute),
-				startAt.Add(24*time.Hour),
-			)
+		"when series occurrence overlaps an existing event, returns date busy error", func(t *testing.T) {
+			store := newTestStorage(workday)
+			standup := storage.Event{
+				ID:      "standup",
+				OwnerID: "user",
+				StartAt: day.AddDate(0, 0, -3).Add(10 * time.Hour),
+				EndAt:   day.AddDate(0, 0, -3).Add(10*time.Hour + 15*time.Minute),
+				RRule:   "FREQ=DAILY",
+			}
+			require.ErrorIs(t, store.Save(context.Background(), &standup), storage.ErrDateBusy)
+		},
+	)
+
+	t.Run(
+		"when events are saved concurrently, saves only one of them", func(t *testing.T) {
+			store := New()
+			wg := &sync.WaitGroup{}
+			var saved int32
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					event := newEvent(uuid.NewString(), time.Duration(i)*time.Minute, time.Hour)
+					if store.Save(context.Background(), &event) == nil {
+						atomic.AddInt32(&saved, 1)
+					}
+				}(i)
+			}
+			wg.Wait()
+			require.Equal(t, int32(1), saved)
+		},
+	)
+}
+
+func TestStorage_Attendees(t *testing.T) {
+	day := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	newEvent := func(id, ownerID string, from, to time.Duration, attendees ...storage.Attendee) storage.Event {
+		return storage.Event{
+			ID:        storage.EventID(id),
+			StartAt:   day.Add(from),
+			EndAt:     day.Add(to),
+			OwnerID:   storage.UserID(ownerID),
+			Attendees: attendees,
+		}
+	}
+	bob := storage.Attendee{UserID: "bob", Status: storage.StatusNeedsAction}
+
+	t.Run(
+		"when user is invited, lists the event in the user calendar", func(t *testing.T) {
+			meeting := newEvent("meeting", "alice", 10*time.Hour, 11*time.Hour, bob)
+			store := New()
+			require.NoError(t, store.Save(context.Background(), &meeting))
+
+			events, err := store.FindAllByUserIDAndPeriod(context.Background(), "bob", day, day.AddDate(0, 0, 1))
 			require.NoError(t, err)
-			require.False(t, ok)
+			require.Equal(t, []storage.Event{meeting}, events)
+
+			events, err = store.FindAllByUserID(context.Background(), "bob")
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+
+			meeting.Attendees = nil
+			require.NoError(t, store.Save(context.Background(), &meeting))
+			events, err = store.FindAllByUserID(context.Background(), "bob")
+			require.NoError(t, err)
+			require.Empty(t, events, "uninvited user must not see the event")
 		},
 	)
 
 	t.Run(
-		"not in range and existent user", func(t *testing.T) {
-			ok, err := store.HasByUserIDAndPeriod(
-				context.Background(),
-				aEvent.OwnerID,
-				startAt.Add(30*time.Minute+time.Millisecond),
-				startAt.Add(24*time.Hour),
-			)
+		"when attendee is busy, returns date busy error", func(t *testing.T) {
+			store := newTestStorage(newEvent("lunch", "bob", 10*time.Hour, 11*time.Hour))
+			meeting := newEvent("meeting", "alice", 10*time.Hour, 11*time.Hour, bob)
+			require.ErrorIs(t, store.Save(context.Background(), &meeting), storage.ErrDateBusy)
+		},
+	)
+
+	t.Run(
+		"when invited event overlaps attendee's new event, returns date busy error", func(t *testing.T) {
+			store := newTestStorage(newEvent("meeting", "alice", 10*time.Hour, 11*time.Hour, bob))
+			lunch := newEvent("lunch", "bob", 10*time.Hour, 11*time.Hour)
+			require.ErrorIs(t, store.Save(context.Background(), &lunch), storage.ErrDateBusy)
+		},
+	)
+
+	t.Run(
+		"when attendee declined, does not take the attendee time", func(t *testing.T) {
+			declined := storage.Attendee{UserID: "bob", Status: storage.StatusDeclined}
+			store := newTestStorage(newEvent("meeting", "alice", 10*time.Hour, 11*time.Hour, declined))
+			lunch := newEvent("lunch", "bob", 10*time.Hour, 11*time.Hour)
+			require.NoError(t, store.Save(context.Background(), &lunch))
+		},
+	)
+}
+
+func TestStorage_FindBusyByUserIDsAndPeriod(t *testing.T) {
+	day := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	meeting := storage.Event{
+		ID:      "meeting",
+		StartAt: day.Add(10 * time.Hour),
+		EndAt:   day.Add(11 * time.Hour),
+		OwnerID: "alice",
+		Attendees: []storage.Attendee{
+			{UserID: "bob", Status: storage.StatusAccepted},
+			{UserID: "carol", Status: storage.StatusDeclined},
+		},
+	}
+	lunch := storage.Event{ID: "lunch", StartAt: day.Add(12 * time.Hour), EndAt: day.Add(13 * time.Hour), OwnerID: "bob"}
+	store := newTestStorage(meeting, lunch)
+	ctx := context.Background()
+
+	t.Run(
+		"when users share an event, returns it once", func(t *testing.T) {
+			events, err := store.FindBusyByUserIDsAndPeriod(ctx, []storage.UserID{"alice", "bob"}, day, day.AddDate(0, 0, 1))
 			require.NoError(t, err)
-			require.False(t, ok)
+			require.Len(t, events, 2)
+			require.Equal(t, storage.EventID("meeting"), events[0].ID)
+			require.Equal(t, storage.EventID("lunch"), events[1].ID)
+		},
+	)
+
+	t.Run(
+		"when invitation is declined, skips the event", func(t *testing.T) {
+			events, err := store.FindBusyByUserIDsAndPeriod(ctx, []storage.UserID{"carol"}, day, day.AddDate(0, 0, 1))
+			require.NoError(t, err)
+			require.Empty(t, events)
+		},
+	)
+
+	t.Run(
+		"when events are out of the period, returns nothing", func(t *testing.T) {
+			events, err := store.FindBusyByUserIDsAndPeriod(ctx, []storage.UserID{"bob"}, day, day.Add(10*time.Hour))
+			require.NoError(t, err)
+			require.Empty(t, events)
 		},
 	)
 }
 
-func TestStorage_NextID(t *testing.T) {
-	store := &Storage{
-		mu:    &sync.RWMutex{},
-		items: map[storage.EventID]storage.Event{},
+func TestStorage_Calendars(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	store := New()
+
+	work := storage.Calendar{
+		ID:      "work",
+		OwnerID: "alice",
+		Name:    "work",
+		Grants:  []storage.Grant{{UserID: "bob", Access: storage.AccessRead}},
 	}
-	id, err := store.NextID(context.Background())
-	require.NoError(t, err)
+	require.NoError(t, store.SaveCalendar(ctx, &work))
+	require.False(t, work.CreatedAt.IsZero())
 
-	u, err := uuid.Parse(id.String())
-	require.NoError(t, err)
-	require.Equal(t, id.String(), u.String())
+	review := storage.Event{
+		ID:         "review",
+		StartAt:    day.Add(10 * time.Hour),
+		EndAt:      day.Add(11 * time.Hour),
+		OwnerID:    "alice",
+		CalendarID: work.ID,
+	}
+	lunch := storage.Event{ID: "lunch", StartAt: day.Add(12 * time.Hour), EndAt: day.Add(13 * time.Hour), OwnerID: "alice"}
+	require.NoError(t, store.Save(ctx, &review))
+	require.NoError(t, store.Save(ctx, &lunch))
+
+	t.Run(
+		"when calendar is shared, finds it for the grantee", func(t *testing.T) {
+			calendars, err := store.FindCalendarsByUserID(ctx, "bob")
+			require.NoError(t, err)
+			require.Equal(t, []storage.Calendar{work}, calendars)
+
+			calendars, err = store.FindCalendarsByUserID(ctx, "carol")
+			require.NoError(t, err)
+			require.Empty(t, calendars)
+		},
+	)
+
+	t.Run(
+		"when events are listed by calendar, skips other calendars", func(t *testing.T) {
+			events, err := store.FindAllByCalendarIDAndPeriod(ctx, work.ID, day, day.AddDate(0, 0, 1))
+			require.NoError(t, err)
+			require.Equal(t, []storage.Event{review}, events)
+		},
+	)
+
+	t.Run(
+		"when calendar is deleted, deletes its events", func(t *testing.T) {
+			require.NoError(t, store.DeleteCalendar(ctx, work.ID))
+
+			_, err := store.FindCalendarByID(ctx, work.ID)
+			require.ErrorIs(t, err, storage.ErrNotFound)
+			_, err = store.FindByID(ctx, review.ID)
+			require.ErrorIs(t, err, storage.ErrNotFound)
+			_, err = store.FindByID(ctx, lunch.ID)
+			require.NoError(t, err)
+		},
+	)
 }
 
-func TestNew(t *testing.T) {
-	expected := &Storage{&sync.RWMutex{}, map[storage.EventID]storage.Event{}}
-	require.Equal(t, expected, New())
+func TestStorage_FindPageByUserIDAndPeriod(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	store := New()
+
+	// b and c start at the same time, so the id breaks the tie, alice declined b to be able to save c
+	for _, event := range []storage.Event{
+		{ID: "c", StartAt: day.Add(10 * time.Hour), EndAt: day.Add(11 * time.Hour), OwnerID: "alice"},
+		{ID: "a", StartAt: day.Add(12 * time.Hour), EndAt: day.Add(13 * time.Hour), OwnerID: "alice"},
+		{ID: "b", StartAt: day.Add(10 * time.Hour), EndAt: day.Add(11 * time.Hour), OwnerID: "bob"},
+		{ID: "d", StartAt: day.Add(-time.Hour), EndAt: day, OwnerID: "alice"},
+	} {
+		event := event
+		if event.OwnerID == "bob" {
+			event.Attendees = []storage.Attendee{{UserID: "alice", Status: storage.StatusDeclined}}
+		}
+		require.NoError(t, store.Save(ctx, &event))
+	}
+
+	ids := func(events []storage.Event) []storage.EventID {
+		res := make([]storage.EventID, 0, len(events))
+		for _, event := range events {
+			res = append(res, event.ID)
+		}
+		return res
+	}
+
+	t.Run(
+		"when cursor is zero, returns the first page ordered by start and id", func(t *testing.T) {
+			events, err := store.FindPageByUserIDAndPeriod(ctx, "alice", day, time.Time{}, storage.Cursor{}, 2)
+			require.NoError(t, err)
+			require.Equal(t, []storage.EventID{"b", "c"}, ids(events))
+		},
+	)
+
+	t.Run(
+		"when cursor is set, returns the events after it", func(t *testing.T) {
+			after := storage.Cursor{StartAt: day.Add(10 * time.Hour), ID: "b"}
+			events, err := store.FindPageByUserIDAndPeriod(ctx, "alice", time.Time{}, time.Time{}, after, 10)
+			require.NoError(t, err)
+			require.Equal(t, []storage.EventID{"c", "a"}, ids(events))
+		},
+	)
+}
+
+func TestStorage_FindSeriesPageByUserIDAndPeriod(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+	store := New()
+
+	for _, event := range []storage.Event{
+		{ID: "single", StartAt: day, EndAt: day.Add(time.Hour), OwnerID: "alice"},
+		{
+			ID:      "endless",
+			StartAt: day.Add(2 * time.Hour),
+			EndAt:   day.Add(3 * time.Hour),
+			OwnerID: "alice",
+			RRule:   "FREQ=DAILY",
+		},
+		{
+			ID:              "ended",
+			StartAt:         day.AddDate(0, 0, -7),
+			EndAt:           day.AddDate(0, 0, -7).Add(time.Hour),
+			RecurrenceEndAt: day.AddDate(0, 0, -5),
+			OwnerID:         "alice",
+			RRule:           "FREQ=DAILY;COUNT=3",
+		},
+	} {
+		event := event
+		require.NoError(t, store.Save(ctx, &event))
+	}
+
+	t.Run(
+		"when period is unbounded, returns every series without single events", func(t *testing.T) {
+			events, err := store.FindSeriesPageByUserIDAndPeriod(ctx, "alice", time.Time{}, time.Time{}, storage.Cursor{}, 10)
+			require.NoError(t, err)
+			require.Len(t, events, 2)
+			require.Equal(t, storage.EventID("ended"), events[0].ID)
+			require.Equal(t, storage.EventID("endless"), events[1].ID)
+		},
+	)
+
+	t.Run(
+		"when series ended before the period, skips it", func(t *testing.T) {
+			events, err := store.FindSeriesPageByUserIDAndPeriod(ctx, "alice", day, time.Time{}, storage.Cursor{}, 10)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			require.Equal(t, storage.EventID("endless"), events[0].ID)
+		},
+	)
+
+	t.Run(
+		"when limit is less than the number of series, returns the first of them", func(t *testing.T) {
+			events, err := store.FindSeriesPageByUserIDAndPeriod(ctx, "alice", time.Time{}, time.Time{}, storage.Cursor{}, 1)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			require.Equal(t, storage.EventID("ended"), events[0].ID)
+		},
+	)
+
+	t.Run(
+		"when cursor is set, returns the series after it", func(t *testing.T) {
+			after := storage.CursorOf(storage.Event{ID: "ended", StartAt: day.AddDate(0, 0, -7)})
+			events, err := store.FindSeriesPageByUserIDAndPeriod(ctx, "alice", time.Time{}, time.Time{}, after, 10)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			require.Equal(t, storage.EventID("endless"), events[0].ID)
+		},
+	)
+}
+
+func TestStorage_History(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+
+	event := &storage.Event{ID: "event", Title: "draft", Attendees: []storage.Attendee{{UserID: "bob"}}}
+	first := &storage.Change{EventID: event.ID, Kind: storage.ChangeCreate, ActorID: "alice", After: event}
+	require.NoError(t, store.SaveChange(ctx, first))
+	second := &storage.Change{EventID: event.ID, Kind: storage.ChangeDelete, ActorID: "alice", Before: event}
+	require.NoError(t, store.SaveChange(ctx, second))
+	require.Less(t, first.ID, second.ID)
+	require.False(t, first.At.IsZero())
+
+	t.Run(
+		"when snapshot is modified after save, keeps the recorded state", func(t *testing.T) {
+			event.Title = "changed"
+			event.Attendees[0].UserID = "carol"
+
+			changes, err := store.FindChangesByEventID(ctx, event.ID)
+			require.NoError(t, err)
+			require.Len(t, changes, 2)
+			require.Equal(t, "draft", changes[0].After.Title)
+			require.Equal(t, storage.UserID("bob"), changes[0].After.Attendees[0].UserID)
+			require.Nil(t, changes[0].Before)
+			require.Equal(t, storage.ChangeDelete, changes[1].Kind)
+		},
+	)
+
+	t.Run(
+		"when event has no history, returns empty list", func(t *testing.T) {
+			changes, err := store.FindChangesByEventID(ctx, "unknown")
+			require.NoError(t, err)
+			require.Empty(t, changes)
+		},
+	)
+}
+
+func TestStorage_WithChange(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+	startAt := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+
+	event := &storage.Event{ID: "event", OwnerID: "alice", StartAt: startAt, EndAt: startAt.Add(time.Hour)}
+	require.NoError(t, store.SaveWithChange(ctx, event, &storage.Change{Kind: storage.ChangeCreate, ActorID: "alice"}))
+
+	t.Run(
+		"when event is saved, records the change with the saved state", func(t *testing.T) {
+			changes, err := store.FindChangesByEventID(ctx, event.ID)
+			require.NoError(t, err)
+			require.Len(t, changes, 1)
+			require.Equal(t, event.ID, changes[0].EventID)
+			require.Equal(t, int64(1), changes[0].After.Version)
+		},
+	)
+
+	t.Run(
+		"when save fails, does not record the change", func(t *testing.T) {
+			stale := *event
+			stale.Version = 5
+			err := store.SaveWithChange(ctx, &stale, &storage.Change{Kind: storage.ChangeUpdate, ActorID: "alice"})
+			require.ErrorIs(t, err, storage.ErrVersionMismatch)
+
+			changes, err := store.FindChangesByEventID(ctx, event.ID)
+			require.NoError(t, err)
+			require.Len(t, changes, 1)
+		},
+	)
+
+	t.Run(
+		"when delete fails, does not record the change", func(t *testing.T) {
+			missing := &storage.Event{ID: "missing"}
+			err := store.DeleteWithChange(ctx, missing, &storage.Change{Kind: storage.ChangeDelete, Before: missing})
+			require.ErrorIs(t, err, storage.ErrNotFound)
+
+			changes, err := store.FindChangesByEventID(ctx, missing.ID)
+			require.NoError(t, err)
+			require.Empty(t, changes)
+		},
+	)
+
+	t.Run(
+		"when event is deleted, records the change", func(t *testing.T) {
+			err := store.DeleteWithChange(ctx, event, &storage.Change{Kind: storage.ChangeDelete, Before: event})
+			require.NoError(t, err)
+
+			changes, err := store.FindChangesByEventID(ctx, event.ID)
+			require.NoError(t, err)
+			require.Len(t, changes, 2)
+			require.Equal(t, storage.ChangeDelete, changes[1].Kind)
+		},
+	)
+}
+
+func TestStorage_Outbox(t *testing.T) {
+	ctx := context.Background()
+	event := storage.Event{ID: "event", Reminders: []storage.Reminder{{EventID: "event", Offset: time.Hour}}}
+	store := newTestStorage(event)
+
+	due := event.Reminders[0]
+	sent := due
+	sent.Sent = true
+	messages := []storage.OutboxMessage{{Key: "a", Body: []byte("a")}, {Key: "b", Body: []byte("b")}}
+	require.NoError(t, store.CompleteReminder(ctx, due, sent, messages))
+	require.True(t, store.items[event.ID].Reminders[0].Sent)
+
+	t.Run(
+		"when key is already in outbox, skips the message", func(t *testing.T) {
+			require.NoError(t, store.CompleteReminder(ctx, sent, sent, messages[1:]))
+			outbox, err := store.FindOutbox(ctx, 10)
+			require.NoError(t, err)
+			require.Len(t, outbox, 2)
+			require.Equal(t, "a", outbox[0].Key)
+			require.Equal(t, []byte("b"), outbox[1].Body)
+		},
+	)
+
+	t.Run(
+		"when message is deleted, returns the rest in order", func(t *testing.T) {
+			outbox, err := store.FindOutbox(ctx, 1)
+			require.NoError(t, err)
+			require.Len(t, outbox, 1)
+			require.NoError(t, store.DeleteOutbox(ctx, outbox[0].ID))
+
+			outbox, err = store.FindOutbox(ctx, 10)
+			require.NoError(t, err)
+			require.Len(t, outbox, 1)
+			require.Equal(t, "b", outbox[0].Key)
+		},
+	)
+
+	t.Run(
+		"when reminder has changed since it was read, returns conflict error and keeps outbox", func(t *testing.T) {
+			rescheduled := sent
+			rescheduled.Sent = false
+			rescheduled.NotifyAt = rescheduled.NotifyAt.Add(time.Hour)
+			store.saveReminder(rescheduled)
+
+			err := store.CompleteReminder(ctx, due, sent, []storage.OutboxMessage{{Key: "c"}})
+			require.ErrorIs(t, err, storage.ErrConflict)
+			require.False(t, store.items[event.ID].Reminders[0].Sent, "rescheduled reminder is not overwritten")
+			outbox, err := store.FindOutbox(ctx, 10)
+			require.NoError(t, err)
+			require.Len(t, outbox, 1)
+		},
+	)
+
+	t.Run(
+		"when event is deleted, returns not found error and keeps outbox", func(t *testing.T) {
+			err := store.CompleteReminder(
+				ctx,
+				storage.Reminder{EventID: "unknown"},
+				storage.Reminder{EventID: "unknown"},
+				[]storage.OutboxMessage{{Key: "c"}},
+			)
+			require.ErrorIs(t, err, storage.ErrNotFound)
+			outbox, err := store.FindOutbox(ctx, 10)
+			require.NoError(t, err)
+			require.Len(t, outbox, 1)
+		},
+	)
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+	event := storage.Event{ID: "event", OwnerID: "alice", Title: "review", Attendees: []storage.Attendee{{UserID: "bob"}}}
+	require.NoError(t, store.Save(ctx, &event))
+	require.NoError(t, store.SaveCalendar(ctx, &storage.Calendar{ID: "work", OwnerID: "alice"}))
+	require.NoError(t, store.SaveChange(ctx, &storage.Change{EventID: event.ID, After: &event}))
+
+	restored := Restore(store.Snapshot())
+	require.Equal(t, store.Snapshot(), restored.Snapshot())
+
+	events, _, err := restored.Search(ctx, storage.SearchFilter{UserID: "bob", Text: "review"})
+	require.NoError(t, err)
+	require.Len(t, events, 1, "indexes are rebuilt")
 }
@@ -0,0 +1,15 @@
+package storage
+
+import "time"
+
+// OutboxMessage is a message waiting in the outbox to be published to the queue. It is written
+// together with the change that produced it and deleted once published, so it is never lost.
+type OutboxMessage struct {
+	// ID is assigned by the storage, messages are published in the order of ids.
+	ID int64
+	// Key identifies the message, the outbox keeps only the first message with the key
+	// and consumers use it to drop duplicates.
+	Key       string
+	Body      []byte
+	CreatedAt time.Time
+}
@@ -0,0 +1,26 @@
+package storage
+
+import "time"
+
+// Cursor is a position in the events ordered by start and id, the zero cursor is before all events.
+type Cursor struct {
+	StartAt time.Time
+	ID      EventID
+}
+
+// CursorOf returns the position of the event.
+func CursorOf(event Event) Cursor {
+	return Cursor{StartAt: event.StartAt, ID: event.ID}
+}
+
+func (c Cursor) IsZero() bool {
+	return c.StartAt.IsZero() && c.ID == ""
+}
+
+// Before reports whether the event follows the cursor.
+func (c Cursor) Before(event Event) bool {
+	if !event.StartAt.Equal(c.StartAt) {
+		return event.StartAt.After(c.StartAt)
+	}
+	return event.ID > c.ID
+}
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/rrule"
+)
+
+// recurrenceHorizonYears limits the comparison of two endless series.
+const recurrenceHorizonYears = 2
+
+// Overlaps reports whether half-open intervals [startA, endA) and [startB, endB) overlap,
+// so an event ending at 10:00 does not overlap an event starting at 10:00.
+func Overlaps(startA, endA, startB, endB time.Time) bool {
+	return startA.Before(endB) && startB.Before(endA)
+}
+
+// SeriesEnd returns the end of the last occurrence, zero for an endless series.
+func (e Event) SeriesEnd() time.Time {
+	if e.RRule == "" {
+		return e.EndAt
+	}
+	return e.RecurrenceEndAt
+}
+
+// Location returns the location the series is expanded in.
+func (e Event) Location() (*time.Location, error) {
+	if e.Timezone == "" {
+		return e.StartAt.Location(), nil
+	}
+	loc, err := time.LoadLocation(e.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("event %s: timezone %q: %w", e.ID, e.Timezone, err)
+	}
+	return loc, nil
+}
+
+// DTStart returns the start of the series in its location, the recurrence rule is applied to its wall clock.
+func (e Event) DTStart() (time.Time, error) {
+	loc, err := e.Location()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return e.StartAt.In(loc), nil
+}
+
+// Occurrences returns the occurrences of the event which overlap [from, to), sorted by start.
+func (e Event) Occurrences(from, to time.Time) ([]Event, error) {
+	if e.RRule == "" {
+		if Overlaps(e.StartAt, e.EndAt, from, to) {
+			return []Event{e}, nil
+		}
+		return nil, nil
+	}
+
+	rule, err := rrule.Parse(e.RRule)
+	if err != nil {
+		return nil, fmt.Errorf("event %s: %w", e.ID, err)
+	}
+	dtstart, err := e.DTStart()
+	if err != nil {
+		return nil, err
+	}
+
+	duration := e.EndAt.Sub(e.StartAt)
+	var res []Event
+	for _, start := range rule.Between(dtstart, from.Add(-duration), to) {
+		if isExcluded(start, e.ExDates) || !Overlaps(start, start.Add(duration), from, to) {
+			continue
+		}
+
+		occurrence := e
+		occurrence.StartAt = start
+		occurrence.EndAt = start.Add(duration)
+		res = append(res, occurrence)
+	}
+
+	return res, nil
+}
+
+// OccurrencesAfter returns up to limit occurrences of the event which start after the time and before to,
+// sorted by start. Zero to means no end.
+func (e Event) OccurrencesAfter(after, to time.Time, limit int) ([]Event, error) {
+	if e.RRule == "" {
+		if limit > 0 && e.StartAt.After(after) && (to.IsZero() || e.StartAt.Before(to)) {
+			return []Event{e}, nil
+		}
+		return nil, nil
+	}
+
+	rule, err := rrule.Parse(e.RRule)
+	if err != nil {
+		return nil, fmt.Errorf("event %s: %w", e.ID, err)
+	}
+	dtstart, err := e.DTStart()
+	if err != nil {
+		return nil, err
+	}
+
+	duration := e.EndAt.Sub(e.StartAt)
+	var res []Event
+	for len(res) < limit {
+		// excluded dates take places of the requested occurrences, so the rest is requested again
+		n := limit - len(res)
+		starts := rule.Next(dtstart, after, to, n)
+		for _, start := range starts {
+			after = start
+			if isExcluded(start, e.ExDates) {
+				continue
+			}
+
+			occurrence := e
+			occurrence.StartAt = start
+			occurrence.EndAt = start.Add(duration)
+			res = append(res, occurrence)
+		}
+		if len(starts) < n {
+			break
+		}
+	}
+
+	return res, nil
+}
+
+// NextOccurrence returns the start of the first occurrence starting after the time.
+func (e Event) NextOccurrence(after time.Time) (time.Time, bool, error) {
+	if e.RRule == "" {
+		return e.StartAt, e.StartAt.After(after), nil
+	}
+
+	rule, err := rrule.Parse(e.RRule)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("event %s: %w", e.ID, err)
+	}
+	dtstart, err := e.DTStart()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	for {
+		start, ok := rule.After(dtstart, after)
+		if !ok || !isExcluded(start, e.ExDates) {
+			return start, ok, nil
+		}
+		after = start
+	}
+}
+
+// Conflicts reports whether any occurrences of the events overlap.
+// Two endless series are compared within the horizon after the later start.
+func (e Event) Conflicts(other Event) (bool, error) {
+	from := e.StartAt
+	if other.StartAt.After(from) {
+		from = other.StartAt
+	}
+
+	to := e.SeriesEnd()
+	if end := other.SeriesEnd(); to.IsZero() || (!end.IsZero() && end.Before(to)) {
+		to = end
+	}
+	if to.IsZero() {
+		to = from.AddDate(recurrenceHorizonYears, 0, 0)
+	}
+	if !from.Before(to) {
+		return false, nil
+	}
+
+	a, err := e.Occurrences(from, to)
+	if err != nil {
+		return false, err
+	}
+	b, err := other.Occurrences(from, to)
+	if err != nil {
+		return false, err
+	}
+
+	return overlapsAny(a, b), nil
+}
+
+func isExcluded(start time.Time, exDates []time.Time) bool {
+	for _, exDate := range exDates {
+		if exDate.Equal(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlapsAny reports whether any two occurrences of the sorted lists overlap.
+func overlapsAny(a, b []Event) bool {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if Overlaps(a[i].StartAt, a[i].EndAt, b[j].StartAt, b[j].EndAt) {
+			return true
+		}
+		if a[i].EndAt.Before(b[j].EndAt) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return false
+}
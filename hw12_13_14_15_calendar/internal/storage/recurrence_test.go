@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlaps(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2022, time.January, 10, hour, 0, 0, 0, time.UTC)
+	}
+
+	require.True(t, Overlaps(at(9), at(18), at(10), at(11)), "inner interval")
+	require.True(t, Overlaps(at(10), at(11), at(9), at(18)), "outer interval")
+	require.True(t, Overlaps(at(9), at(11), at(10), at(12)), "partial overlap")
+	require.False(t, Overlaps(at(9), at(10), at(10), at(11)), "adjacent intervals")
+	require.False(t, Overlaps(at(9), at(10), at(11), at(12)), "disjoint intervals")
+}
+
+func TestEvent_Conflicts(t *testing.T) {
+	monday := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+	week3 := monday.AddDate(0, 0, 21)
+	weekly := Event{
+		StartAt: monday,
+		EndAt:   monday.Add(time.Hour),
+		RRule:   "FREQ=WEEKLY",
+	}
+
+	tests := []struct {
+		name     string
+		other    Event
+		expected bool
+	}{
+		{
+			name:     "single event overlapping a later occurrence",
+			other:    Event{StartAt: week3.Add(30 * time.Minute), EndAt: week3.Add(2 * time.Hour)},
+			expected: true,
+		},
+		{
+			name:     "single event right after an occurrence",
+			other:    Event{StartAt: week3.Add(time.Hour), EndAt: week3.Add(2 * time.Hour)},
+			expected: false,
+		},
+		{
+			name:     "single event before the series",
+			other:    Event{StartAt: monday.AddDate(0, 0, -7), EndAt: monday.AddDate(0, 0, -7).Add(time.Hour)},
+			expected: false,
+		},
+		{
+			name: "endless series on another day",
+			other: Event{
+				StartAt: monday.AddDate(0, 0, 1),
+				EndAt:   monday.AddDate(0, 0, 1).Add(time.Hour),
+				RRule:   "FREQ=WEEKLY",
+			},
+			expected: false,
+		},
+		{
+			name: "series meeting the weekly one every other week",
+			other: Event{
+				StartAt: monday.AddDate(0, 0, 1),
+				EndAt:   monday.AddDate(0, 0, 1).Add(time.Hour),
+				RRule:   "FREQ=WEEKLY;BYDAY=MO,TU",
+			},
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			conflicts, err := weekly.Conflicts(tc.other)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, conflicts)
+
+			conflicts, err = tc.other.Conflicts(weekly)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, conflicts)
+		})
+	}
+}
+
+func TestEvent_Occurrences(t *testing.T) {
+	monday := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+	event := Event{
+		StartAt: monday,
+		EndAt:   monday.Add(time.Hour),
+		RRule:   "FREQ=DAILY;COUNT=5",
+		ExDates: []time.Time{monday.AddDate(0, 0, 2)},
+	}
+
+	occurrences, err := event.Occurrences(monday.Add(30*time.Minute), monday.AddDate(0, 0, 3))
+	require.NoError(t, err)
+	require.Len(t, occurrences, 2)
+	require.Equal(t, monday, occurrences[0].StartAt, "occurrence started before the period")
+	require.Equal(t, monday.AddDate(0, 0, 1), occurrences[1].StartAt)
+	require.Equal(t, occurrences[1].StartAt.Add(time.Hour), occurrences[1].EndAt)
+}
+
+func TestEvent_Occurrences_Timezone(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("tzdata is not available")
+	}
+	// the start is read back from the storage without its zone
+	startAt := time.Date(2022, time.March, 21, 9, 0, 0, 0, loc).UTC()
+	event := Event{
+		StartAt:  startAt,
+		EndAt:    startAt.Add(time.Hour),
+		RRule:    "FREQ=WEEKLY;COUNT=3",
+		Timezone: "Europe/Berlin",
+	}
+
+	t.Run(
+		"when series crosses DST change, keeps the wall clock time in the timezone", func(t *testing.T) {
+			occurrences, err := event.Occurrences(startAt, startAt.AddDate(0, 1, 0))
+			require.NoError(t, err)
+			require.Len(t, occurrences, 3)
+			for _, occurrence := range occurrences {
+				require.Equal(t, 9, occurrence.StartAt.In(loc).Hour())
+				require.Equal(t, time.Hour, occurrence.EndAt.Sub(occurrence.StartAt))
+			}
+			require.Equal(t, time.Date(2022, time.April, 4, 7, 0, 0, 0, time.UTC), occurrences[2].StartAt.UTC())
+
+			next, ok, err := event.NextOccurrence(occurrences[1].StartAt)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.True(t, next.Equal(occurrences[2].StartAt))
+		},
+	)
+
+	t.Run(
+		"when timezone is unknown, returns error", func(t *testing.T) {
+			broken := event
+			broken.Timezone = "Mars/Olympus"
+			_, err := broken.Occurrences(startAt, startAt.AddDate(0, 1, 0))
+			require.Error(t, err)
+		},
+	)
+}
+
+func TestEvent_OccurrencesAfter(t *testing.T) {
+	monday := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+	event := Event{
+		StartAt: monday,
+		EndAt:   monday.Add(time.Hour),
+		RRule:   "FREQ=DAILY",
+		ExDates: []time.Time{monday.AddDate(0, 0, 1)},
+	}
+
+	occurrences, err := event.OccurrencesAfter(monday.Add(-time.Nanosecond), time.Time{}, 3)
+	require.NoError(t, err)
+	require.Len(t, occurrences, 3, "excluded date does not shorten the endless series")
+	require.Equal(t, monday, occurrences[0].StartAt)
+	require.Equal(t, monday.AddDate(0, 0, 2), occurrences[1].StartAt)
+	require.Equal(t, monday.AddDate(0, 0, 3), occurrences[2].StartAt)
+
+	occurrences, err = event.OccurrencesAfter(monday, monday.AddDate(0, 0, 3), 10)
+	require.NoError(t, err)
+	require.Len(t, occurrences, 1)
+	require.Equal(t, monday.AddDate(0, 0, 2), occurrences[0].StartAt)
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// Reminder is a notification sent Offset before the start of an occurrence of the event.
+type Reminder struct {
+	EventID EventID
+	Offset  time.Duration
+	// OccurrenceAt is the start of the occurrence the reminder is scheduled for.
+	OccurrenceAt time.Time
+	NotifyAt     time.Time
+	Sent         bool
+}
+
+// ScheduleReminders schedules a reminder per offset for the first occurrence starting after now,
+// reminders of a past event are created as sent. Reminders of the same offset and occurrence
+// keep their state, so updates don't resend them.
+func ScheduleReminders(event Event, offsets []time.Duration, now time.Time) ([]Reminder, error) {
+	next, ok, err := event.NextOccurrence(now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		next = event.StartAt
+	}
+
+	reminders := make([]Reminder, 0, len(offsets))
+	for _, offset := range offsets {
+		reminder := Reminder{
+			EventID:      event.ID,
+			Offset:       offset,
+			OccurrenceAt: next,
+			NotifyAt:     next.Add(-offset),
+			Sent:         !ok,
+		}
+		for _, old := range event.Reminders {
+			if old.Offset == offset && old.OccurrenceAt.Equal(next) && old.Sent {
+				reminder.Sent = true
+			}
+		}
+		reminders = append(reminders, reminder)
+	}
+	sort.Slice(reminders, func(i, j int) bool {
+		return reminders[i].NotifyAt.Before(reminders[j].NotifyAt)
+	})
+
+	return reminders, nil
+}
+
+// Next returns the reminder for the next occurrence of the event,
+// the sent reminder is returned if there are no more occurrences.
+func (r Reminder) Next(event Event) (Reminder, error) {
+	next, ok, err := event.NextOccurrence(r.OccurrenceAt)
+	if err != nil {
+		return Reminder{}, err
+	}
+	if !ok {
+		r.Sent = true
+		return r, nil
+	}
+
+	r.OccurrenceAt = next
+	r.NotifyAt = next.Add(-r.Offset)
+	r.Sent = false
+	return r, nil
+}
+
+// Offsets returns the offsets of the event reminders.
+func (e Event) Offsets() []time.Duration {
+	offsets := make([]time.Duration, 0, len(e.Reminders))
+	for _, reminder := range e.Reminders {
+		offsets = append(offsets, reminder.Offset)
+	}
+	return offsets
+}
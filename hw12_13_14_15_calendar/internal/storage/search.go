@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SearchSort is the order of the search results, ties are broken by the event id.
+type SearchSort string
+
+const (
+	SortStartAsc  SearchSort = "start"
+	SortStartDesc SearchSort = "-start"
+	// SortRelevance puts the events matching the text in the title first, then the earlier ones.
+	SortRelevance SearchSort = "relevance"
+)
+
+// SearchFilter selects events of the user's calendar, both own and invited, or of the calendar if it is set.
+type SearchFilter struct {
+	UserID     UserID
+	CalendarID CalendarID
+	// Text matches the events having all its words in the title or the description, ignoring case.
+	Text string
+	// From and To select the events and the series overlapping [From, To), zero means unbounded.
+	From time.Time
+	To   time.Time
+	// HasReminders selects the events with or without reminders, nil means any.
+	HasReminders *bool
+	Sort         SearchSort
+	Offset       int
+	Limit        int
+}
+
+// Words splits the text into lower case words of letters and digits, the way the search matches them.
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
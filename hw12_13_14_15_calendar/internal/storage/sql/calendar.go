@@ -0,0 +1,229 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// SaveCalendar creates or renames the calendar and replaces its grants.
+func (s *Storage) SaveCalendar(ctx context.Context, calendar *storage.Calendar) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err)
+	}
+	defer func() {
+		// rollback after commit is a no-op
+		_ = tx.Rollback()
+	}()
+
+	users := []storage.UserID{calendar.OwnerID}
+	for _, grant := range calendar.Grants {
+		users = append(users, grant.UserID)
+	}
+	if _, err := tx.ExecContext(ctx, saveUsersQuery, userIDs(users)); err != nil {
+		return translateError(err)
+	}
+
+	var createdAt time.Time
+	err = tx.QueryRowContext(ctx, saveCalendarQuery, calendar.ID, calendar.OwnerID, calendar.Name).Scan(&createdAt)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, deleteGrantsQuery, calendar.ID); err != nil {
+		return translateError(err)
+	}
+	for _, grant := range calendar.Grants {
+		if _, err := tx.ExecContext(ctx, saveGrantQuery, calendar.ID, grant.UserID, grant.Access); err != nil {
+			return translateError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return translateError(err)
+	}
+
+	calendar.CreatedAt = createdAt
+	return nil
+}
+
+func (s *Storage) FindCalendarByID(ctx context.Context, calendarID storage.CalendarID) (*storage.Calendar, error) {
+	// the id column is uuid, other ids can't be stored
+	if _, err := uuid.Parse(calendarID.String()); err != nil {
+		return nil, fmt.Errorf("calendar %s: %w", calendarID, storage.ErrNotFound)
+	}
+
+	calendar, err := scanCalendar(s.db.QueryRowContext(ctx, selectCalendarQuery, calendarID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("calendar %s: %w", calendarID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	calendars := []storage.Calendar{calendar}
+	if err := attachGrants(ctx, s.db, calendars); err != nil {
+		return nil, translateError(err)
+	}
+
+	return &calendars[0], nil
+}
+
+// FindCalendarsByUserID returns the calendars owned by the user or shared with them.
+func (s *Storage) FindCalendarsByUserID(ctx context.Context, userID storage.UserID) ([]storage.Calendar, error) {
+	rows, err := s.db.QueryContext(ctx, selectCalendarsByUserQuery, userID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var calendars []storage.Calendar
+	for rows.Next() {
+		calendar, err := scanCalendar(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		calendars = append(calendars, calendar)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err)
+	}
+
+	return calendars, translateError(attachGrants(ctx, s.db, calendars))
+}
+
+// DeleteCalendar removes the calendar together with its events.
+func (s *Storage) DeleteCalendar(ctx context.Context, calendarID storage.CalendarID) error {
+	_, err := s.db.ExecContext(ctx, deleteCalendarQuery, calendarID)
+	return translateError(err)
+}
+
+// DeleteCalendarWithChanges deletes the calendar like DeleteCalendar and appends the changes
+// to the histories of its events in the same transaction.
+func (s *Storage) DeleteCalendarWithChanges(
+	ctx context.Context,
+	calendarID storage.CalendarID,
+	changes []*storage.Change,
+) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err)
+	}
+	defer func() {
+		// rollback after commit is a no-op
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, deleteCalendarQuery, calendarID); err != nil {
+		return translateError(err)
+	}
+	for _, change := range changes {
+		if err := saveChange(ctx, tx, change); err != nil {
+			return translateError(err)
+		}
+	}
+	return translateError(tx.Commit())
+}
+
+// FindAllByCalendarIDAndPeriod returns the events and the series of the calendar which overlap [from, to).
+func (s *Storage) FindAllByCalendarIDAndPeriod(
+	ctx context.Context,
+	calendarID storage.CalendarID,
+	from time.Time,
+	to time.Time,
+) ([]storage.Event, error) {
+	rows, err := s.db.QueryContext(ctx, selectAllByCalendarQuery, calendarID, from, to)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return events, translateError(attachDetails(ctx, s.db, events))
+}
+
+func attachGrants(ctx context.Context, q queryer, calendars []storage.Calendar) error {
+	if len(calendars) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(calendars))
+	for _, calendar := range calendars {
+		ids = append(ids, calendar.ID.String())
+	}
+
+	rows, err := q.QueryContext(ctx, selectGrantsQuery, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	grants := make(map[storage.CalendarID][]storage.Grant)
+	for rows.Next() {
+		var calendarID storage.CalendarID
+		var grant storage.Grant
+		if err := rows.Scan(&calendarID, &grant.UserID, &grant.Access); err != nil {
+			return err
+		}
+		grants[calendarID] = append(grants[calendarID], grant)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range calendars {
+		calendars[i].Grants = grants[calendars[i].ID]
+	}
+	return nil
+}
+
+func scanCalendar(row scanner) (storage.Calendar, error) {
+	var calendar storage.Calendar
+	err := row.Scan(&calendar.ID, &calendar.OwnerID, &calendar.Name, &calendar.CreatedAt)
+	return calendar, err
+}
+
+const calendarColumns = `id, owner_id, name, created_at`
+
+const saveCalendarQuery = `insert into calendars (id, owner_id, name)
+values ($1, $2, $3)
+on conflict (id) do update set name = excluded.name
+returning created_at`
+
+const selectCalendarQuery = `select ` + calendarColumns + `
+from calendars
+where id = $1`
+
+const selectCalendarsByUserQuery = `select ` + calendarColumns + `
+from calendars
+where owner_id = $1
+   or exists (select 1 from grants g where g.calendar_id = calendars.id and g.user_id = $1)
+order by created_at, id`
+
+const deleteCalendarQuery = `delete from calendars where id = $1`
+
+const deleteGrantsQuery = `delete from grants where calendar_id = $1`
+
+const saveGrantQuery = `insert into grants (calendar_id, user_id, access) values ($1, $2, $3)`
+
+const selectGrantsQuery = `select calendar_id, user_id, access
+from grants
+where calendar_id = any($1::uuid[])
+order by user_id`
+
+// selectAllByCalendarQuery returns the events and the series of the calendar $1 which overlap [$2, $3).
+const selectAllByCalendarQuery = `select ` + selectColumns + `
+from events
+where calendar_id = $1
+  and ((rrule is null and tstzrange(start_at, end_at, '[)') && tstzrange($2, $3, '[)'))
+    or (rrule is not null and tstzrange(start_at, recurrence_end_at, '[)') && tstzrange($2, $3, '[)')))`
@@ -0,0 +1,65 @@
+package sqlstorage
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// SQLSTATE codes of the errors translated to the storage errors.
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+	exclusionViolation  = "23P01"
+	tooManyConnections  = "53300"
+	// connectionException is the class of connection errors like 08006 connection_failure.
+	connectionException = "08"
+	// operatorIntervention is the class of errors like 57P01 admin_shutdown.
+	operatorIntervention = "57P0"
+)
+
+// translateError wraps driver errors into the storage errors, so callers don't depend on pgx.
+func translateError(err error) error {
+	if err == nil || isStorageError(err) {
+		return err
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == exclusionViolation:
+			return storage.ErrDateBusy
+		case pgErr.Code == uniqueViolation, pgErr.Code == foreignKeyViolation:
+			return fmt.Errorf("%w: %s", storage.ErrConflict, pgErr.Message)
+		case pgErr.Code == tooManyConnections,
+			strings.HasPrefix(pgErr.Code, connectionException),
+			strings.HasPrefix(pgErr.Code, operatorIntervention):
+			return fmt.Errorf("%w: %s", storage.ErrUnavailable, err)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.As(err, &netErr) ||
+		pgconn.Timeout(err) ||
+		pgconn.SafeToRetry(err) {
+		return fmt.Errorf("%w: %s", storage.ErrUnavailable, err)
+	}
+
+	return err
+}
+
+func isStorageError(err error) bool {
+	return errors.Is(err, storage.ErrNotFound) ||
+		errors.Is(err, storage.ErrConflict) ||
+		errors.Is(err, storage.ErrVersionMismatch) ||
+		errors.Is(err, storage.ErrUnavailable)
+}
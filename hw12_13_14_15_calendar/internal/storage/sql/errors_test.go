@@ -0,0 +1,43 @@
+package sqlstorage
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{name: "exclusion violation", err: &pgconn.PgError{Code: "23P01"}, expected: storage.ErrDateBusy},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}, expected: storage.ErrConflict},
+		{name: "foreign key violation", err: &pgconn.PgError{Code: "23503"}, expected: storage.ErrConflict},
+		{name: "connection failure", err: &pgconn.PgError{Code: "08006"}, expected: storage.ErrUnavailable},
+		{name: "admin shutdown", err: &pgconn.PgError{Code: "57P01"}, expected: storage.ErrUnavailable},
+		{name: "bad connection", err: fmt.Errorf("query: %w", driver.ErrBadConn), expected: storage.ErrUnavailable},
+		{name: "storage error", err: storage.ErrNotFound, expected: storage.ErrNotFound},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.ErrorIs(t, translateError(tc.err), tc.expected)
+		})
+	}
+
+	t.Run(
+		"when error is unknown, returns it as is", func(t *testing.T) {
+			err := errors.New("syntax error")
+			require.Equal(t, err, translateError(err))
+			require.Equal(t, &pgconn.PgError{Code: "42601"}, translateError(&pgconn.PgError{Code: "42601"}))
+			require.NoError(t, translateError(nil))
+		},
+	)
+}
@@ -0,0 +1,101 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// SaveChange appends the change to the event history, the snapshots are stored as json.
+func (s *Storage) SaveChange(ctx context.Context, change *storage.Change) error {
+	return translateError(saveChange(ctx, s.db, change))
+}
+
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func saveChange(ctx context.Context, q rowQueryer, change *storage.Change) error {
+	before, err := marshalSnapshot(change.Before)
+	if err != nil {
+		return err
+	}
+	after, err := marshalSnapshot(change.After)
+	if err != nil {
+		return err
+	}
+
+	return q.QueryRowContext(
+		ctx, saveChangeQuery,
+		change.EventID, change.Kind, change.ActorID, before, after,
+	).Scan(&change.ID, &change.At)
+}
+
+// FindChangesByEventID returns the history of the event from the oldest change.
+func (s *Storage) FindChangesByEventID(ctx context.Context, eventID storage.EventID) ([]storage.Change, error) {
+	changes := make([]storage.Change, 0)
+	// the event_id column is uuid, other ids have no history
+	if _, err := uuid.Parse(eventID.String()); err != nil {
+		return changes, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectChangesQuery, eventID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var change storage.Change
+		var before, after []byte
+		err := rows.Scan(&change.ID, &change.EventID, &change.Kind, &change.ActorID, &change.At, &before, &after)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		if change.Before, err = unmarshalSnapshot(before); err != nil {
+			return nil, err
+		}
+		if change.After, err = unmarshalSnapshot(after); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, translateError(rows.Err())
+}
+
+// marshalSnapshot returns nil for a missing snapshot, so it is stored as null, and json text otherwise.
+func marshalSnapshot(event *storage.Event) (interface{}, error) {
+	if event == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("marshal event %s: %w", event.ID, err)
+	}
+	return string(data), nil
+}
+
+func unmarshalSnapshot(data []byte) (*storage.Event, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var event storage.Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("unmarshal event snapshot: %w", err)
+	}
+	return &event, nil
+}
+
+const saveChangeQuery = `insert into event_history (event_id, kind, actor_id, before, after)
+values ($1, $2, $3, $4, $5)
+returning id, at`
+
+const selectChangesQuery = `select id, event_id, kind, actor_id, at, before, after
+from event_history
+where event_id = $1
+order by id`
@@ -0,0 +1,113 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// leaderLockClass separates the leader locks from the user locks which use the single key advisory locks.
+const leaderLockClass = 1
+
+var errLockLost = errors.New("advisory lock is not held")
+
+// Elector takes a session advisory lock on a dedicated connection. The lock is released by Postgres
+// when the connection dies, so another replica becomes the leader once the old leader is gone.
+type Elector struct {
+	mu   *sync.Mutex
+	db   *sql.DB
+	name string
+	conn *sql.Conn
+}
+
+// Elector returns an elector of the leader among the replicas campaigning with the same name.
+func (s *Storage) Elector(name string) *Elector {
+	return &Elector{mu: &sync.Mutex{}, db: s.db, name: name}
+}
+
+func (e *Elector) Acquire(ctx context.Context) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn != nil {
+		return true, nil
+	}
+
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return false, translateError(err)
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, tryLockQuery, leaderLockClass, e.name).Scan(&acquired); err != nil {
+		discard(conn)
+		return false, translateError(err)
+	}
+	if !acquired {
+		return false, translateError(conn.Close())
+	}
+
+	e.conn = conn
+	return true, nil
+}
+
+// Check verifies that the connection holding the lock is alive and still holds it.
+func (e *Elector) Check(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		return errLockLost
+	}
+	var held bool
+	if err := e.conn.QueryRowContext(ctx, lockHeldQuery, leaderLockClass, e.name).Scan(&held); err != nil {
+		return translateError(err)
+	}
+	if !held {
+		return errLockLost
+	}
+	return nil
+}
+
+func (e *Elector) Release(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		return nil
+	}
+	conn := e.conn
+	e.conn = nil
+
+	if _, err := conn.ExecContext(ctx, unlockQuery, leaderLockClass, e.name); err != nil {
+		// the connection must not return to the pool while it may hold the lock
+		discard(conn)
+		return fmt.Errorf("unlock: %w", translateError(err))
+	}
+	return translateError(conn.Close())
+}
+
+// discard closes the connection instead of returning it to the pool.
+func discard(conn *sql.Conn) {
+	_ = conn.Raw(func(interface{}) error {
+		return driver.ErrBadConn
+	})
+	_ = conn.Close()
+}
+
+const tryLockQuery = `select pg_try_advisory_lock($1::int, hashtext($2))`
+
+const lockHeldQuery = `select exists(
+    select 1
+    from pg_locks
+    where locktype = 'advisory'
+      and pid = pg_backend_pid()
+      and classid = $1::int
+      and objid = hashtext($2)::oid
+      and objsubid = 2
+      and granted
+)`
+
+const unlockQuery = `select pg_advisory_unlock($1::int, hashtext($2))`
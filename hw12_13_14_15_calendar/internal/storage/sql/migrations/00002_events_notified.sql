@@ -0,0 +1,11 @@
+-- +goose Up
+alter table events add column notified boolean not null default false;
+
+drop index if exists events_notify_idx;
+create index if not exists events_notify_idx on events using btree (notify_at) where notify_at is not null and not notified;
+
+-- +goose Down
+drop index if exists events_notify_idx;
+create index if not exists events_notify_idx on events using btree (notify_at) where notify_at is not null;
+
+alter table events drop column notified;
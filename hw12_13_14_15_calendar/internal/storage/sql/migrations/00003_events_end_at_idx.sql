@@ -0,0 +1,5 @@
+-- +goose Up
+create index if not exists events_end_at_idx on events using btree (end_at);
+
+-- +goose Down
+drop index if exists events_end_at_idx;
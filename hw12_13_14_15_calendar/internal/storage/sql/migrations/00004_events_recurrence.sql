@@ -0,0 +1,14 @@
+-- +goose Up
+alter table events add column rrule text null;
+alter table events add column exdates timestamp[] not null default '{}';
+alter table events add column recurrence_end_at timestamp null;
+
+create index if not exists events_owner_recurring_idx on events using btree (owner_id, start_at, recurrence_end_at)
+    where rrule is not null;
+
+-- +goose Down
+drop index if exists events_owner_recurring_idx;
+
+alter table events drop column recurrence_end_at;
+alter table events drop column exdates;
+alter table events drop column rrule;
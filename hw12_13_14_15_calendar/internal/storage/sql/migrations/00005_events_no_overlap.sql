@@ -0,0 +1,23 @@
+-- +goose Up
+create extension if not exists btree_gist;
+
+-- single events of an owner must not overlap, [start_at, end_at) is half-open so adjacent events are allowed.
+-- the constraint covers only non-recurring events keyed on owner_id: recurring series and the events
+-- of attendees are checked by the storage under the advisory locks of every participant.
+alter table events add constraint events_no_overlap
+    exclude using gist (owner_id with =, tsrange(start_at, end_at, '[)') with &&)
+    where (rrule is null);
+
+create index if not exists events_owner_recurring_range_idx on events
+    using gist (owner_id, tsrange(start_at, recurrence_end_at, '[)'))
+    where rrule is not null;
+
+drop index if exists events_owner_recurring_idx;
+
+-- +goose Down
+create index if not exists events_owner_recurring_idx on events using btree (owner_id, start_at, recurrence_end_at)
+    where rrule is not null;
+
+drop index if exists events_owner_recurring_range_idx;
+
+alter table events drop constraint if exists events_no_overlap;
@@ -0,0 +1,45 @@
+-- +goose Up
+-- existing values were written as UTC wall clock
+set local timezone = 'UTC';
+
+alter table events drop constraint if exists events_no_overlap;
+drop index if exists events_owner_recurring_range_idx;
+
+alter table events alter column start_at type timestamptz;
+alter table events alter column end_at type timestamptz;
+alter table events alter column notify_at type timestamptz;
+alter table events alter column recurrence_end_at type timestamptz;
+alter table events alter column exdates drop default;
+alter table events alter column exdates type timestamptz[];
+alter table events alter column exdates set default '{}';
+
+-- as before, only non-recurring events keyed on owner_id, attendees and series are left to the advisory locks
+alter table events add constraint events_no_overlap
+    exclude using gist (owner_id with =, tstzrange(start_at, end_at, '[)') with &&)
+    where (rrule is null);
+
+create index if not exists events_owner_recurring_range_idx on events
+    using gist (owner_id, tstzrange(start_at, recurrence_end_at, '[)'))
+    where rrule is not null;
+
+-- +goose Down
+set local timezone = 'UTC';
+
+alter table events drop constraint if exists events_no_overlap;
+drop index if exists events_owner_recurring_range_idx;
+
+alter table events alter column exdates drop default;
+alter table events alter column exdates type timestamp[];
+alter table events alter column exdates set default '{}';
+alter table events alter column recurrence_end_at type timestamp;
+alter table events alter column notify_at type timestamp;
+alter table events alter column end_at type timestamp;
+alter table events alter column start_at type timestamp;
+
+alter table events add constraint events_no_overlap
+    exclude using gist (owner_id with =, tsrange(start_at, end_at, '[)') with &&)
+    where (rrule is null);
+
+create index if not exists events_owner_recurring_range_idx on events
+    using gist (owner_id, tsrange(start_at, recurrence_end_at, '[)'))
+    where rrule is not null;
@@ -0,0 +1,37 @@
+-- +goose Up
+create table reminders
+(
+    event_id      varchar(16) not null references events (id) on delete cascade,
+    lead_time     bigint      not null,
+    occurrence_at timestamptz not null,
+    notify_at     timestamptz not null,
+    sent          boolean     not null default false,
+    primary key (event_id, lead_time)
+);
+
+create index if not exists reminders_due_idx on reminders using btree (notify_at) where not sent;
+
+insert into reminders (event_id, lead_time, occurrence_at, notify_at, sent)
+select id, (extract(epoch from start_at - notify_at) * 1000000000)::bigint, start_at, notify_at, notified
+from events
+where notify_at is not null and notify_at <= start_at;
+
+drop index if exists events_notify_idx;
+alter table events drop column notified;
+alter table events drop column notify_at;
+
+-- +goose Down
+alter table events add column notify_at timestamptz null;
+alter table events add column notified boolean not null default false;
+
+update events
+set notify_at = r.notify_at,
+    notified  = r.sent
+from (select distinct on (event_id) event_id, notify_at, sent
+      from reminders
+      order by event_id, lead_time desc) r
+where events.id = r.event_id;
+
+create index if not exists events_notify_idx on events using btree (notify_at) where notify_at is not null and not notified;
+
+drop table reminders;
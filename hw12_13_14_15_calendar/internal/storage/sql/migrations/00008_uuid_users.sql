@@ -0,0 +1,39 @@
+-- +goose Up
+create table users
+(
+    id         text primary key,
+    created_at timestamptz not null default now()
+);
+
+insert into users (id)
+select distinct owner_id
+from events;
+
+alter table reminders drop constraint if exists reminders_event_id_fkey;
+
+alter table events alter column id type uuid using id::uuid;
+alter table events alter column owner_id type text;
+alter table events add column created_at timestamptz not null default now();
+alter table events add column updated_at timestamptz not null default now();
+alter table events add constraint events_owner_id_fkey foreign key (owner_id) references users (id);
+
+alter table reminders alter column event_id type uuid using event_id::uuid;
+alter table reminders add constraint reminders_event_id_fkey
+    foreign key (event_id) references events (id) on delete cascade;
+
+-- +goose Down
+alter table reminders drop constraint if exists reminders_event_id_fkey;
+alter table events drop constraint if exists events_owner_id_fkey;
+
+-- uuids don't fit the former varchar(16), so the length is not restored
+alter table reminders alter column event_id type varchar;
+
+alter table events drop column updated_at;
+alter table events drop column created_at;
+alter table events alter column owner_id type varchar;
+alter table events alter column id type varchar;
+
+alter table reminders add constraint reminders_event_id_fkey
+    foreign key (event_id) references events (id) on delete cascade;
+
+drop table users;
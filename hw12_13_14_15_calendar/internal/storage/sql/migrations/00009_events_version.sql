@@ -0,0 +1,5 @@
+-- +goose Up
+alter table events add column version bigint not null default 1;
+
+-- +goose Down
+alter table events drop column version;
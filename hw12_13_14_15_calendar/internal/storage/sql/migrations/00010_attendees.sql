@@ -0,0 +1,14 @@
+-- +goose Up
+create table attendees
+(
+    event_id uuid not null references events (id) on delete cascade,
+    user_id  text not null references users (id),
+    status   text not null default 'needs-action'
+        check (status in ('needs-action', 'accepted', 'declined', 'tentative')),
+    primary key (event_id, user_id)
+);
+
+create index if not exists attendees_user_idx on attendees using btree (user_id, event_id);
+
+-- +goose Down
+drop table attendees;
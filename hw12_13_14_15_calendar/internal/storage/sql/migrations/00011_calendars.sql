@@ -0,0 +1,33 @@
+-- +goose Up
+create table calendars
+(
+    id         uuid        not null primary key,
+    owner_id   text        not null references users (id),
+    name       text        not null,
+    created_at timestamptz not null default now()
+);
+
+create index if not exists calendars_owner_idx on calendars using btree (owner_id);
+
+create table grants
+(
+    calendar_id uuid not null references calendars (id) on delete cascade,
+    user_id     text not null references users (id),
+    access      text not null check (access in ('free-busy', 'read', 'write')),
+    primary key (calendar_id, user_id)
+);
+
+create index if not exists grants_user_idx on grants using btree (user_id, calendar_id);
+
+-- events without a calendar belong to the default calendar of the owner
+alter table events
+    add column calendar_id uuid references calendars (id) on delete cascade;
+
+create index if not exists events_calendar_idx on events using btree (calendar_id, start_at);
+
+-- +goose Down
+alter table events
+    drop column calendar_id;
+
+drop table grants;
+drop table calendars;
@@ -0,0 +1,13 @@
+-- +goose Up
+alter table events
+    add column search tsvector generated always as (
+        to_tsvector('simple', coalesce(title, '') || ' ' || coalesce(description, ''))
+    ) stored;
+
+create index if not exists events_search_idx on events using gin (search);
+
+-- +goose Down
+drop index if exists events_search_idx;
+
+alter table events
+    drop column search;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- supports the keyset pagination of the events of the owner by (start_at, id)
+create index if not exists events_owner_start_id_idx on events using btree (owner_id, start_at, id);
+
+-- +goose Down
+drop index if exists events_owner_start_id_idx;
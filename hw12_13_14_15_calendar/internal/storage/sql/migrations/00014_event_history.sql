@@ -0,0 +1,17 @@
+-- +goose Up
+-- the history outlives the events, so event_id does not reference them
+create table event_history
+(
+    id       bigserial   not null primary key,
+    event_id uuid        not null,
+    kind     text        not null check (kind in ('create', 'update', 'delete', 'restore')),
+    actor_id text        not null,
+    at       timestamptz not null default now(),
+    before   jsonb,
+    after    jsonb
+);
+
+create index if not exists event_history_event_idx on event_history using btree (event_id, id);
+
+-- +goose Down
+drop table event_history;
@@ -0,0 +1,11 @@
+-- +goose Up
+create table outbox
+(
+    id         bigserial   not null primary key,
+    key        text        not null unique,
+    body       bytea       not null,
+    created_at timestamptz not null default now()
+);
+
+-- +goose Down
+drop table outbox;
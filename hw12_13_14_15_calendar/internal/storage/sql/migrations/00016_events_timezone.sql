@@ -0,0 +1,7 @@
+-- +goose Up
+-- IANA timezone the series is expanded in, the stored instants don't keep the zone of the start.
+-- empty for the events written before, they are expanded like before in the location of the scanned start.
+alter table events add column timezone text not null default '';
+
+-- +goose Down
+alter table events drop column if exists timezone;
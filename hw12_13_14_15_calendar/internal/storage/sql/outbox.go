@@ -0,0 +1,105 @@
+package sqlstorage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// CompleteReminder replaces the due reminder with the next one and puts the messages to the outbox
+// in one transaction, messages with the keys already in the outbox are skipped. It returns storage.ErrConflict
+// if the reminder is not in the due state anymore, for example the event was rescheduled after it was read.
+func (s *Storage) CompleteReminder(
+	ctx context.Context,
+	due, next storage.Reminder,
+	messages []storage.OutboxMessage,
+) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err)
+	}
+	defer func() {
+		// rollback after commit is a no-op
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(
+		ctx,
+		completeReminderQuery,
+		next.EventID,
+		int64(next.Offset),
+		next.OccurrenceAt,
+		next.NotifyAt,
+		next.Sent,
+		due.OccurrenceAt,
+		due.NotifyAt,
+		due.Sent,
+	)
+	if err != nil {
+		return translateError(err)
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+	if updated == 0 {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, existsQuery, due.EventID).Scan(&exists); err != nil {
+			return translateError(err)
+		}
+		if !exists {
+			return fmt.Errorf("event %s: %w", due.EventID, storage.ErrNotFound)
+		}
+		return fmt.Errorf("%w: reminder of event %s has changed", storage.ErrConflict, due.EventID)
+	}
+
+	for _, msg := range messages {
+		if _, err := tx.ExecContext(ctx, saveOutboxQuery, msg.Key, msg.Body); err != nil {
+			return translateError(err)
+		}
+	}
+
+	return translateError(tx.Commit())
+}
+
+// FindOutbox returns up to limit oldest messages of the outbox.
+func (s *Storage) FindOutbox(ctx context.Context, limit int) ([]storage.OutboxMessage, error) {
+	rows, err := s.db.QueryContext(ctx, selectOutboxQuery, limit)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	messages := make([]storage.OutboxMessage, 0)
+	for rows.Next() {
+		var msg storage.OutboxMessage
+		if err := rows.Scan(&msg.ID, &msg.Key, &msg.Body, &msg.CreatedAt); err != nil {
+			return nil, translateError(err)
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, translateError(rows.Err())
+}
+
+// DeleteOutbox acknowledges the published message, an unknown id is ignored.
+func (s *Storage) DeleteOutbox(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, deleteOutboxQuery, id)
+	return translateError(err)
+}
+
+// completeReminderQuery updates the reminder only if it is still in the state the scheduler has read.
+const completeReminderQuery = `update reminders
+set occurrence_at = $3, notify_at = $4, sent = $5
+where event_id = $1 and lead_time = $2
+  and occurrence_at = $6 and notify_at = $7 and sent = $8`
+
+const saveOutboxQuery = `insert into outbox (key, body) values ($1, $2) on conflict (key) do nothing`
+
+const selectOutboxQuery = `select id, key, body, created_at
+from outbox
+order by id
+limit $1`
+
+const deleteOutboxQuery = `delete from outbox where id = $1`
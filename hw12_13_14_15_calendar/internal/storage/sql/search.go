@@ -0,0 +1,120 @@
+package sqlstorage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Search returns a page of the events matching the filter and the number of all matching events.
+func (s *Storage) Search(ctx context.Context, filter storage.SearchFilter) ([]storage.Event, int, error) {
+	where, args := searchCondition(filter)
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `select count(*) from events where `+where, args...).Scan(&total); err != nil {
+		return nil, 0, translateError(err)
+	}
+	if total == 0 || filter.Offset >= total {
+		return []storage.Event{}, total, nil
+	}
+
+	query, args := searchQuery(filter)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, translateError(err)
+	}
+	defer rows.Close()
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, 0, translateError(err)
+	}
+	if events == nil {
+		events = []storage.Event{}
+	}
+
+	return events, total, translateError(attachDetails(ctx, s.db, events))
+}
+
+// searchCondition builds the where clause of the filter, the text is matched against the search column
+// which is indexed by GIN.
+func searchCondition(filter storage.SearchFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.CalendarID != "" {
+		conditions = append(conditions, "calendar_id = "+arg(filter.CalendarID))
+	} else {
+		user := arg(filter.UserID)
+		conditions = append(conditions, "(owner_id = "+user+
+			" or exists (select 1 from attendees a where a.event_id = events.id and a.user_id = "+user+"))")
+	}
+
+	if words := storage.Words(filter.Text); len(words) > 0 {
+		conditions = append(conditions, "search @@ plainto_tsquery('simple', "+arg(strings.Join(words, " "))+")")
+	}
+
+	if !filter.From.IsZero() || !filter.To.IsZero() {
+		// null bounds of tstzrange are infinite
+		var from, to interface{}
+		if !filter.From.IsZero() {
+			from = filter.From
+		}
+		if !filter.To.IsZero() {
+			to = filter.To
+		}
+		period := "tstzrange(" + arg(from) + "::timestamptz, " + arg(to) + "::timestamptz, '[)')"
+		conditions = append(conditions, "((rrule is null and tstzrange(start_at, end_at, '[)') && "+period+")"+
+			" or (rrule is not null and tstzrange(start_at, recurrence_end_at, '[)') && "+period+"))")
+	}
+
+	if filter.HasReminders != nil {
+		reminders := "exists (select 1 from reminders r where r.event_id = events.id)"
+		if !*filter.HasReminders {
+			reminders = "not " + reminders
+		}
+		conditions = append(conditions, reminders)
+	}
+
+	return strings.Join(conditions, "\n  and "), args
+}
+
+// searchQuery builds the query of a page of the events matching the filter.
+func searchQuery(filter storage.SearchFilter) (string, []interface{}) {
+	where, args := searchCondition(filter)
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	order := "start_at, id"
+	switch filter.Sort {
+	case storage.SortStartAsc:
+		order = "start_at, id"
+	case storage.SortStartDesc:
+		order = "start_at desc, id"
+	case storage.SortRelevance:
+		if words := storage.Words(filter.Text); len(words) > 0 {
+			query := "plainto_tsquery('simple', " + arg(strings.Join(words, " ")) + ")"
+			order = "(to_tsvector('simple', title) @@ " + query + ") desc, start_at, id"
+		}
+	}
+
+	query := `select ` + selectColumns + `
+from events
+where ` + where + `
+order by ` + order
+	if filter.Limit > 0 {
+		query += "\nlimit " + arg(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += "\noffset " + arg(filter.Offset)
+	}
+	return query, args
+}
@@ -0,0 +1,60 @@
+package sqlstorage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchQuery(t *testing.T) {
+	t.Run(
+		"when only user is set, matches the user calendar ordered by start", func(t *testing.T) {
+			query, args := searchQuery(storage.SearchFilter{UserID: "alice"})
+			require.Equal(t, `select `+selectColumns+`
+from events
+where (owner_id = $1 or exists (select 1 from attendees a where a.event_id = events.id and a.user_id = $1))
+order by start_at, id`, query)
+			require.Equal(t, []interface{}{storage.UserID("alice")}, args)
+		},
+	)
+
+	t.Run(
+		"when all filters are set, numbers the arguments in order", func(t *testing.T) {
+			from := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+			hasReminders := false
+			query, args := searchQuery(storage.SearchFilter{
+				CalendarID:   "work",
+				Text:         "Design, REVIEW",
+				From:         from,
+				HasReminders: &hasReminders,
+				Sort:         storage.SortRelevance,
+				Offset:       20,
+				Limit:        10,
+			})
+			require.Equal(t, `select `+selectColumns+`
+from events
+where calendar_id = $1
+  and search @@ plainto_tsquery('simple', $2)
+  and ((rrule is null and tstzrange(start_at, end_at, '[)') && tstzrange($3::timestamptz, $4::timestamptz, '[)'))`+
+				` or (rrule is not null and tstzrange(start_at, recurrence_end_at, '[)') && `+
+				`tstzrange($3::timestamptz, $4::timestamptz, '[)')))
+  and not exists (select 1 from reminders r where r.event_id = events.id)
+order by (to_tsvector('simple', title) @@ plainto_tsquery('simple', $5)) desc, start_at, id
+limit $6
+offset $7`, query)
+			require.Equal(t, []interface{}{
+				storage.CalendarID("work"), "design review", from, nil, "design review", 10, 20,
+			}, args)
+		},
+	)
+
+	t.Run(
+		"when text is empty, ignores relevance", func(t *testing.T) {
+			query, _ := searchQuery(storage.SearchFilter{UserID: "alice", Text: " ,. ", Sort: storage.SortRelevance})
+			require.NotContains(t, query, "tsquery")
+			require.Contains(t, query, "order by start_at, id")
+		},
+	)
+}
@@ -6,9 +6,11 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
 	_ "github.com/jackc/pgx/v4/stdlib" // pg driver
 	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
 	"github.com/pressly/goose/v3"
@@ -30,51 +32,247 @@ func (s *Storage) NextID(ctx context.Context) (storage.EventID, error) {
 	return storage.EventID(uuid.NewString()), nil
 }
 
+// Save stores the event, returns storage.ErrDateBusy if it overlaps another event of the owner
+// or of an attendee who has not declined. An update must have the version of the stored event,
+// otherwise storage.ErrVersionMismatch is returned, or storage.ErrNotFound if there is no event.
+// Writers of the same participant are serialized by advisory locks, so concurrent saves can't double-book.
 func (s *Storage) Save(ctx context.Context, event *storage.Event) error {
-	_, err := s.db.ExecContext(
-		ctx,
-		saveQuery,
+	return s.save(ctx, event, nil)
+}
+
+// SaveWithChange stores the event like Save and appends the change with the saved event as After
+// to its history in the same transaction.
+func (s *Storage) SaveWithChange(ctx context.Context, event *storage.Event, change *storage.Change) error {
+	return s.save(ctx, event, change)
+}
+
+func (s *Storage) save(ctx context.Context, event *storage.Event, change *storage.Change) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err)
+	}
+	defer func() {
+		// rollback after commit is a no-op
+		_ = tx.Rollback()
+	}()
+
+	participants := userIDs(event.Participants())
+	// locks are taken in the same order by all writers to avoid deadlocks
+	sort.Strings(participants)
+	for _, participant := range participants {
+		if _, err := tx.ExecContext(ctx, lockUserQuery, participant); err != nil {
+			return translateError(err)
+		}
+	}
+	if err := checkBusy(ctx, tx, event); err != nil {
+		return translateError(err)
+	}
+	if _, err := tx.ExecContext(ctx, saveUsersQuery, userIDs(event.Members())); err != nil {
+		return translateError(err)
+	}
+
+	query, args := insertQuery, []interface{}{
 		event.ID,
 		event.Title,
 		event.StartAt,
 		event.EndAt,
 		event.Description,
 		event.OwnerID,
-		event.NotifyAt,
-	)
+		sql.NullString{String: event.RRule, Valid: event.RRule != ""},
+		event.ExDates,
+		sql.NullTime{Time: event.RecurrenceEndAt, Valid: !event.RecurrenceEndAt.IsZero()},
+		sql.NullString{String: event.CalendarID.String(), Valid: event.CalendarID != ""},
+		event.Timezone,
+	}
+	if event.Version != 0 {
+		query, args = updateQuery, append(args, event.Version)
+	}
+
+	var version int64
+	var createdAt, updatedAt time.Time
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&version, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return versionError(ctx, tx, event.ID)
+	}
 	if err != nil {
+		return translateError(err)
+	}
+
+	if err := saveReminders(ctx, tx, event); err != nil {
+		return translateError(err)
+	}
+	if err := saveAttendees(ctx, tx, event); err != nil {
+		return translateError(err)
+	}
+	if change != nil {
+		// the event is updated only after the commit, so the change gets a copy with the new version
+		saved := *event
+		saved.Version, saved.CreatedAt, saved.UpdatedAt = version, createdAt, updatedAt
+		change.EventID, change.After = event.ID, &saved
+		if err := saveChange(ctx, tx, change); err != nil {
+			return translateError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return translateError(err)
+	}
+
+	event.Version, event.CreatedAt, event.UpdatedAt = version, createdAt, updatedAt
+	return nil
+}
+
+func saveReminders(ctx context.Context, tx *sql.Tx, event *storage.Event) error {
+	if _, err := tx.ExecContext(ctx, deleteRemindersQuery, event.ID); err != nil {
 		return err
 	}
+	for _, reminder := range event.Reminders {
+		_, err := tx.ExecContext(
+			ctx,
+			saveReminderQuery,
+			event.ID,
+			int64(reminder.Offset),
+			reminder.OccurrenceAt,
+			reminder.NotifyAt,
+			reminder.Sent,
+		)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (s *Storage) FindByID(ctx context.Context, eventID storage.EventID) (*storage.Event, error) {
-	row := s.db.QueryRowContext(ctx, selectQuery, eventID)
-	if errors.Is(row.Err(), sql.ErrNoRows) {
-		return nil, nil
+func saveAttendees(ctx context.Context, tx *sql.Tx, event *storage.Event) error {
+	if _, err := tx.ExecContext(ctx, deleteAttendeesQuery, event.ID); err != nil {
+		return err
 	}
-	if row.Err() != nil {
-		return nil, row.Err()
+	for _, attendee := range event.Attendees {
+		if _, err := tx.ExecContext(ctx, saveAttendeeQuery, event.ID, attendee.UserID, attendee.Status); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	var event storage.Event
-	if err := row.Scan(
+func checkBusy(ctx context.Context, tx *sql.Tx, event *storage.Event) error {
+	rows, err := tx.QueryContext(
+		ctx,
+		selectBusyQuery,
+		userIDs(event.Participants()),
 		event.ID,
-		event.Title,
 		event.StartAt,
-		event.EndAt,
-		event.Description,
-		event.NotifyAt,
-	); err != nil {
-		return nil, err
+		sql.NullTime{Time: event.SeriesEnd(), Valid: !event.SeriesEnd().IsZero()},
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	others, err := scanEvents(rows)
+	if err != nil {
+		return err
+	}
+	if err := attachDetails(ctx, tx, others); err != nil {
+		return err
+	}
+
+	for _, other := range others {
+		if !event.SharesParticipant(other) {
+			continue
+		}
+		conflicts, err := event.Conflicts(other)
+		if err != nil {
+			return err
+		}
+		if conflicts {
+			return storage.ErrDateBusy
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) FindByID(ctx context.Context, eventID storage.EventID) (*storage.Event, error) {
+	// the id column is uuid, other ids can't be stored
+	if _, err := uuid.Parse(eventID.String()); err != nil {
+		return nil, fmt.Errorf("event %s: %w", eventID, storage.ErrNotFound)
+	}
+
+	event, err := scanEvent(s.db.QueryRowContext(ctx, selectQuery, eventID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("event %s: %w", eventID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	events := []storage.Event{event}
+	if err := attachDetails(ctx, s.db, events); err != nil {
+		return nil, translateError(err)
 	}
 
-	return &event, nil
+	return &events[0], nil
+}
+
+// FindCurrentByID is FindByID, the storage has no cache.
+func (s *Storage) FindCurrentByID(ctx context.Context, eventID storage.EventID) (*storage.Event, error) {
+	return s.FindByID(ctx, eventID)
 }
 
+// Delete removes the event, returns storage.ErrNotFound if there is no event
+// and storage.ErrVersionMismatch if it was changed since it was read.
 func (s *Storage) Delete(ctx context.Context, event *storage.Event) error {
-	_, err := s.db.ExecContext(ctx, deleteQuery, event.ID)
-	return err
+	return s.delete(ctx, event, nil)
+}
+
+// DeleteWithChange removes the event like Delete and appends the change to its history in the same transaction.
+func (s *Storage) DeleteWithChange(ctx context.Context, event *storage.Event, change *storage.Change) error {
+	return s.delete(ctx, event, change)
+}
+
+func (s *Storage) delete(ctx context.Context, event *storage.Event, change *storage.Change) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err)
+	}
+	defer func() {
+		// rollback after commit is a no-op
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, deleteQuery, event.ID, event.Version)
+	if err != nil {
+		return translateError(err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+	if count == 0 {
+		return versionError(ctx, tx, event.ID)
+	}
+
+	if change != nil {
+		change.EventID = event.ID
+		if err := saveChange(ctx, tx, change); err != nil {
+			return translateError(err)
+		}
+	}
+	return translateError(tx.Commit())
+}
+
+// versionError returns the error of the write which matched no event by the id and the version,
+// storage.ErrNotFound if there is no event and storage.ErrVersionMismatch if it has another version.
+func versionError(ctx context.Context, tx *sql.Tx, eventID storage.EventID) error {
+	var exists bool
+	if err := tx.QueryRowContext(ctx, existsQuery, eventID).Scan(&exists); err != nil {
+		return translateError(err)
+	}
+	if exists {
+		return fmt.Errorf("%w: event %s is changed", storage.ErrVersionMismatch, eventID)
+	}
+	return fmt.Errorf("event %s: %w", eventID, storage.ErrNotFound)
 }
 
 func (s *Storage) FindAllByUserIDAndPeriod(
@@ -85,79 +283,320 @@ func (s *Storage) FindAllByUserIDAndPeriod(
 ) ([]storage.Event, error) {
 	rows, err := s.db.QueryContext(ctx, selectAllQuery, ownerID, from, to)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 	defer rows.Close()
 
-	if rows.Err() != nil {
-		return nil, rows.Err()
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return events, translateError(attachDetails(ctx, s.db, events))
+}
+
+// FindPageByUserIDAndPeriod returns up to limit events and series of the user which overlap [from, to)
+// and follow the cursor, ordered by start and id. Zero from and to mean an unbounded period.
+func (s *Storage) FindPageByUserIDAndPeriod(
+	ctx context.Context,
+	userID storage.UserID,
+	from time.Time,
+	to time.Time,
+	after storage.Cursor,
+	limit int,
+) ([]storage.Event, error) {
+	// null bounds of tstzrange are infinite, null cursor is before all events
+	var fromArg, toArg, afterStartAt, afterID interface{}
+	if !from.IsZero() {
+		fromArg = from
+	}
+	if !to.IsZero() {
+		toArg = to
+	}
+	if !after.IsZero() {
+		afterStartAt, afterID = after.StartAt, after.ID
 	}
 
-	var events []storage.Event
-	for rows.Next() {
-		var event storage.Event
-		if err := rows.Scan(
-			event.ID,
-			event.Title,
-			event.StartAt,
-			event.EndAt,
-			event.Description,
-			event.NotifyAt,
-		); err != nil {
-			return nil, err
-		}
-		events = append(events, event)
+	rows, err := s.db.QueryContext(ctx, selectPageQuery, userID, fromArg, toArg, afterStartAt, afterID, limit)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, translateError(err)
 	}
 
-	return events, nil
+	return events, translateError(attachDetails(ctx, s.db, events))
 }
 
-func (s *Storage) HasByUserIDAndPeriod(
+// FindSeriesPageByUserIDAndPeriod returns up to limit series of the user which overlap [from, to)
+// and follow the cursor, ordered by start and id. Zero from and to mean an unbounded period.
+func (s *Storage) FindSeriesPageByUserIDAndPeriod(
 	ctx context.Context,
-	ownerID storage.UserID,
+	userID storage.UserID,
 	from time.Time,
 	to time.Time,
-) (bool, error) {
-	row := s.db.QueryRowContext(ctx, fmt.Sprintf("select exists (%s) as exists", selectAllQuery), ownerID, from, to)
-	if row.Err() != nil {
-		return false, row.Err()
+	after storage.Cursor,
+	limit int,
+) ([]storage.Event, error) {
+	var fromArg, toArg, afterStartAt, afterID interface{}
+	if !from.IsZero() {
+		fromArg = from
+	}
+	if !to.IsZero() {
+		toArg = to
 	}
-	var res struct {
-		Exists bool
+	if !after.IsZero() {
+		afterStartAt, afterID = after.StartAt, after.ID
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectSeriesPageQuery, userID, fromArg, toArg, afterStartAt, afterID, limit)
+	if err != nil {
+		return nil, translateError(err)
 	}
-	if err := row.Scan(&res); err != nil {
-		return false, err
+	defer rows.Close()
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, translateError(err)
 	}
 
-	return res.Exists, nil
+	return events, translateError(attachDetails(ctx, s.db, events))
 }
 
-func (s *Storage) HasByUserIDAndPeriodForUpdate(
+// FindBusyByUserIDsAndPeriod returns the events and the series which overlap [from, to)
+// and take time of any of the users, declined invitations are skipped.
+func (s *Storage) FindBusyByUserIDsAndPeriod(
 	ctx context.Context,
-	forUpdate storage.EventID,
-	ownerID storage.UserID,
+	users []storage.UserID,
 	from time.Time,
 	to time.Time,
-) (bool, error) {
-	row := s.db.QueryRowContext(
+) ([]storage.Event, error) {
+	rows, err := s.db.QueryContext(ctx, selectBusyByUsersQuery, userIDs(users), from, to)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return events, translateError(attachDetails(ctx, s.db, events))
+}
+
+func (s *Storage) FindAllByUserID(ctx context.Context, ownerID storage.UserID) ([]storage.Event, error) {
+	rows, err := s.db.QueryContext(ctx, selectAllByUserQuery, ownerID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return events, translateError(attachDetails(ctx, s.db, events))
+}
+
+// FindDueReminders returns unsent reminders due until the time, ordered by the notification time.
+func (s *Storage) FindDueReminders(ctx context.Context, until time.Time) ([]storage.Reminder, error) {
+	rows, err := s.db.QueryContext(ctx, selectDueRemindersQuery, until)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	reminders, err := scanReminders(rows)
+	return reminders, translateError(err)
+}
+
+// SaveReminder updates the state of the event reminder with the same offset.
+func (s *Storage) SaveReminder(ctx context.Context, reminder storage.Reminder) error {
+	_, err := s.db.ExecContext(
 		ctx,
-		fmt.Sprintf("select exists (%s) as exists", selectAllForUpdateQuery),
-		ownerID,
-		forUpdate,
-		from,
-		to,
+		updateReminderQuery,
+		reminder.EventID,
+		int64(reminder.Offset),
+		reminder.OccurrenceAt,
+		reminder.NotifyAt,
+		reminder.Sent,
 	)
-	if row.Err() != nil {
-		return false, row.Err()
+	return translateError(err)
+}
+
+// queryer is implemented by both sql.DB and sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// attachDetails loads reminders and attendees of the events.
+func attachDetails(ctx context.Context, q queryer, events []storage.Event) error {
+	if len(events) == 0 {
+		return nil
 	}
-	var res struct {
-		Exists bool
+
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID.String())
+	}
+
+	reminders, err := findReminders(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+	attendees, err := findAttendees(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		events[i].Reminders = reminders[events[i].ID]
+		events[i].Attendees = attendees[events[i].ID]
+	}
+	return nil
+}
+
+func findReminders(ctx context.Context, q queryer, ids []string) (map[storage.EventID][]storage.Reminder, error) {
+	rows, err := q.QueryContext(ctx, selectRemindersQuery, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders, err := scanReminders(rows)
+	if err != nil {
+		return nil, err
 	}
-	if err := row.Scan(&res); err != nil {
-		return false, err
+
+	res := make(map[storage.EventID][]storage.Reminder, len(ids))
+	for _, reminder := range reminders {
+		res[reminder.EventID] = append(res[reminder.EventID], reminder)
+	}
+	return res, nil
+}
+
+func findAttendees(ctx context.Context, q queryer, ids []string) (map[storage.EventID][]storage.Attendee, error) {
+	rows, err := q.QueryContext(ctx, selectAttendeesQuery, ids)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return res.Exists, nil
+	res := make(map[storage.EventID][]storage.Attendee, len(ids))
+	for rows.Next() {
+		var eventID storage.EventID
+		var attendee storage.Attendee
+		if err := rows.Scan(&eventID, &attendee.UserID, &attendee.Status); err != nil {
+			return nil, err
+		}
+		res[eventID] = append(res[eventID], attendee)
+	}
+	return res, rows.Err()
+}
+
+func userIDs(users []storage.UserID) []string {
+	res := make([]string, 0, len(users))
+	for _, user := range users {
+		res = append(res, string(user))
+	}
+	return res
+}
+
+func (s *Storage) CountAllEndedBefore(ctx context.Context, before time.Time) (int, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, countEndedBeforeQuery, before).Scan(&count); err != nil {
+		return 0, translateError(err)
+	}
+	return count, nil
+}
+
+func (s *Storage) DeleteAllEndedBefore(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, deleteEndedBeforeQuery, before)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return int(count), nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEvent(row scanner) (storage.Event, error) {
+	var event storage.Event
+	var description, rrule, calendarID sql.NullString
+	var recurrenceEndAt sql.NullTime
+	var exDates pgtype.TimestamptzArray
+	if err := row.Scan(
+		&event.ID,
+		&event.Title,
+		&event.StartAt,
+		&event.EndAt,
+		&description,
+		&event.OwnerID,
+		&rrule,
+		&exDates,
+		&recurrenceEndAt,
+		&calendarID,
+		&event.Timezone,
+		&event.Version,
+		&event.CreatedAt,
+		&event.UpdatedAt,
+	); err != nil {
+		return storage.Event{}, err
+	}
+
+	event.Description = description.String
+	event.RRule = rrule.String
+	event.RecurrenceEndAt = recurrenceEndAt.Time
+	event.CalendarID = storage.CalendarID(calendarID.String)
+	if err := exDates.AssignTo(&event.ExDates); err != nil {
+		return storage.Event{}, err
+	}
+
+	return event, nil
+}
+
+func scanEvents(rows *sql.Rows) ([]storage.Event, error) {
+	var events []storage.Event
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func scanReminders(rows *sql.Rows) ([]storage.Reminder, error) {
+	var reminders []storage.Reminder
+	for rows.Next() {
+		var reminder storage.Reminder
+		var offset int64
+		if err := rows.Scan(
+			&reminder.EventID,
+			&offset,
+			&reminder.OccurrenceAt,
+			&reminder.NotifyAt,
+			&reminder.Sent,
+		); err != nil {
+			return nil, err
+		}
+		reminder.Offset = time.Duration(offset)
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, rows.Err()
 }
 
 func New(dsn string, maxOpenConns, maxIdleConns int, connMaxLifetime, connMaxIdleTime time.Duration) *Storage {
@@ -206,27 +645,168 @@ func (s *Storage) Migrate(ctx context.Context, command string) error {
 	return goose.Run(command, s.db, "migrations")
 }
 
-const saveQuery = `insert into events (id, title, start_at, end_at, description, owner_id, notify_at)
-values ($1, $2, $3, $4, $5, $6, $7)
-on conflict (id) do update
-set title = excluded.title,
-	start_at = excluded.start_at,
-	end_at = excluded.end_at,
-	description = excluded.description,
-	notify_at = excluded.notify_at`
-
-const selectQuery = `select id, title, start_at, end_at, description, owner_id, notify_at
+const eventColumns = `id, title, start_at, end_at, description, owner_id, rrule, exdates, recurrence_end_at,
+	calendar_id, timezone`
+
+// selectColumns are eventColumns followed by the columns maintained by the database.
+const selectColumns = eventColumns + `, version, created_at, updated_at`
+
+const saveUsersQuery = `insert into users (id)
+select unnest($1::text[])
+on conflict (id) do nothing`
+
+const insertQuery = `insert into events (` + eventColumns + `)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+returning version, created_at, updated_at`
+
+const updateQuery = `update events
+set title = $2,
+	start_at = $3,
+	end_at = $4,
+	description = $5,
+	owner_id = $6,
+	rrule = $7,
+	exdates = $8,
+	recurrence_end_at = $9,
+	calendar_id = $10,
+	timezone = $11,
+	version = version + 1,
+	updated_at = now()
+where id = $1 and version = $12
+returning version, created_at, updated_at`
+
+const selectQuery = `select ` + selectColumns + `
 from events
 where id = $1`
 
-const deleteQuery = `delete from events where id = $1`
+// deleteQuery deletes the event of the version, zero version deletes any.
+const deleteQuery = `delete from events where id = $1 and ($2 = 0 or version = $2)`
+
+const existsQuery = `select exists (select 1 from events where id = $1)`
+
+const lockUserQuery = `select pg_advisory_xact_lock(hashtext($1))`
+
+// memberCondition matches the events of the user calendar, both own and invited.
+const memberCondition = `(owner_id = $1
+    or exists (select 1 from attendees a where a.event_id = events.id and a.user_id = $1))`
+
+// selectAllQuery returns the events and the series which overlap [$2, $3), null $3 means unbounded.
+const selectAllQuery = `select ` + selectColumns + `
+from events
+where ` + memberCondition + `
+  and ((rrule is null and tstzrange(start_at, end_at, '[)') && tstzrange($2, $3, '[)'))
+    or (rrule is not null and tstzrange(start_at, recurrence_end_at, '[)') && tstzrange($2, $3, '[)')))`
+
+// pagePeriodCondition matches the events and the series which overlap [$2, $3) and follow ($4, $5).
+const pagePeriodCondition = `((rrule is null
+      and tstzrange(start_at, end_at, '[)') && tstzrange($2::timestamptz, $3::timestamptz, '[)'))
+    or (rrule is not null and tstzrange(start_at, recurrence_end_at, '[)')
+      && tstzrange($2::timestamptz, $3::timestamptz, '[)')))
+  and ($4::timestamptz is null or (start_at, id) > ($4::timestamptz, $5::uuid))`
+
+// selectPageQuery returns $6 events and series of the user which overlap [$2, $3) and follow ($4, $5)
+// in the order of start_at and id. The owned and the attended events are selected separately instead of
+// memberCondition: its "or exists" can't use an index, so every event of the user would be sorted.
+// The owned ones are read in order from events_owner_start_id_idx and stop at the limit, the attended ones
+// are found by attendees_user_idx, only the invitations of the user are sorted.
+const selectPageQuery = `select ` + selectColumns + `
+from events
+where id in (
+    (select id from events
+      where owner_id = $1 and ` + pagePeriodCondition + `
+      order by start_at, id
+      limit $6)
+    union all
+    (select id from events
+      join attendees a on a.event_id = events.id
+      where a.user_id = $1 and ` + pagePeriodCondition + `
+      order by start_at, id
+      limit $6))
+order by start_at, id
+limit $6`
+
+// seriesPeriodCondition matches the series which overlap [$2, $3) and follow ($4, $5).
+const seriesPeriodCondition = `rrule is not null
+  and tstzrange(start_at, recurrence_end_at, '[)') && tstzrange($2::timestamptz, $3::timestamptz, '[)')
+  and ($4::timestamptz is null or (start_at, id) > ($4::timestamptz, $5::uuid))`
+
+// selectSeriesPageQuery returns $6 series of the user which overlap [$2, $3) and follow ($4, $5)
+// in the order of start_at and id, the owned and the attended ones are selected like in selectPageQuery.
+const selectSeriesPageQuery = `select ` + selectColumns + `
+from events
+where id in (
+    (select id from events
+      where owner_id = $1 and ` + seriesPeriodCondition + `
+      order by start_at, id
+      limit $6)
+    union all
+    (select id from events
+      join attendees a on a.event_id = events.id
+      where a.user_id = $1 and ` + seriesPeriodCondition + `
+      order by start_at, id
+      limit $6))
+order by start_at, id
+limit $6`
+
+const selectAllByUserQuery = `select ` + selectColumns + `
+from events
+where ` + memberCondition + `
+order by start_at`
+
+// participantCondition matches the events taking time of any of the users $1, declined invitations don't.
+const participantCondition = `(owner_id = any($1::text[])
+    or exists (select 1 from attendees a
+      where a.event_id = events.id and a.user_id = any($1::text[]) and a.status != 'declined'))`
+
+// selectBusyQuery returns the events taking time of any of the users $1 which may overlap [$3, $4).
+const selectBusyQuery = `select ` + selectColumns + `
+from events
+where id != $2
+  and ` + participantCondition + `
+  and ((rrule is null and tstzrange(start_at, end_at, '[)') && tstzrange($3, $4, '[)'))
+    or (rrule is not null and tstzrange(start_at, recurrence_end_at, '[)') && tstzrange($3, $4, '[)')))`
+
+// selectBusyByUsersQuery returns the events taking time of any of the users $1 which overlap [$2, $3).
+const selectBusyByUsersQuery = `select ` + selectColumns + `
+from events
+where ` + participantCondition + `
+  and ((rrule is null and tstzrange(start_at, end_at, '[)') && tstzrange($2, $3, '[)'))
+    or (rrule is not null and tstzrange(start_at, recurrence_end_at, '[)') && tstzrange($2, $3, '[)')))`
+
+const deleteAttendeesQuery = `delete from attendees where event_id = $1`
+
+const saveAttendeeQuery = `insert into attendees (event_id, user_id, status) values ($1, $2, $3)`
+
+const selectAttendeesQuery = `select event_id, user_id, status
+from attendees
+where event_id = any($1::uuid[])
+order by user_id`
+
+const reminderColumns = `event_id, lead_time, occurrence_at, notify_at, sent`
+
+const deleteRemindersQuery = `delete from reminders where event_id = $1`
+
+const saveReminderQuery = `insert into reminders (` + reminderColumns + `)
+values ($1, $2, $3, $4, $5)`
+
+const updateReminderQuery = `update reminders
+set occurrence_at = $3, notify_at = $4, sent = $5
+where event_id = $1 and lead_time = $2`
+
+const selectRemindersQuery = `select ` + reminderColumns + `
+from reminders
+where event_id = any($1::uuid[])
+order by notify_at`
+
+const selectDueRemindersQuery = `select ` + reminderColumns + `
+from reminders
+where not sent and notify_at <= $1
+order by notify_at`
 
-const selectAllQuery = `select id, title, start_at, end_at, description, owner_id, notify_at
+const countEndedBeforeQuery = `select count(*)
 from events
-where owner_id = $1 
-  and (start_at between $2 and $3 or end_at between $2 and $3)`
+where (rrule is null and end_at < $1) or (rrule is not null and recurrence_end_at < $1)`
 
-const selectAllForUpdateQuery = `select id, title, start_at, end_at, description, owner_id, notify_at
+const deleteEndedBeforeQuery = `delete
 from events
-where owner_id = $1 and id != $2
-  and (start_at between $3 and $4 or end_at between $3 and $4)`
+where (rrule is null and end_at < $1) or (rrule is not null and recurrence_end_at < $1)`
@@ -0,0 +1,124 @@
+package sqlstorage
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mayerkv/otus_go_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// testDSNEnv names the database for the integration tests, its tables are truncated after every test.
+const testDSNEnv = "CALENDAR_TEST_POSTGRES_DSN"
+
+// newTestStorage returns the migrated storage, the test is skipped when the database is not configured.
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	ctx := context.Background()
+	s := New(dsn, 10, 10, time.Minute, time.Minute)
+	require.NoError(t, s.Migrate(ctx, "up"))
+	t.Cleanup(func() {
+		_, err := s.db.ExecContext(ctx, `truncate events, users, calendars, event_history, outbox cascade`)
+		require.NoError(t, err)
+		require.NoError(t, s.Close(ctx))
+	})
+	return s
+}
+
+func TestStorage_SaveConcurrently(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	startAt := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+
+	t.Run(
+		"when owners invite the same attendee at the same time, saves only one event", func(t *testing.T) {
+			// the exclusion constraint is keyed on owner_id, so only the advisory lock of bob prevents double-booking
+			for i := 0; i < 20; i++ {
+				from := startAt.Add(time.Duration(i) * 2 * time.Hour)
+				events := []*storage.Event{
+					{OwnerID: "alice", StartAt: from, EndAt: from.Add(time.Hour)},
+					{OwnerID: "carol", StartAt: from.Add(30 * time.Minute), EndAt: from.Add(90 * time.Minute)},
+				}
+
+				errs := make([]error, len(events))
+				start := make(chan struct{})
+				wg := sync.WaitGroup{}
+				for j, event := range events {
+					event.ID = storage.EventID(uuid.NewString())
+					event.Title = "meeting"
+					event.Attendees = []storage.Attendee{{UserID: "bob"}}
+
+					wg.Add(1)
+					go func(j int, event *storage.Event) {
+						defer wg.Done()
+						<-start
+						errs[j] = s.Save(ctx, event)
+					}(j, event)
+				}
+				close(start)
+				wg.Wait()
+
+				saved := 0
+				for _, err := range errs {
+					if err == nil {
+						saved++
+						continue
+					}
+					require.ErrorIs(t, err, storage.ErrDateBusy)
+				}
+				require.Equal(t, 1, saved)
+			}
+		},
+	)
+}
+
+func TestStorage_SaveVersion(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	newEvent := func() storage.Event {
+		startAt := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.UTC)
+		return storage.Event{
+			ID:      storage.EventID(uuid.NewString()),
+			Title:   "test",
+			StartAt: startAt,
+			EndAt:   startAt.Add(time.Hour),
+			OwnerID: storage.UserID(uuid.NewString()),
+		}
+	}
+
+	t.Run(
+		"when version is stale, returns version mismatch error", func(t *testing.T) {
+			aEvent := newEvent()
+			require.NoError(t, s.Save(ctx, &aEvent))
+			first, second := aEvent, aEvent
+
+			first.Title = "first"
+			require.NoError(t, s.Save(ctx, &first))
+			second.Title = "second"
+			require.ErrorIs(t, s.Save(ctx, &second), storage.ErrVersionMismatch)
+			require.ErrorIs(t, s.Delete(ctx, &second), storage.ErrVersionMismatch)
+
+			found, err := s.FindByID(ctx, aEvent.ID)
+			require.NoError(t, err)
+			require.Equal(t, "first", found.Title)
+		},
+	)
+
+	t.Run(
+		"when updated event does not exist, returns not found error", func(t *testing.T) {
+			aEvent := newEvent()
+			aEvent.Version = 1
+			require.ErrorIs(t, s.Save(ctx, &aEvent), storage.ErrNotFound)
+			require.ErrorIs(t, s.Delete(ctx, &aEvent), storage.ErrNotFound)
+		},
+	)
+}